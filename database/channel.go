@@ -0,0 +1,46 @@
+package database
+
+import (
+	"kmud/utils"
+	"strings"
+	"time"
+)
+
+// Channel is a chat channel that players can talk on from anywhere. Channels
+// are defined in code rather than by builders.
+type Channel struct {
+	Name      string
+	Color     utils.Color
+	Role      Role // Needed to join the channel
+	Moderator Role // Needed to mute and ban people on the channel
+	Default   bool // Whether characters are on the channel until they leave it
+}
+
+var Channels = []Channel{
+	{Name: "ooc", Color: utils.ColorCyan, Role: RolePlayer, Moderator: RoleAdmin, Default: true},
+	{Name: "newbie", Color: utils.ColorGreen, Role: RolePlayer, Moderator: RoleBuilder, Default: true},
+	{Name: "builder", Color: utils.ColorYellow, Role: RoleBuilder, Moderator: RoleAdmin, Default: true},
+	{Name: "admin", Color: utils.ColorRed, Role: RoleAdmin, Moderator: RoleAdmin, Default: true},
+}
+
+// FindChannel returns the channel with the given name, or nil if there isn't
+// one
+func FindChannel(name string) *Channel {
+	for i, channel := range Channels {
+		if strings.EqualFold(channel.Name, name) {
+			return &Channels[i]
+		}
+	}
+
+	return nil
+}
+
+// ChannelMembership is a character's standing on a channel that differs from
+// the channel's defaults
+type ChannelMembership struct {
+	Joined     bool
+	Banned     bool      `bson:",omitempty"`
+	MutedUntil time.Time `bson:",omitempty"`
+}
+
+// vim: nocindent
@@ -4,23 +4,96 @@ import (
 	"fmt"
 	"kmud/utils"
 	"labix.org/v2/mgo/bson"
+	"strings"
 )
 
+// DefaultStat is the value given to each of a new character's stats
+const DefaultStat = 10
+
+// DefaultMovement and DefaultMana are the pools of a character without a class
+const (
+	DefaultMovement = 100
+	DefaultMana     = 100
+)
+
+// Position is how a character is holding themselves, which affects how fast
+// they recover
+type Position string
+
+const (
+	PositionStanding Position = "standing"
+	PositionResting  Position = "resting"
+	PositionSleeping Position = "sleeping"
+)
+
+// TellPreference is who a character accepts tells from
+type TellPreference string
+
+const (
+	TellsFromEveryone TellPreference = "everyone"
+	TellsFromFriends  TellPreference = "friends"
+	TellsFromNobody   TellPreference = "nobody"
+)
+
+var TellPreferences = []TellPreference{TellsFromEveryone, TellsFromFriends, TellsFromNobody}
+
 type Character struct {
 	DbObject `bson:",inline"`
 
-	RoomId bson.ObjectId `bson:",omitempty"`
-	UserId bson.ObjectId `bson:",omitempty"`
+	RoomId     bson.ObjectId `bson:",omitempty"`
+	UserId     bson.ObjectId `bson:",omitempty"`
+	TemplateId bson.ObjectId `bson:",omitempty"`
+	SpawnerId  bson.ObjectId `bson:",omitempty"`
 
 	Name         string
 	Cash         int
 	Inventory    []bson.ObjectId
+	Equipment    map[EquipmentSlot]bson.ObjectId `bson:",omitempty"`
 	Health       int
 	HitPoints    int
+	Strength     int
+	Dexterity    int
 	Conversation string
 	Roaming      bool
+	Movement     int
+	MaxMovement  int
+	Mana         int
+	MaxMana      int
+
+	Class  string         `bson:",omitempty"`
+	Skills map[string]int `bson:",omitempty"` // Proficiency in each skill the character knows
+
+	Statuses []ActiveStatus `bson:",omitempty"`
+
+	Behavior    Behavior        `bson:",omitempty"`
+	PatrolRoute []bson.ObjectId `bson:",omitempty"`
+	FleeHealth  int             `bson:",omitempty"` // Percentage of health below which the NPC runs away
+
+	LootTable []LootEntry   `bson:",omitempty"`
+	Shop      *Shop         `bson:",omitempty"`
+	ScriptId  bson.ObjectId `bson:",omitempty"`
+
+	Topics   []Topic        `bson:",omitempty"`
+	Dialogue []DialogueNode `bson:",omitempty"`
+	Flags    []string       `bson:",omitempty"`
+
+	Experience int             `bson:",omitempty"`
+	Quests     []QuestProgress `bson:",omitempty"`
 
-	online bool
+	Channels map[string]ChannelMembership `bson:",omitempty"`
+
+	Ignoring []bson.ObjectId `bson:",omitempty"` // Characters whose messages this one doesn't see
+	Friends  []bson.ObjectId `bson:",omitempty"`
+	Tells    TellPreference  `bson:",omitempty"`
+
+	online   bool
+	position Position // Not saved, everyone is standing when the server starts
+}
+
+// LootEntry gives an NPC a chance of carrying a copy of an item template
+type LootEntry struct {
+	TemplateId bson.ObjectId
+	Chance     int // Percent
 }
 
 func NewCharacter(name string, userId bson.ObjectId, roomId bson.ObjectId) *Character {
@@ -32,6 +105,12 @@ func NewCharacter(name string, userId bson.ObjectId, roomId bson.ObjectId) *Char
 	character.Cash = 0
 	character.Health = 100
 	character.HitPoints = 100
+	character.Strength = DefaultStat
+	character.Dexterity = DefaultStat
+	character.Movement = DefaultMovement
+	character.MaxMovement = DefaultMovement
+	character.Mana = DefaultMana
+	character.MaxMana = DefaultMana
 	character.Name = utils.FormatName(name)
 
 	character.online = false
@@ -69,12 +148,33 @@ func NewNpcTemplate(name string) *Character {
 }
 
 func NewNpcFromTemplate(template *Character, roomId bson.ObjectId) *Character {
-	return NewNpc(template.GetName(), template.GetRoomId())
+	npc := NewNpc(template.GetName(), roomId)
+
+	template.ReadLock()
+	npc.TemplateId = template.Id
+	npc.Health = template.Health
+	npc.HitPoints = template.Health
+	npc.Strength = template.Strength
+	npc.Dexterity = template.Dexterity
+	npc.Conversation = template.Conversation
+	npc.Roaming = template.Roaming
+	npc.Behavior = template.Behavior
+	npc.PatrolRoute = append([]bson.ObjectId(nil), template.PatrolRoute...)
+	npc.FleeHealth = template.FleeHealth
+	npc.Topics = template.Topics
+	npc.Dialogue = template.Dialogue
+	npc.Shop = template.Shop.Copy()
+	npc.ScriptId = template.ScriptId
+	template.ReadUnlock()
+
+	modified(npc)
+	return npc
 }
 
 func (self *Character) SetOnline(online bool) {
 	self.WriteLock()
 	self.online = online
+	self.position = PositionStanding
 	self.WriteUnlock()
 }
 
@@ -137,6 +237,30 @@ func (self *Character) GetUserId() bson.ObjectId {
 	return self.UserId
 }
 
+func (self *Character) GetTemplateId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.TemplateId
+}
+
+func (self *Character) SetSpawnerId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.SpawnerId {
+		self.SpawnerId = id
+		modified(self)
+	}
+}
+
+func (self *Character) GetSpawnerId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.SpawnerId
+}
+
 func (self *Character) SetCash(cash int) {
 	self.WriteLock()
 	defer self.WriteUnlock()
@@ -148,7 +272,28 @@ func (self *Character) SetCash(cash int) {
 }
 
 func (self *Character) AddCash(amount int) {
-	self.SetCash(self.GetCash() + amount)
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if amount != 0 {
+		self.Cash += amount
+		modified(self)
+	}
+}
+
+// TakeCash removes the given amount of cash from the character, provided they
+// have that much. Returns false, leaving their cash untouched, if they don't.
+func (self *Character) TakeCash(amount int) bool {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if amount > self.Cash {
+		return false
+	}
+
+	self.Cash -= amount
+	modified(self)
+	return true
 }
 
 func (self *Character) GetCash() int {
@@ -181,6 +326,12 @@ func (self *Character) RemoveItem(item *Item) {
 			}
 		}
 
+		for slot, itemId := range self.Equipment {
+			if itemId == item.GetId() {
+				delete(self.Equipment, slot)
+			}
+		}
+
 		modified(self)
 	}
 }
@@ -204,6 +355,55 @@ func (self *Character) GetItemIds() []bson.ObjectId {
 	return self.Inventory
 }
 
+// Equip puts an item from the character's inventory in to the given slot
+func (self *Character) Equip(slot EquipmentSlot, item *Item) {
+	if self.HasItem(item) {
+		self.WriteLock()
+		defer self.WriteUnlock()
+
+		if self.Equipment == nil {
+			self.Equipment = map[EquipmentSlot]bson.ObjectId{}
+		}
+
+		self.Equipment[slot] = item.GetId()
+		modified(self)
+	}
+}
+
+// Unequip empties the given slot, leaving the item in the character's inventory
+func (self *Character) Unequip(slot EquipmentSlot) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if _, found := self.Equipment[slot]; found {
+		delete(self.Equipment, slot)
+		modified(self)
+	}
+}
+
+// GetEquipped returns the id of the item in the given slot, if there is one
+func (self *Character) GetEquipped(slot EquipmentSlot) bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Equipment[slot]
+}
+
+// EquippedSlot returns the slot that the item is equipped in, or false if it
+// isn't equipped
+func (self *Character) EquippedSlot(item *Item) (EquipmentSlot, bool) {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	for slot, itemId := range self.Equipment {
+		if itemId == item.GetId() {
+			return slot, true
+		}
+	}
+
+	return "", false
+}
+
 func (self *Character) SetConversation(conversation string) {
 	self.WriteLock()
 	defer self.WriteUnlock()
@@ -227,9 +427,205 @@ func (self *Character) PrettyConversation() string {
 		return fmt.Sprintf("%s has nothing to say", self.GetName())
 	}
 
+	return self.PrettySay(conv)
+}
+
+// PrettySay formats a line of speech from the character
+func (self *Character) PrettySay(text string) string {
 	return fmt.Sprintf("%s%s",
 		utils.Colorize(utils.ColorBlue, self.GetName()),
-		utils.Colorize(utils.ColorWhite, ": "+conv))
+		utils.Colorize(utils.ColorWhite, ": "+text))
+}
+
+// GetTopics returns the topics that can be asked about. The returned slice
+// must not be modified, use SetTopics instead.
+func (self *Character) GetTopics() []Topic {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Topics
+}
+
+func (self *Character) SetTopics(topics []Topic) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Topics = topics
+	modified(self)
+}
+
+// GetDialogue returns the character's dialogue tree. The returned slice must
+// not be modified, use SetDialogue instead.
+func (self *Character) GetDialogue() []DialogueNode {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Dialogue
+}
+
+func (self *Character) SetDialogue(dialogue []DialogueNode) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Dialogue = dialogue
+	modified(self)
+}
+
+// GetLootTable returns the NPC template's loot table. The returned slice must
+// not be modified, use SetLootTable instead.
+func (self *Character) GetLootTable() []LootEntry {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.LootTable
+}
+
+func (self *Character) SetLootTable(loot []LootEntry) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.LootTable = loot
+	modified(self)
+}
+
+// GetShop returns a copy of the NPC's shop configuration, or nil if the NPC
+// isn't a shopkeeper
+func (self *Character) GetShop() *Shop {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Shop.Copy()
+}
+
+// SetShop changes the NPC's shop configuration. Setting it to nil stops the
+// NPC being a shopkeeper.
+func (self *Character) SetShop(shop *Shop) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Shop = shop.Copy()
+	modified(self)
+}
+
+func (self *Character) IsShopkeeper() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Shop != nil
+}
+
+func (self *Character) HasFlag(flag string) bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	flag = strings.ToLower(flag)
+
+	for _, f := range self.Flags {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetFlag marks the character with the given flag, used to track things such
+// as quest progress
+func (self *Character) SetFlag(flag string) {
+	if !self.HasFlag(flag) {
+		self.WriteLock()
+		defer self.WriteUnlock()
+
+		self.Flags = append(self.Flags, strings.ToLower(flag))
+		modified(self)
+	}
+}
+
+func (self *Character) ClearFlag(flag string) {
+	if self.HasFlag(flag) {
+		self.WriteLock()
+		defer self.WriteUnlock()
+
+		flag = strings.ToLower(flag)
+
+		for i, f := range self.Flags {
+			if f == flag {
+				self.Flags = append(self.Flags[:i], self.Flags[i+1:]...)
+				break
+			}
+		}
+
+		modified(self)
+	}
+}
+
+func (self *Character) GetFlags() []string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Flags
+}
+
+func (self *Character) GetExperience() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Experience
+}
+
+func (self *Character) AddExperience(amount int) {
+	if amount != 0 {
+		self.WriteLock()
+		self.Experience += amount
+		self.WriteUnlock()
+		modified(self)
+	}
+}
+
+// GetQuests returns the character's progress on every quest they have started
+func (self *Character) GetQuests() []QuestProgress {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	quests := make([]QuestProgress, len(self.Quests))
+	for i, progress := range self.Quests {
+		quests[i] = progress
+		quests[i].Counts = append([]int(nil), progress.Counts...)
+	}
+
+	return quests
+}
+
+// GetQuestProgress returns the character's progress on the given quest, or
+// false if they haven't started it
+func (self *Character) GetQuestProgress(questId bson.ObjectId) (QuestProgress, bool) {
+	for _, progress := range self.GetQuests() {
+		if progress.QuestId == questId {
+			return progress, true
+		}
+	}
+
+	return QuestProgress{}, false
+}
+
+// SetQuestProgress records the character's progress on a quest, replacing
+// whatever was recorded for it before
+func (self *Character) SetQuestProgress(progress QuestProgress) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	progress.Counts = append([]int(nil), progress.Counts...)
+
+	for i, existing := range self.Quests {
+		if existing.QuestId == progress.QuestId {
+			self.Quests[i] = progress
+			modified(self)
+			return
+		}
+	}
+
+	self.Quests = append(self.Quests, progress)
+	modified(self)
 }
 
 func (self *Character) SetHealth(health int) {
@@ -283,29 +679,535 @@ func (self *Character) Heal(hitpoints int) {
 	self.SetHitPoints(self.GetHitPoints() + hitpoints)
 }
 
-func (self *Character) GetRoaming() bool {
+// MigrateResources gives characters saved before movement and mana existed
+// full pools of them
+func (self *Character) MigrateResources() {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if self.MaxMovement == 0 {
+		self.MaxMovement = DefaultMovement
+		self.Movement = DefaultMovement
+		modified(self)
+	}
+
+	if self.MaxMana == 0 {
+		self.MaxMana = DefaultMana
+		self.Mana = DefaultMana
+		modified(self)
+	}
+}
+
+func (self *Character) SetMaxMovement(movement int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if movement != self.MaxMovement {
+		self.MaxMovement = movement
+
+		if self.Movement > self.MaxMovement {
+			self.Movement = self.MaxMovement
+		}
+
+		modified(self)
+	}
+}
+
+func (self *Character) GetMaxMovement() int {
 	self.ReadLock()
 	defer self.ReadUnlock()
 
-	return self.Roaming
+	return self.MaxMovement
 }
 
-func (self *Character) SetRoaming(roaming bool) {
+func (self *Character) SetMovement(movement int) {
 	self.WriteLock()
 	defer self.WriteUnlock()
 
-	self.Roaming = roaming
-	modified(self)
+	if movement > self.MaxMovement {
+		movement = self.MaxMovement
+	} else if movement < 0 {
+		movement = 0
+	}
+
+	if movement != self.Movement {
+		self.Movement = movement
+		modified(self)
+	}
 }
 
-func CharacterNames(characters []*Character) []string {
-	names := make([]string, len(characters))
+func (self *Character) GetMovement() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
 
-	for i, char := range characters {
-		names[i] = char.GetName()
+	return self.Movement
+}
+
+// UseMovement takes the given number of movement points, returning false
+// without taking any if the character doesn't have enough
+func (self *Character) UseMovement(amount int) bool {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if amount > self.Movement {
+		return false
 	}
 
-	return names
+	if amount > 0 {
+		self.Movement -= amount
+		modified(self)
+	}
+
+	return true
+}
+
+func (self *Character) SetMaxMana(mana int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if mana != self.MaxMana {
+		self.MaxMana = mana
+
+		if self.Mana > self.MaxMana {
+			self.Mana = self.MaxMana
+		}
+
+		modified(self)
+	}
+}
+
+func (self *Character) GetMaxMana() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.MaxMana
+}
+
+func (self *Character) SetMana(mana int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if mana > self.MaxMana {
+		mana = self.MaxMana
+	} else if mana < 0 {
+		mana = 0
+	}
+
+	if mana != self.Mana {
+		self.Mana = mana
+		modified(self)
+	}
+}
+
+func (self *Character) GetMana() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Mana
+}
+
+// UseMana takes the given amount of mana, returning false without taking any
+// if the character doesn't have enough
+func (self *Character) UseMana(amount int) bool {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if amount > self.Mana {
+		return false
+	}
+
+	if amount > 0 {
+		self.Mana -= amount
+		modified(self)
+	}
+
+	return true
+}
+
+func (self *Character) GetStatuses() []ActiveStatus {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return append([]ActiveStatus(nil), self.Statuses...)
+}
+
+func (self *Character) SetStatuses(statuses []ActiveStatus) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Statuses = statuses
+	modified(self)
+}
+
+// GetChannelMembership returns the character's standing on the channel. A
+// character that has never joined, left or been punished on the channel is
+// on it if the channel is one that everyone starts on.
+func (self *Character) GetChannelMembership(channel *Channel) ChannelMembership {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if membership, found := self.Channels[channel.Name]; found {
+		return membership
+	}
+
+	return ChannelMembership{Joined: channel.Default}
+}
+
+func (self *Character) SetChannelMembership(channel *Channel, membership ChannelMembership) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if self.Channels == nil {
+		self.Channels = map[string]ChannelMembership{}
+	}
+
+	self.Channels[channel.Name] = membership
+	modified(self)
+}
+
+func (self *Character) GetIgnoring() []bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return append([]bson.ObjectId(nil), self.Ignoring...)
+}
+
+// IsIgnoring returns true if the character doesn't want to see messages from
+// the other character
+func (self *Character) IsIgnoring(other *Character) bool {
+	id := other.GetId()
+
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return containsId(self.Ignoring, id)
+}
+
+func (self *Character) SetIgnoring(other *Character, ignoring bool) {
+	id := other.GetId()
+
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Ignoring = setId(self.Ignoring, id, ignoring)
+	modified(self)
+}
+
+func (self *Character) GetFriends() []bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return append([]bson.ObjectId(nil), self.Friends...)
+}
+
+func (self *Character) IsFriend(other *Character) bool {
+	id := other.GetId()
+
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return containsId(self.Friends, id)
+}
+
+func (self *Character) SetFriend(other *Character, friend bool) {
+	id := other.GetId()
+
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Friends = setId(self.Friends, id, friend)
+	modified(self)
+}
+
+func (self *Character) GetTellPreference() TellPreference {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Tells == "" {
+		return TellsFromEveryone
+	}
+
+	return self.Tells
+}
+
+func (self *Character) SetTellPreference(preference TellPreference) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Tells = preference
+	modified(self)
+}
+
+// AcceptsTellFrom returns true if the character's preferences let the other
+// character send them tells
+func (self *Character) AcceptsTellFrom(other *Character) bool {
+	if self.IsIgnoring(other) {
+		return false
+	}
+
+	switch self.GetTellPreference() {
+	case TellsFromNobody:
+		return false
+	case TellsFromFriends:
+		return self.IsFriend(other)
+	}
+
+	return true
+}
+
+func containsId(ids []bson.ObjectId, id bson.ObjectId) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setId adds the id to the list if present is true, otherwise it removes it
+func setId(ids []bson.ObjectId, id bson.ObjectId, present bool) []bson.ObjectId {
+	if present {
+		if !containsId(ids, id) {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	for i, other := range ids {
+		if other == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+
+	return ids
+}
+
+func (self *Character) GetPosition() Position {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.position == "" {
+		return PositionStanding
+	}
+
+	return self.position
+}
+
+func (self *Character) SetPosition(position Position) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.position = position
+}
+
+func (self *Character) GetClass() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Class
+}
+
+func (self *Character) SetClass(class string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if class != self.Class {
+		self.Class = class
+		modified(self)
+	}
+}
+
+// GetSkills returns the character's proficiency in each skill they know
+func (self *Character) GetSkills() map[string]int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	skills := map[string]int{}
+	for name, proficiency := range self.Skills {
+		skills[name] = proficiency
+	}
+
+	return skills
+}
+
+// GetSkill returns the character's proficiency in the skill, which is zero
+// if they don't know it
+func (self *Character) GetSkill(name string) int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Skills[strings.ToLower(name)]
+}
+
+func (self *Character) SetSkill(name string, proficiency int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if proficiency > MaxProficiency {
+		proficiency = MaxProficiency
+	}
+
+	name = strings.ToLower(name)
+
+	if proficiency != self.Skills[name] {
+		if self.Skills == nil {
+			self.Skills = map[string]int{}
+		}
+
+		self.Skills[name] = proficiency
+		modified(self)
+	}
+}
+
+func (self *Character) SetStrength(strength int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if strength != self.Strength {
+		self.Strength = strength
+		modified(self)
+	}
+}
+
+// GetStrength returns the character's strength. Characters that were saved
+// before stats existed are treated as having the default value.
+func (self *Character) GetStrength() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Strength == 0 {
+		return DefaultStat
+	}
+
+	return self.Strength
+}
+
+func (self *Character) SetDexterity(dexterity int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if dexterity != self.Dexterity {
+		self.Dexterity = dexterity
+		modified(self)
+	}
+}
+
+// GetDexterity returns the character's dexterity. Characters that were saved
+// before stats existed are treated as having the default value.
+func (self *Character) GetDexterity() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Dexterity == 0 {
+		return DefaultStat
+	}
+
+	return self.Dexterity
+}
+
+func (self *Character) IsDead() bool {
+	return self.GetHitPoints() <= 0
+}
+
+func (self *Character) GetRoaming() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Roaming
+}
+
+func (self *Character) SetRoaming(roaming bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Roaming = roaming
+	modified(self)
+}
+
+// GetBehavior returns the NPC's behavior. NPCs that haven't been given one
+// wander if they are roaming and stay put otherwise.
+func (self *Character) GetBehavior() Behavior {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Behavior == "" {
+		if self.Roaming {
+			return BehaviorWander
+		}
+		return BehaviorIdle
+	}
+
+	return self.Behavior
+}
+
+func (self *Character) SetBehavior(behavior Behavior) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if behavior != self.Behavior {
+		self.Behavior = behavior
+		modified(self)
+	}
+}
+
+func (self *Character) GetPatrolRoute() []bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.PatrolRoute
+}
+
+func (self *Character) SetPatrolRoute(route []bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.PatrolRoute = route
+	modified(self)
+}
+
+func (self *Character) GetFleeHealth() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.FleeHealth
+}
+
+func (self *Character) SetFleeHealth(percent int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if percent != self.FleeHealth {
+		self.FleeHealth = percent
+		modified(self)
+	}
+}
+
+func CharacterNames(characters []*Character) []string {
+	names := make([]string, len(characters))
+
+	for i, char := range characters {
+		names[i] = char.GetName()
+	}
+
+	return names
+}
+
+func (self *Character) GetScriptId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.ScriptId
+}
+
+func (self *Character) SetScriptId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.ScriptId {
+		self.ScriptId = id
+		modified(self)
+	}
 }
 
 // vim: nocindent
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strings"
+)
+
+// Class gives a new character their starting stats and skills
+type Class struct {
+	Name        string
+	Description string
+	Health      int
+	Strength    int
+	Dexterity   int
+	Movement    int
+	Mana        int
+	Skills      []string
+	Spells      []string
+}
+
+var Classes = []Class{
+	{
+		Name:        "Warrior",
+		Description: "Tough fighters who hit hard",
+		Health:      120,
+		Strength:    14,
+		Dexterity:   10,
+		Movement:    100,
+		Mana:        20,
+		Skills:      []string{"kick", "bash", "enhanced damage"},
+	},
+	{
+		Name:        "Rogue",
+		Description: "Quick on their feet and deadly from behind",
+		Health:      90,
+		Strength:    10,
+		Dexterity:   14,
+		Movement:    120,
+		Mana:        40,
+		Skills:      []string{"kick", "backstab", "dodge"},
+		Spells:      []string{"poison"},
+	},
+	{
+		Name:        "Mage",
+		Description: "Frail, but with powerful magic",
+		Health:      80,
+		Strength:    8,
+		Dexterity:   12,
+		Movement:    80,
+		Mana:        150,
+		Skills:      []string{"dodge"},
+		Spells:      []string{"magic missile", "weaken", "stone skin"},
+	},
+	{
+		Name:        "Cleric",
+		Description: "Healers who make their friends stronger",
+		Health:      100,
+		Strength:    10,
+		Dexterity:   10,
+		Movement:    100,
+		Mana:        120,
+		Skills:      []string{"kick"},
+		Spells:      []string{"heal", "regenerate", "giant strength"},
+	},
+}
+
+// FindClass returns the class with the given name, or nil if there isn't one
+func FindClass(name string) *Class {
+	for i, class := range Classes {
+		if strings.EqualFold(class.Name, name) {
+			return &Classes[i]
+		}
+	}
+
+	return nil
+}
+
+// vim: nocindent
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"sync"
 )
 
 type Session interface {
@@ -32,7 +33,8 @@ type Iterator interface {
 }
 
 var modifiedObjects map[Identifiable]bool
-var modifiedObjectChannel chan Identifiable
+var modifiedMutex sync.Mutex
+var modifiedSignal chan bool
 
 var session Session
 
@@ -40,18 +42,36 @@ func Init(s Session) {
 	session = s
 
 	modifiedObjects = make(map[Identifiable]bool)
-	modifiedObjectChannel = make(chan Identifiable, 10)
+	modifiedSignal = make(chan bool, 1)
 	go watchModifiedObjects()
 }
 
+// modified marks the object as needing to be written to the database. It
+// never blocks since callers are often holding the object's write lock, which
+// the committing goroutine needs in order to read the object.
 func modified(obj Identifiable) {
-	modifiedObjectChannel <- obj
+	modifiedMutex.Lock()
+	modifiedObjects[obj] = true
+	modifiedMutex.Unlock()
+
+	select {
+	case modifiedSignal <- true:
+	default:
+	}
 }
 
 func watchModifiedObjects() {
 	for {
-		obj := <-modifiedObjectChannel
-		commitObject(obj)
+		<-modifiedSignal
+
+		modifiedMutex.Lock()
+		objects := modifiedObjects
+		modifiedObjects = make(map[Identifiable]bool)
+		modifiedMutex.Unlock()
+
+		for obj := range objects {
+			commitObject(obj)
+		}
 	}
 }
 
@@ -77,6 +97,16 @@ func getCollectionFromType(t objectType) Collection {
 		return getCollection(cRooms)
 	case ItemType:
 		return getCollection(cItems)
+	case SpawnerType:
+		return getCollection(cSpawners)
+	case TransactionType:
+		return getCollection(cTransactions)
+	case ScriptType:
+		return getCollection(cScripts)
+	case QuestType:
+		return getCollection(cQuests)
+	case MailType:
+		return getCollection(cMail)
 	default:
 		panic("database.getCollectionFromType: Unhandled object type")
 	}
@@ -86,12 +116,17 @@ type collectionName string
 
 // Collection names
 const (
-	cUsers      = collectionName("users")
-	cCharacters = collectionName("characters")
-	cRooms      = collectionName("rooms")
-	cZones      = collectionName("zones")
-	cItems      = collectionName("items")
-	cAreas      = collectionName("areas")
+	cUsers        = collectionName("users")
+	cCharacters   = collectionName("characters")
+	cRooms        = collectionName("rooms")
+	cZones        = collectionName("zones")
+	cItems        = collectionName("items")
+	cAreas        = collectionName("areas")
+	cSpawners     = collectionName("spawners")
+	cTransactions = collectionName("transactions")
+	cScripts      = collectionName("scripts")
+	cQuests       = collectionName("quests")
+	cMail         = collectionName("mail")
 )
 
 // Field names
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"fmt"
+)
+
+type ConditionType string
+
+const (
+	ConditionHasItem ConditionType = "has item"
+	ConditionCash    ConditionType = "cash at least"
+	ConditionFlag    ConditionType = "flag set"
+	ConditionNoFlag  ConditionType = "flag not set"
+	ConditionQuest   ConditionType = "quest complete"
+)
+
+var ConditionTypes = []ConditionType{
+	ConditionHasItem,
+	ConditionCash,
+	ConditionFlag,
+	ConditionNoFlag,
+	ConditionQuest,
+}
+
+// UsesAmount returns true if the condition checks a number rather than a name
+func (self ConditionType) UsesAmount() bool {
+	return self == ConditionCash
+}
+
+// Condition is a requirement that a character must meet before a topic or a
+// dialogue choice is available to them
+type Condition struct {
+	Type   ConditionType
+	Value  string // Name of the item, flag or quest
+	Amount int
+}
+
+func (self Condition) String() string {
+	if self.Type.UsesAmount() {
+		return fmt.Sprintf("%s %v", self.Type, self.Amount)
+	}
+	return fmt.Sprintf("%s %s", self.Type, self.Value)
+}
+
+type EffectType string
+
+const (
+	EffectGiveItem  EffectType = "give item"
+	EffectTakeItem  EffectType = "take item"
+	EffectGiveCash  EffectType = "give cash"
+	EffectTakeCash  EffectType = "take cash"
+	EffectSetFlag   EffectType = "set flag"
+	EffectClearFlag EffectType = "clear flag"
+	EffectQuest     EffectType = "start quest"
+)
+
+var EffectTypes = []EffectType{
+	EffectGiveItem,
+	EffectTakeItem,
+	EffectGiveCash,
+	EffectTakeCash,
+	EffectSetFlag,
+	EffectClearFlag,
+	EffectQuest,
+}
+
+// UsesAmount returns true if the effect works with a number rather than a name
+func (self EffectType) UsesAmount() bool {
+	return self == EffectGiveCash || self == EffectTakeCash
+}
+
+// Effect is something that happens to a character as a result of a choice
+// they made. Items and cash move between the character and the NPC.
+type Effect struct {
+	Type   EffectType
+	Value  string // Name of the item, flag or quest
+	Amount int
+}
+
+func (self Effect) String() string {
+	if self.Type.UsesAmount() {
+		return fmt.Sprintf("%s %v", self.Type, self.Amount)
+	}
+	return fmt.Sprintf("%s %s", self.Type, self.Value)
+}
+
+// Topic is something a player can ask an NPC about
+type Topic struct {
+	Keyword    string
+	Response   string
+	Conditions []Condition
+}
+
+type DialogueChoice struct {
+	Text       string
+	Next       int // Number of the node this choice leads to, 0 ends the dialogue
+	Conditions []Condition
+	Effects    []Effect
+}
+
+// DialogueNode is a single step of a conversation. Dialogue always starts at
+// the first node.
+type DialogueNode struct {
+	Text    string
+	Choices []DialogueChoice
+}
+
+// vim: nocindent
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+)
+
+// DoorState describes the door on an exit. Exits without a door have an
+// empty state and are always open.
+type DoorState string
+
+const (
+	DoorNone   DoorState = ""
+	DoorOpen   DoorState = "open"
+	DoorClosed DoorState = "closed"
+	DoorLocked DoorState = "locked"
+)
+
+const DefaultPickDifficulty = 50
+
+// Exit is a way out of a room. Compass exits lead to the neighbouring room
+// unless they are linked to a destination, which may be in any zone. Named
+// exits always have a destination.
+type Exit struct {
+	Destination bson.ObjectId `bson:",omitempty"`
+
+	Door       DoorState `bson:",omitempty"`
+	Key        string    `bson:",omitempty"` // Code shared with the keys that fit the door's lock
+	Difficulty int       `bson:",omitempty"` // Percent chance of failing to pick the lock
+	Hidden     bool      `bson:",omitempty"`
+}
+
+func (self Exit) HasDoor() bool {
+	return self.Door != DoorNone
+}
+
+func (self Exit) HasLock() bool {
+	return self.HasDoor() && self.Key != ""
+}
+
+// IsOpen returns true if characters can pass through the exit
+func (self Exit) IsOpen() bool {
+	return self.Door == DoorNone || self.Door == DoorOpen
+}
+
+func (self Exit) IsLocked() bool {
+	return self.Door == DoorLocked
+}
+
+// IsLinked returns true if the exit leads to a specific room rather than the
+// neighbouring one
+func (self Exit) IsLinked() bool {
+	return self.Destination != ""
+}
+
+func exitKey(dir Direction) string {
+	return DirectionToString(dir)
+}
+
+// vim: nocindent
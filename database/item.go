@@ -2,12 +2,114 @@ package database
 
 import (
 	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"strconv"
+	"time"
 )
 
+type ItemKind string
+
+const (
+	ItemKindGeneric   ItemKind = "generic"
+	ItemKindWeapon    ItemKind = "weapon"
+	ItemKindArmor     ItemKind = "armor"
+	ItemKindContainer ItemKind = "container"
+	ItemKindFood      ItemKind = "food"
+	ItemKindKey       ItemKind = "key"
+	ItemKindLight     ItemKind = "light"
+	ItemKindCurrency  ItemKind = "currency"
+)
+
+var ItemKinds = []ItemKind{
+	ItemKindGeneric,
+	ItemKindWeapon,
+	ItemKindArmor,
+	ItemKindContainer,
+	ItemKindFood,
+	ItemKindKey,
+	ItemKindLight,
+	ItemKindCurrency,
+}
+
+const (
+	PropertyWeight     = "weight"
+	PropertyValue      = "value"
+	PropertyDamage     = "damage" // Dice notation, e.g. 1d8+1
+	PropertyArmorClass = "armor"
+	PropertyCharges    = "charges"
+	PropertySlot       = "slot" // Where a piece of armor is worn
+	PropertyCapacity   = "capacity"
+	PropertyMaxWeight  = "maxweight"
+	PropertyKey        = "key" // Code shared between a key and the things it unlocks
+)
+
+type EquipmentSlot string
+
+const (
+	SlotHead    EquipmentSlot = "head"
+	SlotBody    EquipmentSlot = "body"
+	SlotHands   EquipmentSlot = "hands"
+	SlotLegs    EquipmentSlot = "legs"
+	SlotFeet    EquipmentSlot = "feet"
+	SlotWielded EquipmentSlot = "wielded"
+	SlotHeld    EquipmentSlot = "held"
+)
+
+var EquipmentSlots = []EquipmentSlot{
+	SlotHead,
+	SlotBody,
+	SlotHands,
+	SlotLegs,
+	SlotFeet,
+	SlotWielded,
+	SlotHeld,
+}
+
+// ArmorSlots are the slots that armor can be worn in
+var ArmorSlots = []EquipmentSlot{
+	SlotHead,
+	SlotBody,
+	SlotHands,
+	SlotLegs,
+	SlotFeet,
+}
+
+// Properties returns the names of the properties that are meaningful for
+// items of this kind
+func (self ItemKind) Properties() []string {
+	properties := []string{PropertyWeight, PropertyValue}
+
+	switch self {
+	case ItemKindWeapon:
+		properties = append(properties, PropertyDamage)
+	case ItemKindArmor:
+		properties = append(properties, PropertyArmorClass, PropertySlot)
+	case ItemKindLight, ItemKindFood:
+		properties = append(properties, PropertyCharges)
+	case ItemKindContainer:
+		properties = append(properties, PropertyCapacity, PropertyMaxWeight, PropertyKey)
+	case ItemKindKey:
+		properties = append(properties, PropertyKey)
+	}
+
+	return properties
+}
+
 type Item struct {
 	DbObject `bson:",inline"`
 
-	Name string
+	Name        string
+	Description string   `bson:",omitempty"`
+	Kind        ItemKind `bson:",omitempty"`
+	Properties  map[string]string
+	Contents    []bson.ObjectId
+	Cash        int
+	Decay       time.Time
+	Locked      bool `bson:",omitempty"`
+
+	Template   bool          `bson:",omitempty"`
+	TemplateId bson.ObjectId `bson:",omitempty"`
+	ScriptId   bson.ObjectId `bson:",omitempty"`
 }
 
 func NewItem(name string) *Item {
@@ -15,11 +117,68 @@ func NewItem(name string) *Item {
 	item.initDbObject()
 
 	item.Name = utils.FormatName(name)
+	item.Kind = ItemKindGeneric
 
 	modified(&item)
 	return &item
 }
 
+func NewItemTemplate(name string) *Item {
+	item := NewItem(name)
+	item.Template = true
+	return item
+}
+
+// NewItemFromTemplate creates a new item that is a copy of the template
+func NewItemFromTemplate(template *Item) *Item {
+	item := NewItem(template.GetName())
+	item.TemplateId = template.GetId()
+	item.CopyFromTemplate(template)
+	return item
+}
+
+// CopyFromTemplate overwrites the item's name, description, type, properties
+// and script with those of the template
+func (self *Item) CopyFromTemplate(template *Item) {
+	template.ReadLock()
+	name := template.Name
+	description := template.Description
+	kind := template.Kind
+	locked := template.Locked
+	scriptId := template.ScriptId
+
+	properties := map[string]string{}
+	for key, value := range template.Properties {
+		properties[key] = value
+	}
+	template.ReadUnlock()
+
+	self.WriteLock()
+	self.Name = name
+	self.Description = description
+	self.Kind = kind
+	self.Locked = locked
+	self.ScriptId = scriptId
+	self.Properties = properties
+	self.WriteUnlock()
+
+	modified(self)
+}
+
+func (self *Item) IsTemplate() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Template
+}
+
+func (self *Item) GetTemplateId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.TemplateId
+}
+
 func (self *Item) GetType() objectType {
 	return ItemType
 }
@@ -31,6 +190,244 @@ func (self *Item) GetName() string {
 	return self.Name
 }
 
+func (self *Item) SetName(name string) {
+	if name != self.GetName() {
+		self.WriteLock()
+		self.Name = utils.FormatName(name)
+		self.WriteUnlock()
+		modified(self)
+	}
+}
+
+func (self *Item) GetDescription() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Description
+}
+
+func (self *Item) SetDescription(description string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if description != self.Description {
+		self.Description = description
+		modified(self)
+	}
+}
+
+// GetKind returns the kind of the item. Items that were saved before items
+// had kinds are generic.
+func (self *Item) GetKind() ItemKind {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Kind == "" {
+		return ItemKindGeneric
+	}
+
+	return self.Kind
+}
+
+func (self *Item) SetKind(kind ItemKind) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if kind != self.Kind {
+		self.Kind = kind
+		modified(self)
+	}
+}
+
+func (self *Item) SetProperty(name, value string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if self.Properties == nil {
+		self.Properties = map[string]string{}
+	}
+
+	if self.Properties[name] != value {
+		self.Properties[name] = value
+		modified(self)
+	}
+}
+
+func (self *Item) GetProperty(name string) string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Properties[name]
+}
+
+func (self *Item) GetProperties() map[string]string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Properties
+}
+
+func (self *Item) RemoveProperty(key string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	delete(self.Properties, key)
+	modified(self)
+}
+
+// GetIntProperty returns the value of a numeric property, or zero if the
+// property isn't set or isn't a number
+func (self *Item) GetIntProperty(name string) int {
+	value, err := strconv.Atoi(self.GetProperty(name))
+
+	if err != nil {
+		return 0
+	}
+
+	return value
+}
+
+func (self *Item) SetIntProperty(name string, value int) {
+	self.SetProperty(name, strconv.Itoa(value))
+}
+
+func (self *Item) GetWeight() int {
+	return self.GetIntProperty(PropertyWeight)
+}
+
+func (self *Item) GetValue() int {
+	return self.GetIntProperty(PropertyValue)
+}
+
+// EquipSlot returns the slot the item goes in when it is equipped. Weapons are
+// wielded, lights are held and armor is worn wherever its slot property says,
+// defaulting to the body. Returns false if the item can't be equipped.
+func (self *Item) EquipSlot() (EquipmentSlot, bool) {
+	switch self.GetKind() {
+	case ItemKindWeapon:
+		return SlotWielded, true
+	case ItemKindLight:
+		return SlotHeld, true
+	case ItemKindArmor:
+		slot := EquipmentSlot(self.GetProperty(PropertySlot))
+
+		for _, armorSlot := range ArmorSlots {
+			if slot == armorSlot {
+				return slot, true
+			}
+		}
+
+		return SlotBody, true
+	}
+
+	return "", false
+}
+
+func (self *Item) AddItem(item *Item) {
+	if !self.HasItem(item) {
+		self.WriteLock()
+		defer self.WriteUnlock()
+
+		self.Contents = append(self.Contents, item.GetId())
+		modified(self)
+	}
+}
+
+func (self *Item) RemoveItem(item *Item) {
+	if self.HasItem(item) {
+		self.WriteLock()
+		defer self.WriteUnlock()
+
+		for i, itemId := range self.Contents {
+			if itemId == item.GetId() {
+				// TODO: Potential memory leak. See http://code.google.com/p/go-wiki/wiki/SliceTricks
+				self.Contents = append(self.Contents[:i], self.Contents[i+1:]...)
+				break
+			}
+		}
+
+		modified(self)
+	}
+}
+
+func (self *Item) HasItem(item *Item) bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	for _, itemId := range self.Contents {
+		if itemId == item.GetId() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (self *Item) GetItemIds() []bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Contents
+}
+
+func (self *Item) SetCash(cash int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if cash != self.Cash {
+		self.Cash = cash
+		modified(self)
+	}
+}
+
+func (self *Item) GetCash() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Cash
+}
+
+// IsContainer returns true if other items can be put inside the item
+func (self *Item) IsContainer() bool {
+	return self.GetKind() == ItemKindContainer
+}
+
+func (self *Item) SetLocked(locked bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if locked != self.Locked {
+		self.Locked = locked
+		modified(self)
+	}
+}
+
+func (self *Item) IsLocked() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Locked
+}
+
+// SetDecay sets the time at which the item should crumble away. A zero time
+// means that the item never decays.
+func (self *Item) SetDecay(decay time.Time) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if decay != self.Decay {
+		self.Decay = decay
+		modified(self)
+	}
+}
+
+func (self *Item) GetDecay() time.Time {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Decay
+}
+
 func ItemNames(items []*Item) []string {
 	names := make([]string, len(items))
 
@@ -41,4 +438,21 @@ func ItemNames(items []*Item) []string {
 	return names
 }
 
+func (self *Item) GetScriptId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.ScriptId
+}
+
+func (self *Item) SetScriptId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.ScriptId {
+		self.ScriptId = id
+		modified(self)
+	}
+}
+
 // vim: nocindent
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Mail is a message sent from one character to another that waits in the
+// recipient's mailbox until they delete it. Cash and items can be attached,
+// and are held by the mail until the recipient collects them.
+type Mail struct {
+	DbObject `bson:",inline"`
+
+	FromId   bson.ObjectId
+	FromName string
+	ToId     bson.ObjectId
+	Subject  string
+	Body     string
+	Sent     time.Time
+	Read     bool
+	Cash     int             `bson:",omitempty"`
+	Items    []bson.ObjectId `bson:",omitempty"`
+}
+
+type MailByTime []*Mail
+
+func (self MailByTime) Len() int {
+	return len(self)
+}
+
+func (self MailByTime) Less(i, j int) bool {
+	return self[i].GetSent().Before(self[j].GetSent())
+}
+
+func (self MailByTime) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func NewMail(from *Character, to *Character, subject string, body string) *Mail {
+	var mail Mail
+	mail.initDbObject()
+
+	mail.FromId = from.GetId()
+	mail.FromName = from.GetName()
+	mail.ToId = to.GetId()
+	mail.Subject = subject
+	mail.Body = body
+	mail.Sent = time.Now()
+
+	modified(&mail)
+	return &mail
+}
+
+func (self *Mail) GetType() objectType {
+	return MailType
+}
+
+func (self *Mail) GetFromId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.FromId
+}
+
+// GetFromName returns the name of the sender when the mail was sent, so that
+// it can still be shown if the sender has since been deleted
+func (self *Mail) GetFromName() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.FromName
+}
+
+func (self *Mail) GetToId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.ToId
+}
+
+func (self *Mail) GetSubject() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Subject
+}
+
+func (self *Mail) GetBody() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Body
+}
+
+func (self *Mail) GetSent() time.Time {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Sent
+}
+
+func (self *Mail) IsRead() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Read
+}
+
+func (self *Mail) SetRead(read bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if read != self.Read {
+		self.Read = read
+		modified(self)
+	}
+}
+
+func (self *Mail) GetCash() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Cash
+}
+
+func (self *Mail) SetCash(cash int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if cash != self.Cash {
+		self.Cash = cash
+		modified(self)
+	}
+}
+
+func (self *Mail) GetItemIds() []bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return append([]bson.ObjectId(nil), self.Items...)
+}
+
+func (self *Mail) SetItemIds(items []bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Items = items
+	modified(self)
+}
+
+// HasAttachments returns true if there is cash or items waiting to be
+// collected from the mail
+func (self *Mail) HasAttachments() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Cash > 0 || len(self.Items) > 0
+}
+
+// vim: nocindent
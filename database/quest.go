@@ -0,0 +1,154 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+)
+
+type ObjectiveType string
+
+const (
+	ObjectiveKill    ObjectiveType = "kill"
+	ObjectiveCollect ObjectiveType = "collect"
+	ObjectiveVisit   ObjectiveType = "visit"
+	ObjectiveTalk    ObjectiveType = "talk to"
+)
+
+var ObjectiveTypes = []ObjectiveType{
+	ObjectiveKill,
+	ObjectiveCollect,
+	ObjectiveVisit,
+	ObjectiveTalk,
+}
+
+// Objective is one of the things a player has to do to complete a quest. The
+// target is an NPC template for kill and talk objectives, an item template
+// for collect objectives and a room for visit objectives.
+type Objective struct {
+	Type     ObjectiveType
+	TargetId bson.ObjectId
+	Count    int
+}
+
+// Reward is paid out to a player when they complete a quest. Items are
+// created from the given item templates.
+type Reward struct {
+	Cash       int
+	Experience int
+	Items      []bson.ObjectId `bson:",omitempty"`
+}
+
+type Quest struct {
+	DbObject `bson:",inline"`
+
+	Name        string
+	Description string
+	Ordered     bool // Objectives must be completed one after another
+	Objectives  []Objective
+	Reward      Reward
+}
+
+// QuestProgress is a player's record of a quest they have started
+type QuestProgress struct {
+	QuestId  bson.ObjectId
+	Counts   []int // How far the player has got with each of the quest's objectives
+	Complete bool
+}
+
+func NewQuest(name string) *Quest {
+	var quest Quest
+	quest.initDbObject()
+
+	quest.Name = name
+
+	modified(&quest)
+	return &quest
+}
+
+func (self *Quest) GetType() objectType {
+	return QuestType
+}
+
+func (self *Quest) GetName() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Name
+}
+
+func (self *Quest) SetName(name string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if name != self.Name {
+		self.Name = name
+		modified(self)
+	}
+}
+
+func (self *Quest) GetDescription() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Description
+}
+
+func (self *Quest) SetDescription(description string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if description != self.Description {
+		self.Description = description
+		modified(self)
+	}
+}
+
+func (self *Quest) IsOrdered() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Ordered
+}
+
+func (self *Quest) SetOrdered(ordered bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if ordered != self.Ordered {
+		self.Ordered = ordered
+		modified(self)
+	}
+}
+
+func (self *Quest) GetObjectives() []Objective {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return append([]Objective(nil), self.Objectives...)
+}
+
+func (self *Quest) SetObjectives(objectives []Objective) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Objectives = objectives
+	modified(self)
+}
+
+func (self *Quest) GetReward() Reward {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	reward := self.Reward
+	reward.Items = append([]bson.ObjectId(nil), self.Reward.Items...)
+	return reward
+}
+
+func (self *Quest) SetReward(reward Reward) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Reward = reward
+	modified(self)
+}
+
+// vim: nocindent
@@ -8,25 +8,40 @@ import (
 	"strings"
 )
 
+// RoomPropertyRegeneration scales how quickly characters recover in the room,
+// as a percentage of the normal rate, e.g. 200 in an inn or 0 in a swamp
+const RoomPropertyRegeneration = "regeneration"
+
+// RoomPropertyPostOffice marks a room where mail attachments can be collected
+// when set to "true"
+const RoomPropertyPostOffice = "postoffice"
+
 type Room struct {
 	DbObject `bson:",inline"`
 
-	ZoneId        bson.ObjectId
-	AreaId        bson.ObjectId `bson:",omitempty"`
-	Title         string
-	Description   string
-	Items         []bson.ObjectId
-	Location      Coordinate
-	ExitNorth     bool
-	ExitNorthEast bool
-	ExitEast      bool
-	ExitSouthEast bool
-	ExitSouth     bool
-	ExitSouthWest bool
-	ExitWest      bool
-	ExitNorthWest bool
-	ExitUp        bool
-	ExitDown      bool
+	ZoneId      bson.ObjectId
+	AreaId      bson.ObjectId `bson:",omitempty"`
+	Title       string
+	Description string
+	Items       []bson.ObjectId
+	Location    Coordinate
+	Exits       map[string]Exit `bson:",omitempty"`
+	NamedExits  map[string]Exit `bson:",omitempty"` // Keyed by the command used to take them, e.g. "enter portal"
+	Triggers    []Trigger       `bson:",omitempty"`
+	ScriptId    bson.ObjectId   `bson:",omitempty"`
+
+	// Exits used to be stored as one flag per direction. These are only read
+	// so that MigrateExits can convert older rooms.
+	ExitNorth     bool `bson:",omitempty"`
+	ExitNorthEast bool `bson:",omitempty"`
+	ExitEast      bool `bson:",omitempty"`
+	ExitSouthEast bool `bson:",omitempty"`
+	ExitSouth     bool `bson:",omitempty"`
+	ExitSouthWest bool `bson:",omitempty"`
+	ExitWest      bool `bson:",omitempty"`
+	ExitNorthWest bool `bson:",omitempty"`
+	ExitUp        bool `bson:",omitempty"`
+	ExitDown      bool `bson:",omitempty"`
 
 	Properties map[string]string
 }
@@ -40,17 +55,6 @@ func NewRoom(zoneId bson.ObjectId, location Coordinate) *Room {
 		"you in all directions. There is no escape, there is no hope, just the emptiness. " +
 		"You are likely to be eaten by a grue."
 
-	room.ExitNorth = false
-	room.ExitNorthEast = false
-	room.ExitEast = false
-	room.ExitSouthEast = false
-	room.ExitSouth = false
-	room.ExitSouthWest = false
-	room.ExitWest = false
-	room.ExitNorthWest = false
-	room.ExitUp = false
-	room.ExitDown = false
-
 	room.Location = location
 	room.ZoneId = zoneId
 
@@ -140,7 +144,26 @@ func (self *Room) ToString(players []*Character, npcs []*Character, items []*Ite
 
 	var exitList []string
 	for _, direction := range self.GetExits() {
-		exitList = append(exitList, directionToExitString(direction))
+		exit, _ := self.GetExit(direction)
+
+		if exit.Hidden {
+			continue
+		}
+
+		exitString := directionToExitString(direction)
+		if !exit.IsOpen() {
+			exitString = exitString + utils.Colorize(utils.ColorDarkBlue, "(closed)")
+		}
+
+		exitList = append(exitList, exitString)
+	}
+
+	for _, name := range self.GetNamedExits() {
+		exit, _ := self.GetNamedExit(name)
+
+		if !exit.Hidden {
+			exitList = append(exitList, utils.Colorize(utils.ColorYellow, name))
+		}
 	}
 
 	if len(exitList) == 0 {
@@ -158,62 +181,161 @@ func (self *Room) HasExit(dir Direction) bool {
 	self.ReadLock()
 	defer self.ReadUnlock()
 
-	switch dir {
-	case DirectionNorth:
-		return self.ExitNorth
-	case DirectionNorthEast:
-		return self.ExitNorthEast
-	case DirectionEast:
-		return self.ExitEast
-	case DirectionSouthEast:
-		return self.ExitSouthEast
-	case DirectionSouth:
-		return self.ExitSouth
-	case DirectionSouthWest:
-		return self.ExitSouthWest
-	case DirectionWest:
-		return self.ExitWest
-	case DirectionNorthWest:
-		return self.ExitNorthWest
-	case DirectionUp:
-		return self.ExitUp
-	case DirectionDown:
-		return self.ExitDown
+	_, found := self.Exits[exitKey(dir)]
+	return found
+}
+
+func (self *Room) SetExitEnabled(dir Direction, enabled bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	_, found := self.Exits[exitKey(dir)]
+
+	if enabled && !found {
+		if self.Exits == nil {
+			self.Exits = map[string]Exit{}
+		}
+		self.Exits[exitKey(dir)] = Exit{}
+		modified(self)
+	} else if !enabled && found {
+		delete(self.Exits, exitKey(dir))
+		modified(self)
 	}
+}
 
-	panic("Unexpected code path")
+// GetExit returns the exit in the given direction, and false if there isn't one
+func (self *Room) GetExit(dir Direction) (Exit, bool) {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	exit, found := self.Exits[exitKey(dir)]
+	return exit, found
 }
 
-func (self *Room) SetExitEnabled(dir Direction, enabled bool) {
+// SetExit replaces the exit in the given direction, enabling it if needed
+func (self *Room) SetExit(dir Direction, exit Exit) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if self.Exits == nil {
+		self.Exits = map[string]Exit{}
+	}
+
+	if self.Exits[exitKey(dir)] != exit {
+		self.Exits[exitKey(dir)] = exit
+		modified(self)
+	}
+}
+
+// GetNamedExit returns the exit taken with the given command, and false if
+// there isn't one
+func (self *Room) GetNamedExit(name string) (Exit, bool) {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	exit, found := self.NamedExits[strings.ToLower(name)]
+	return exit, found
+}
+
+func (self *Room) SetNamedExit(name string, exit Exit) {
 	self.WriteLock()
 	defer self.WriteUnlock()
 
-	switch dir {
-	case DirectionNorth:
-		self.ExitNorth = enabled
-	case DirectionNorthEast:
-		self.ExitNorthEast = enabled
-	case DirectionEast:
-		self.ExitEast = enabled
-	case DirectionSouthEast:
-		self.ExitSouthEast = enabled
-	case DirectionSouth:
-		self.ExitSouth = enabled
-	case DirectionSouthWest:
-		self.ExitSouthWest = enabled
-	case DirectionWest:
-		self.ExitWest = enabled
-	case DirectionNorthWest:
-		self.ExitNorthWest = enabled
-	case DirectionUp:
-		self.ExitUp = enabled
-	case DirectionDown:
-		self.ExitDown = enabled
+	if self.NamedExits == nil {
+		self.NamedExits = map[string]Exit{}
 	}
 
+	self.NamedExits[strings.ToLower(name)] = exit
 	modified(self)
 }
 
+func (self *Room) RemoveNamedExit(name string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	name = strings.ToLower(name)
+
+	if _, found := self.NamedExits[name]; found {
+		delete(self.NamedExits, name)
+		modified(self)
+	}
+}
+
+// GetNamedExits returns the commands used to take the room's named exits, in
+// alphabetical order
+func (self *Room) GetNamedExits() []string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	var names []string
+	for name := range self.NamedExits {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// GetTriggers returns the room's triggers. The returned slice must not be
+// modified, use SetTriggers instead.
+func (self *Room) GetTriggers() []Trigger {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Triggers
+}
+
+func (self *Room) SetTriggers(triggers []Trigger) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	self.Triggers = triggers
+	modified(self)
+}
+
+// MigrateExits converts the per direction exit flags of older rooms into
+// Exits. Returns true if the room needed converting.
+func (self *Room) MigrateExits() bool {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	legacy := map[Direction]*bool{
+		DirectionNorth:     &self.ExitNorth,
+		DirectionNorthEast: &self.ExitNorthEast,
+		DirectionEast:      &self.ExitEast,
+		DirectionSouthEast: &self.ExitSouthEast,
+		DirectionSouth:     &self.ExitSouth,
+		DirectionSouthWest: &self.ExitSouthWest,
+		DirectionWest:      &self.ExitWest,
+		DirectionNorthWest: &self.ExitNorthWest,
+		DirectionUp:        &self.ExitUp,
+		DirectionDown:      &self.ExitDown,
+	}
+
+	migrated := false
+
+	for dir, enabled := range legacy {
+		if *enabled {
+			if self.Exits == nil {
+				self.Exits = map[string]Exit{}
+			}
+
+			if _, found := self.Exits[exitKey(dir)]; !found {
+				self.Exits[exitKey(dir)] = Exit{}
+			}
+
+			*enabled = false
+			migrated = true
+		}
+	}
+
+	if migrated {
+		modified(self)
+	}
+
+	return migrated
+}
+
 func (self *Room) AddItem(item *Item) {
 	if !self.HasItem(item) {
 		self.WriteLock()
@@ -410,4 +532,21 @@ func (self *Room) RemoveProperty(key string) {
 	modified(self)
 }
 
+func (self *Room) GetScriptId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.ScriptId
+}
+
+func (self *Room) SetScriptId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.ScriptId {
+		self.ScriptId = id
+		modified(self)
+	}
+}
+
 // vim: nocindent
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+)
+
+// Script is a piece of Lua that can be attached to rooms, items and NPC
+// templates to give them custom behaviour
+type Script struct {
+	DbObject `bson:",inline"`
+
+	Name   string
+	Source string
+}
+
+func NewScript(name string) *Script {
+	var script Script
+	script.initDbObject()
+
+	script.Name = name
+
+	modified(&script)
+	return &script
+}
+
+func (self *Script) GetType() objectType {
+	return ScriptType
+}
+
+func (self *Script) GetName() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Name
+}
+
+func (self *Script) SetName(name string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if name != self.Name {
+		self.Name = name
+		modified(self)
+	}
+}
+
+func (self *Script) GetSource() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Source
+}
+
+func (self *Script) SetSource(source string) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if source != self.Source {
+		self.Source = source
+		modified(self)
+	}
+}
+
+// Scriptable is implemented by everything a script can be attached to
+type Scriptable interface {
+	Identifiable
+	GetScriptId() bson.ObjectId
+	SetScriptId(bson.ObjectId)
+}
+
+// vim: nocindent
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+)
+
+const (
+	DefaultRestockInterval = 300 // Seconds
+	DefaultMarkup          = 150 // Percent
+	DefaultBuyback         = 50  // Percent
+)
+
+// Shop turns an NPC into a shopkeeper that sells copies of item templates and
+// buys items from players
+type Shop struct {
+	Stock   []StockEntry
+	Restock int // Seconds between restocks
+	Markup  int // Percentage of an item's value charged to players buying it
+	Buyback int // Percentage of an item's value paid to players selling it
+}
+
+// StockEntry keeps a shop supplied with up to Count copies of an item template
+type StockEntry struct {
+	TemplateId bson.ObjectId
+	Count      int
+}
+
+func NewShop() *Shop {
+	return &Shop{
+		Restock: DefaultRestockInterval,
+		Markup:  DefaultMarkup,
+		Buyback: DefaultBuyback,
+	}
+}
+
+// Copy returns a copy of the shop that shares no state with the original
+func (self *Shop) Copy() *Shop {
+	if self == nil {
+		return nil
+	}
+
+	shop := *self
+	shop.Stock = append([]StockEntry(nil), self.Stock...)
+	return &shop
+}
+
+// SellPrice returns what a player pays for an item of the given value
+func (self *Shop) SellPrice(value int) int {
+	price := value * self.Markup / 100
+	if price < 1 {
+		price = 1
+	}
+	return price
+}
+
+// BuyPrice returns what a player is paid for an item of the given value
+func (self *Shop) BuyPrice(value int) int {
+	return value * self.Buyback / 100
+}
+
+// vim: nocindent
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// Proficiency is a percentage. Characters start out with a little skill in
+// everything their class knows and get better with practice.
+const (
+	StartingProficiency = 25
+	MaxProficiency      = 100
+)
+
+type SkillType string
+
+const (
+	SkillActive  SkillType = "active"  // Used as an action, costing movement
+	SkillPassive SkillType = "passive" // Applied automatically during combat
+)
+
+// Skill describes something a character can learn. Skills are defined in code
+// rather than by builders.
+type Skill struct {
+	Name        string
+	Description string
+	Type        SkillType
+	Cost        int           // Movement points used by an active skill
+	Cooldown    time.Duration // Time before an active skill can be used again
+	Damage      string        // Damage dice of an active skill
+	Opener      bool          // Can only be used to start a fight
+}
+
+var Skills = []Skill{
+	{
+		Name:        "kick",
+		Description: "Kick your opponent",
+		Type:        SkillActive,
+		Cost:        5,
+		Cooldown:    6 * time.Second,
+		Damage:      "1d6",
+	},
+	{
+		Name:        "bash",
+		Description: "Slam in to your opponent with your full weight",
+		Type:        SkillActive,
+		Cost:        10,
+		Cooldown:    12 * time.Second,
+		Damage:      "2d6",
+	},
+	{
+		Name:        "backstab",
+		Description: "Stab an unsuspecting victim in the back",
+		Type:        SkillActive,
+		Cost:        15,
+		Cooldown:    30 * time.Second,
+		Damage:      "3d8",
+		Opener:      true,
+	},
+	{
+		Name:        "enhanced damage",
+		Description: "Hit harder with every attack",
+		Type:        SkillPassive,
+	},
+	{
+		Name:        "dodge",
+		Description: "Step out of the way of attacks",
+		Type:        SkillPassive,
+	},
+}
+
+// FindSkill returns the skill with the given name, or nil if there isn't one
+func FindSkill(name string) *Skill {
+	for i, skill := range Skills {
+		if strings.EqualFold(skill.Name, name) {
+			return &Skills[i]
+		}
+	}
+
+	return nil
+}
+
+// vim: nocindent
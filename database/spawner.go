@@ -0,0 +1,120 @@
+package database
+
+import (
+	"labix.org/v2/mgo/bson"
+)
+
+// Spawner is a rule that keeps a population of NPCs created from a template
+// alive in either a single room or anywhere within an area
+type Spawner struct {
+	DbObject `bson:",inline"`
+
+	TemplateId bson.ObjectId
+	RoomId     bson.ObjectId `bson:",omitempty"`
+	AreaId     bson.ObjectId `bson:",omitempty"`
+	Count      int
+	Interval   int // Seconds to wait between respawns
+	Roaming    bool
+}
+
+func NewSpawner(templateId bson.ObjectId, roomId bson.ObjectId, areaId bson.ObjectId) *Spawner {
+	var spawner Spawner
+	spawner.initDbObject()
+
+	spawner.TemplateId = templateId
+	spawner.RoomId = roomId
+	spawner.AreaId = areaId
+	spawner.Count = 1
+	spawner.Interval = 60
+
+	modified(&spawner)
+	return &spawner
+}
+
+func (self *Spawner) GetType() objectType {
+	return SpawnerType
+}
+
+func (self *Spawner) SetTemplateId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.TemplateId {
+		self.TemplateId = id
+		modified(self)
+	}
+}
+
+func (self *Spawner) GetTemplateId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.TemplateId
+}
+
+func (self *Spawner) GetRoomId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.RoomId
+}
+
+func (self *Spawner) GetAreaId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.AreaId
+}
+
+func (self *Spawner) SetCount(count int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if count != self.Count {
+		self.Count = count
+		modified(self)
+	}
+}
+
+func (self *Spawner) GetCount() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Count
+}
+
+func (self *Spawner) SetInterval(interval int) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if interval != self.Interval {
+		self.Interval = interval
+		modified(self)
+	}
+}
+
+func (self *Spawner) GetInterval() int {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Interval
+}
+
+func (self *Spawner) SetRoaming(roaming bool) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if roaming != self.Roaming {
+		self.Roaming = roaming
+		modified(self)
+	}
+}
+
+func (self *Spawner) GetRoaming() bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.Roaming
+}
+
+// vim: nocindent
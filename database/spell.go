@@ -0,0 +1,48 @@
+package database
+
+import (
+	"strings"
+)
+
+type SpellTarget string
+
+const (
+	SpellSelf      SpellTarget = "self"      // Can only be cast on the caster
+	SpellDefensive SpellTarget = "defensive" // Cast on the caster unless someone else is named
+	SpellOffensive SpellTarget = "offensive" // Cast on an enemy, starting a fight with them
+)
+
+// Spell is something a character can cast using mana. It can do damage, heal
+// and apply a status effect. Spells are defined in code rather than by
+// builders.
+type Spell struct {
+	Name   string
+	Target SpellTarget
+	Mana   int
+	Damage string // Damage dice
+	Heal   string // Healing dice
+	Status string // Name of the status effect applied to the target
+}
+
+var Spells = []Spell{
+	{Name: "magic missile", Target: SpellOffensive, Mana: 10, Damage: "2d4"},
+	{Name: "poison", Target: SpellOffensive, Mana: 15, Status: "poison"},
+	{Name: "weaken", Target: SpellOffensive, Mana: 15, Status: "weakness"},
+	{Name: "stone skin", Target: SpellSelf, Mana: 25, Status: "stone skin"},
+	{Name: "heal", Target: SpellDefensive, Mana: 15, Heal: "2d8"},
+	{Name: "regenerate", Target: SpellDefensive, Mana: 20, Status: "regeneration"},
+	{Name: "giant strength", Target: SpellDefensive, Mana: 20, Status: "giant strength"},
+}
+
+// FindSpell returns the spell with the given name, or nil if there isn't one
+func FindSpell(name string) *Spell {
+	for i, spell := range Spells {
+		if strings.EqualFold(spell.Name, name) {
+			return &Spells[i]
+		}
+	}
+
+	return nil
+}
+
+// vim: nocindent
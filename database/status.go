@@ -0,0 +1,102 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// StackingRule decides what happens when a status effect is applied to a
+// character that already has it
+type StackingRule string
+
+const (
+	StackRefresh StackingRule = "refresh" // The duration starts again
+	StackAdd     StackingRule = "stack"   // Another stack is added, up to MaxStacks
+	StackNone    StackingRule = "none"    // Nothing happens until it wears off
+)
+
+// Status is a timed effect on a character, such as a buff, a debuff or
+// damage over time. Modifiers and tick damage are multiplied by the number of
+// stacks. Statuses are defined in code rather than by builders.
+type Status struct {
+	Name         string
+	Duration     time.Duration
+	Stacking     StackingRule
+	MaxStacks    int
+	Strength     int
+	Dexterity    int
+	Armor        int
+	TickInterval time.Duration
+	TickDamage   int // Negative values heal
+	StartMessage string
+	EndMessage   string
+}
+
+var Statuses = []Status{
+	{
+		Name:         "poison",
+		Duration:     15 * time.Second,
+		Stacking:     StackAdd,
+		MaxStacks:    3,
+		TickInterval: 3 * time.Second,
+		TickDamage:   3,
+		StartMessage: "You feel very sick",
+		EndMessage:   "The poison wears off",
+	},
+	{
+		Name:         "regeneration",
+		Duration:     15 * time.Second,
+		Stacking:     StackRefresh,
+		TickInterval: 3 * time.Second,
+		TickDamage:   -5,
+		StartMessage: "Your wounds begin to close",
+		EndMessage:   "Your wounds stop closing",
+	},
+	{
+		Name:         "giant strength",
+		Duration:     time.Minute,
+		Stacking:     StackRefresh,
+		Strength:     4,
+		StartMessage: "You feel much stronger",
+		EndMessage:   "Your strength fades",
+	},
+	{
+		Name:         "weakness",
+		Duration:     30 * time.Second,
+		Stacking:     StackRefresh,
+		Strength:     -4,
+		Dexterity:    -2,
+		StartMessage: "You feel weak",
+		EndMessage:   "Your strength returns",
+	},
+	{
+		Name:         "stone skin",
+		Duration:     time.Minute,
+		Stacking:     StackNone,
+		Armor:        3,
+		StartMessage: "Your skin turns to stone",
+		EndMessage:   "Your skin softens",
+	},
+}
+
+// FindStatus returns the status effect with the given name, or nil if there
+// isn't one
+func FindStatus(name string) *Status {
+	for i, status := range Statuses {
+		if strings.EqualFold(status.Name, name) {
+			return &Statuses[i]
+		}
+	}
+
+	return nil
+}
+
+// ActiveStatus is a status effect that is currently on a character
+type ActiveStatus struct {
+	Name     string
+	Stacks   int
+	Expires  time.Time
+	NextTick time.Time `bson:",omitempty"`
+}
+
+// vim: nocindent
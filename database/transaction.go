@@ -0,0 +1,89 @@
+package database
+
+import (
+	"fmt"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Transaction records a movement of cash. Either side may be empty when cash
+// enters or leaves the game, for example when coins are dropped.
+type Transaction struct {
+	DbObject `bson:",inline"`
+
+	FromId   bson.ObjectId `bson:",omitempty"`
+	FromName string        `bson:",omitempty"`
+	ToId     bson.ObjectId `bson:",omitempty"`
+	ToName   string        `bson:",omitempty"`
+	Amount   int
+	Reason   string
+	Time     time.Time
+}
+
+type TransactionsByTime []*Transaction
+
+func (self TransactionsByTime) Len() int {
+	return len(self)
+}
+
+func (self TransactionsByTime) Less(i, j int) bool {
+	return self[i].Time.Before(self[j].Time)
+}
+
+func (self TransactionsByTime) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func NewTransaction(from *Character, to *Character, amount int, reason string) *Transaction {
+	var transaction Transaction
+	transaction.initDbObject()
+
+	if from != nil {
+		transaction.FromId = from.GetId()
+		transaction.FromName = from.GetName()
+	}
+
+	if to != nil {
+		transaction.ToId = to.GetId()
+		transaction.ToName = to.GetName()
+	}
+
+	transaction.Amount = amount
+	transaction.Reason = reason
+	transaction.Time = time.Now()
+
+	modified(&transaction)
+	return &transaction
+}
+
+func (self *Transaction) GetType() objectType {
+	return TransactionType
+}
+
+// Involves returns true if the character was on either side of the transaction
+func (self *Transaction) Involves(character *Character) bool {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.FromId == character.GetId() || self.ToId == character.GetId()
+}
+
+func (self *Transaction) String() string {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	from := self.FromName
+	if from == "" {
+		from = "-"
+	}
+
+	to := self.ToName
+	if to == "" {
+		to = "-"
+	}
+
+	return fmt.Sprintf("%s %s -> %s: %v (%s)", self.Time.Format("2006-01-02 15:04:05"),
+		from, to, self.Amount, self.Reason)
+}
+
+// vim: nocindent
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+)
+
+type TriggerType string
+
+const (
+	TriggerEnter   TriggerType = "enter"
+	TriggerLeave   TriggerType = "leave"
+	TriggerCommand TriggerType = "command"
+	TriggerTimer   TriggerType = "timer"
+)
+
+var TriggerTypes = []TriggerType{
+	TriggerEnter,
+	TriggerLeave,
+	TriggerCommand,
+	TriggerTimer,
+}
+
+type TriggerActionType string
+
+const (
+	ActionMessage     TriggerActionType = "message character"
+	ActionMessageRoom TriggerActionType = "message room"
+	ActionTeleport    TriggerActionType = "teleport"
+	ActionGiveItem    TriggerActionType = "give item"
+	ActionTakeItem    TriggerActionType = "take item"
+	ActionOpenDoor    TriggerActionType = "open door"
+	ActionCloseDoor   TriggerActionType = "close door"
+	ActionSpawn       TriggerActionType = "spawn"
+)
+
+var TriggerActionTypes = []TriggerActionType{
+	ActionMessage,
+	ActionMessageRoom,
+	ActionTeleport,
+	ActionGiveItem,
+	ActionTakeItem,
+	ActionOpenDoor,
+	ActionCloseDoor,
+	ActionSpawn,
+}
+
+// NeedsCharacter returns true if the action does something to the character
+// that fired the trigger, and so is skipped by timer triggers
+func (self TriggerActionType) NeedsCharacter() bool {
+	switch self {
+	case ActionMessage, ActionTeleport, ActionGiveItem, ActionTakeItem:
+		return true
+	}
+	return false
+}
+
+// Prompt returns what a builder is asked for when adding the action
+func (self TriggerActionType) Prompt() string {
+	switch self {
+	case ActionMessage, ActionMessageRoom:
+		return "Message: "
+	case ActionTeleport:
+		return "Room ID: "
+	case ActionGiveItem, ActionTakeItem:
+		return "Item name: "
+	case ActionOpenDoor, ActionCloseDoor:
+		return "Direction: "
+	case ActionSpawn:
+		return "NPC template name: "
+	}
+	return "Value: "
+}
+
+type TriggerAction struct {
+	Type  TriggerActionType
+	Value string
+}
+
+func (self TriggerAction) String() string {
+	return fmt.Sprintf("%s %s", self.Type, self.Value)
+}
+
+// Trigger runs a list of actions when something happens in a room. Enter,
+// leave and command triggers only fire for characters that meet the
+// conditions. Timer triggers have no character, so their conditions and any
+// actions that need a character are ignored.
+type Trigger struct {
+	Type       TriggerType
+	Verb       string `bson:",omitempty"` // Command that fires a command trigger
+	Interval   int    `bson:",omitempty"` // Seconds between runs of a timer trigger
+	Conditions []Condition
+	Actions    []TriggerAction
+}
+
+func (self Trigger) String() string {
+	switch self.Type {
+	case TriggerCommand:
+		return fmt.Sprintf("%s \"%s\"", self.Type, self.Verb)
+	case TriggerTimer:
+		return fmt.Sprintf("%s every %v seconds", self.Type, self.Interval)
+	}
+	return string(self.Type)
+}
+
+// vim: nocindent
@@ -23,6 +23,25 @@ const (
 	DirectionNone      Direction = iota
 )
 
+// Behavior names the AI state that drives an NPC
+type Behavior string
+
+const (
+	BehaviorIdle       Behavior = "idle"
+	BehaviorWander     Behavior = "wander"
+	BehaviorPatrol     Behavior = "patrol"
+	BehaviorAggressive Behavior = "aggressive"
+	BehaviorFollow     Behavior = "follow"
+)
+
+var Behaviors = []Behavior{
+	BehaviorIdle,
+	BehaviorWander,
+	BehaviorPatrol,
+	BehaviorAggressive,
+	BehaviorFollow,
+}
+
 type Identifiable interface {
 	GetId() bson.ObjectId
 	GetType() objectType
@@ -35,12 +54,17 @@ type Identifiable interface {
 type objectType int
 
 const (
-	CharType objectType = iota
-	UserType objectType = iota
-	ZoneType objectType = iota
-	AreaType objectType = iota
-	RoomType objectType = iota
-	ItemType objectType = iota
+	CharType        objectType = iota
+	UserType        objectType = iota
+	ZoneType        objectType = iota
+	AreaType        objectType = iota
+	RoomType        objectType = iota
+	ItemType        objectType = iota
+	SpawnerType     objectType = iota
+	TransactionType objectType = iota
+	ScriptType      objectType = iota
+	QuestType       objectType = iota
+	MailType        objectType = iota
 )
 
 type Coordinate struct {
@@ -8,18 +8,32 @@ import (
 	"reflect"
 )
 
+// Role is what a user is trusted to do. Each role can do everything that the
+// roles before it in Roles can.
+type Role string
+
+const (
+	RolePlayer  Role = "player"
+	RoleBuilder Role = "builder"
+	RoleAdmin   Role = "admin"
+)
+
+var Roles = []Role{RolePlayer, RoleBuilder, RoleAdmin}
+
 type User struct {
 	DbObject `bson:",inline"`
 
 	Name      string
 	ColorMode utils.ColorMode
 	Password  []byte
+	Role      Role `bson:",omitempty"`
 
 	online       bool
 	conn         net.Conn
 	windowWidth  int
 	windowHeight int
 	terminalType string
+	gmcp         bool
 }
 
 func NewUser(name string, password string) *User {
@@ -54,6 +68,7 @@ func (self *User) SetOnline(online bool) {
 
 	if !online {
 		self.conn = nil
+		self.gmcp = false
 	}
 }
 
@@ -84,6 +99,42 @@ func hash(data string) []byte {
 	return h.Sum(nil)
 }
 
+func (self *User) GetRole() Role {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	if self.Role == "" {
+		return RolePlayer
+	}
+
+	return self.Role
+}
+
+func (self *User) SetRole(role Role) {
+	if role != self.GetRole() {
+		self.WriteLock()
+		self.Role = role
+		self.WriteUnlock()
+
+		modified(self)
+	}
+}
+
+// HasRole returns true if the user's role is at least the given one
+func (self *User) HasRole(role Role) bool {
+	return roleRank(self.GetRole()) >= roleRank(role)
+}
+
+func roleRank(role Role) int {
+	for i, other := range Roles {
+		if other == role {
+			return i
+		}
+	}
+
+	return 0
+}
+
 // SetPassword SHA1 hashes the password before saving it to the database
 func (self *User) SetPassword(password string) {
 	hashed := hash(password)
@@ -135,6 +186,15 @@ func (self *User) TerminalType() string {
 	return self.terminalType
 }
 
+// SetGMCP records whether the user's client has agreed to receive GMCP data
+func (self *User) SetGMCP(enabled bool) {
+	self.gmcp = enabled
+}
+
+func (self *User) GMCP() bool {
+	return self.gmcp
+}
+
 func (self *User) GetInput(text string) string {
 	return utils.GetUserInput(self.conn, text, self.GetColorMode())
 }
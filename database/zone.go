@@ -2,12 +2,14 @@ package database
 
 import (
 	"kmud/utils"
+	"labix.org/v2/mgo/bson"
 )
 
 type Zone struct {
 	DbObject `bson:",inline"`
 
-	Name string
+	Name         string
+	RecallRoomId bson.ObjectId `bson:",omitempty"`
 }
 
 func NewZone(name string) *Zone {
@@ -41,6 +43,24 @@ func (self *Zone) SetName(name string) {
 	}
 }
 
+// SetRecallRoomId sets the room that players who die in this zone are sent to
+func (self *Zone) SetRecallRoomId(id bson.ObjectId) {
+	self.WriteLock()
+	defer self.WriteUnlock()
+
+	if id != self.RecallRoomId {
+		self.RecallRoomId = id
+		modified(self)
+	}
+}
+
+func (self *Zone) GetRecallRoomId() bson.ObjectId {
+	self.ReadLock()
+	defer self.ReadUnlock()
+
+	return self.RecallRoomId
+}
+
 type Zones []*Zone
 
 func (self Zones) Contains(z *Zone) bool {
@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"kmud/database"
+	"kmud/model"
+	"kmud/utils"
+	"time"
+)
+
+const (
+	idleDelay   = 5 * time.Second
+	moveDelay   = 2 * time.Second
+	combatDelay = 1 * time.Second
+	watchDelay  = 1 * time.Second
+)
+
+// behavior carries out a single action for an NPC and returns how long the
+// NPC should wait before acting again
+type behavior func(b *brain) time.Duration
+
+var behaviors = map[database.Behavior]behavior{
+	database.BehaviorIdle:       idle,
+	database.BehaviorWander:     wander,
+	database.BehaviorPatrol:     patrol,
+	database.BehaviorAggressive: aggressive,
+	database.BehaviorFollow:     follow,
+}
+
+// think decides what the NPC does this turn. NPCs that are badly hurt try to
+// run away, and NPCs that are fighting don't do anything else. Otherwise the
+// NPC's script gets a chance to act before its usual behavior.
+func think(b *brain) time.Duration {
+	if shouldFlee(b.npc) {
+		model.Flee(b.npc, database.DirectionNone)
+		return combatDelay
+	}
+
+	if model.InCombat(b.npc) {
+		return combatDelay
+	}
+
+	if model.ThinkScript(b.npc) {
+		return idleDelay
+	}
+
+	act, found := behaviors[b.npc.GetBehavior()]
+	if !found {
+		act = idle
+	}
+
+	return act(b)
+}
+
+func shouldFlee(npc *database.Character) bool {
+	threshold := npc.GetFleeHealth()
+
+	if threshold <= 0 || !model.InCombat(npc) {
+		return false
+	}
+
+	return npc.GetHitPoints()*100 < npc.GetHealth()*threshold
+}
+
+func idle(b *brain) time.Duration {
+	return idleDelay
+}
+
+// wander moves the NPC through a random exit, never leaving the area it is in
+func wander(b *brain) time.Duration {
+	room := model.GetRoom(b.npc.GetRoomId())
+	if room == nil {
+		return idleDelay
+	}
+
+	var exits []database.Direction
+	for _, exit := range room.GetExits() {
+		if door, _ := room.GetExit(exit); !door.IsOpen() {
+			continue
+		}
+
+		next := model.ExitDestination(room, exit)
+
+		if next != nil && next.GetAreaId() == room.GetAreaId() {
+			exits = append(exits, exit)
+		}
+	}
+
+	if len(exits) > 0 {
+		model.MoveCharacter(b.npc, exits[utils.Random(0, len(exits)-1)])
+	}
+
+	return moveDelay
+}
+
+// patrol walks the NPC around its patrol route, one room at a time, starting
+// over once it reaches the end. Unreachable waypoints are skipped.
+func patrol(b *brain) time.Duration {
+	route := b.npc.GetPatrolRoute()
+	room := model.GetRoom(b.npc.GetRoomId())
+
+	if len(route) == 0 || room == nil {
+		return idleDelay
+	}
+
+	for i := 0; i < len(route); i++ {
+		b.patrolIndex = b.patrolIndex % len(route)
+		waypoint := model.GetRoom(route[b.patrolIndex])
+
+		if waypoint != room {
+			direction := nextStep(room, waypoint)
+
+			if direction != database.DirectionNone {
+				model.MoveCharacter(b.npc, direction)
+				return moveDelay
+			}
+		}
+
+		b.patrolIndex++
+	}
+
+	return idleDelay
+}
+
+// aggressive attacks the first living player that it sees
+func aggressive(b *brain) time.Duration {
+	room := model.GetRoom(b.npc.GetRoomId())
+	if room == nil {
+		return idleDelay
+	}
+
+	for _, player := range model.PlayersIn(room, nil) {
+		if !player.IsDead() {
+			model.StartFight(b.npc, player)
+			break
+		}
+	}
+
+	return watchDelay
+}
+
+// follow latches on to the first player that it sees and then follows them as
+// they move. If the player gets away some other way the NPC tracks them from
+// room to room, giving up once it loses their trail.
+func follow(b *brain) time.Duration {
+	room := model.GetRoom(b.npc.GetRoomId())
+	if room == nil {
+		return idleDelay
+	}
+
+	if b.target != nil && (b.target.IsDestroyed() || !b.target.IsOnline()) {
+		b.target = nil
+	}
+
+	if b.target == nil {
+		players := model.PlayersIn(room, nil)
+		if len(players) > 0 {
+			b.target = players[0]
+			model.Follow(b.npc, b.target)
+		}
+		return watchDelay
+	}
+
+	targetRoom := model.GetRoom(b.target.GetRoomId())
+
+	if targetRoom != nil && targetRoom != room {
+		direction := database.DirectionNone
+		if targetRoom.GetZoneId() == room.GetZoneId() {
+			direction = nextStep(room, targetRoom)
+		}
+
+		if direction == database.DirectionNone {
+			model.StopFollowing(b.npc)
+			b.target = nil
+		} else {
+			model.MoveCharacter(b.npc, direction)
+		}
+	}
+
+	return watchDelay
+}
+
+// nextStep returns the direction of the first step along the shortest path
+// between two rooms, or DirectionNone if there is no path between them
+func nextStep(from, to *database.Room) database.Direction {
+	if to == nil || from.GetZoneId() != to.GetZoneId() {
+		return database.DirectionNone
+	}
+
+	firstStep := map[*database.Room]database.Direction{from: database.DirectionNone}
+	queue := []*database.Room{from}
+
+	for len(queue) > 0 {
+		room := queue[0]
+		queue = queue[1:]
+
+		if room == to {
+			return firstStep[room]
+		}
+
+		for _, exit := range room.GetExits() {
+			if door, _ := room.GetExit(exit); !door.IsOpen() {
+				continue
+			}
+
+			next := model.ExitDestination(room, exit)
+
+			if next == nil {
+				continue
+			}
+
+			if _, seen := firstStep[next]; seen {
+				continue
+			}
+
+			if room == from {
+				firstStep[next] = exit
+			} else {
+				firstStep[next] = firstStep[room]
+			}
+
+			queue = append(queue, next)
+		}
+	}
+
+	return database.DirectionNone
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"kmud/database"
+	"kmud/database/dbtest"
+	"kmud/model"
+	tu "kmud/testutils"
+	"labix.org/v2/mgo/bson"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (self *fakeClock) Now() time.Time {
+	return self.now
+}
+
+func (self *fakeClock) Advance(d time.Duration) {
+	self.now = self.now.Add(d)
+}
+
+var initOnce sync.Once
+
+func setup() (*Scheduler, *fakeClock, *database.Zone) {
+	initOnce.Do(func() {
+		model.Init(&dbtest.TestSession{})
+	})
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	zone, _ := model.CreateZone("zone")
+
+	return NewScheduler(clock), clock, zone
+}
+
+func cleanup() {
+	for _, spawner := range model.GetSpawners() {
+		model.DeleteSpawner(spawner)
+	}
+
+	for _, char := range model.GetCharacters() {
+		model.DeleteCharacter(char)
+	}
+
+	for _, user := range model.GetUsers() {
+		model.DeleteUser(user)
+	}
+
+	for _, room := range model.GetRooms() {
+		model.DeleteRoom(room)
+	}
+
+	for _, zone := range model.GetZones() {
+		for _, area := range model.GetAreas(zone) {
+			model.DeleteArea(area)
+		}
+		model.DeleteZone(zone)
+	}
+}
+
+// createRow creates a row of rooms running east to west with exits between
+// each of them
+func createRow(zone *database.Zone, count int) []*database.Room {
+	rooms := make([]*database.Room, count)
+
+	for i := range rooms {
+		rooms[i], _ = model.CreateRoom(zone, database.Coordinate{X: i, Y: 0, Z: 0})
+
+		if i > 0 {
+			rooms[i-1].SetExitEnabled(database.DirectionEast, true)
+			rooms[i].SetExitEnabled(database.DirectionWest, true)
+		}
+	}
+
+	return rooms
+}
+
+func createPlayer(name string, room *database.Room) *database.Character {
+	user := model.CreateUser(name, "password")
+	player := model.CreatePlayer(name, user, room)
+	player.SetOnline(true)
+	return player
+}
+
+func Test_SchedulerClock(t *testing.T) {
+	scheduler, clock, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 2)
+
+	npc := model.CreateNpc("npc", rooms[0])
+	npc.SetBehavior(database.BehaviorWander)
+	scheduler.Add(npc)
+
+	scheduler.Step()
+	tu.Assert(npc.GetRoomId() == rooms[1].GetId(), t, "NPC should have wandered on its first step")
+
+	scheduler.Step()
+	tu.Assert(npc.GetRoomId() == rooms[1].GetId(), t, "NPC shouldn't act again until its delay has passed")
+
+	clock.Advance(moveDelay)
+	scheduler.Step()
+	tu.Assert(npc.GetRoomId() == rooms[0].GetId(), t, "NPC should have wandered back once its delay passed")
+
+	model.DeleteCharacter(npc)
+	scheduler.Step()
+	tu.Assert(len(scheduler.brains) == 0, t, "Destroyed NPCs should be dropped from the scheduler")
+}
+
+func Test_WanderStaysInArea(t *testing.T) {
+	scheduler, clock, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 2)
+
+	area, _ := model.CreateArea("area", zone)
+	rooms[0].SetAreaId(area.GetId())
+
+	npc := model.CreateNpc("npc", rooms[0])
+	npc.SetRoaming(true)
+	scheduler.Add(npc)
+
+	alone, _ := model.CreateRoom(zone, database.Coordinate{X: 10, Y: 10, Z: 0})
+	stuck := model.CreateNpc("stuck", alone)
+	stuck.SetRoaming(true)
+	scheduler.Add(stuck)
+
+	for i := 0; i < 5; i++ {
+		scheduler.Step()
+		clock.Advance(moveDelay)
+	}
+
+	tu.Assert(npc.GetRoomId() == rooms[0].GetId(), t, "Wandering NPC shouldn't leave its area")
+	tu.Assert(stuck.GetRoomId() == alone.GetId(), t, "NPC in a room without exits shouldn't move")
+}
+
+func Test_Patrol(t *testing.T) {
+	scheduler, clock, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 3)
+
+	npc := model.CreateNpc("guard", rooms[0])
+	npc.SetBehavior(database.BehaviorPatrol)
+	npc.SetPatrolRoute([]bson.ObjectId{rooms[2].GetId(), rooms[0].GetId()})
+	scheduler.Add(npc)
+
+	expected := []*database.Room{rooms[1], rooms[2], rooms[1], rooms[0], rooms[1]}
+
+	for i, room := range expected {
+		scheduler.Step()
+		clock.Advance(moveDelay)
+		tu.Assert(npc.GetRoomId() == room.GetId(), t, "Patrolling NPC is in the wrong room at step", i)
+	}
+}
+
+func Test_Aggressive(t *testing.T) {
+	scheduler, clock, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 1)
+
+	npc := model.CreateNpc("wolf", rooms[0])
+	npc.SetBehavior(database.BehaviorAggressive)
+	scheduler.Add(npc)
+
+	scheduler.Step()
+	tu.Assert(!model.InCombat(npc), t, "Aggressive NPC shouldn't fight when nobody is around")
+
+	player := createPlayer("player", rooms[0])
+	defer model.StopFight(player)
+	defer model.StopFight(npc)
+
+	clock.Advance(watchDelay)
+	scheduler.Step()
+	tu.Assert(model.InCombat(npc), t, "Aggressive NPC should have attacked the player")
+}
+
+func Test_ShouldFlee(t *testing.T) {
+	_, _, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 1)
+
+	npc := model.CreateNpc("coward", rooms[0])
+	npc.SetFleeHealth(50)
+	npc.SetHitPoints(10)
+
+	tu.Assert(!shouldFlee(npc), t, "NPC shouldn't flee when it isn't fighting")
+
+	player := createPlayer("player", rooms[0])
+	model.StartFight(player, npc)
+	defer model.StopFight(player)
+	defer model.StopFight(npc)
+
+	tu.Assert(shouldFlee(npc), t, "Hurt NPC should flee")
+
+	npc.SetHitPoints(npc.GetHealth())
+	tu.Assert(!shouldFlee(npc), t, "Healthy NPC shouldn't flee")
+
+	npc.SetHitPoints(10)
+	npc.SetFleeHealth(0)
+	tu.Assert(!shouldFlee(npc), t, "NPC without a flee threshold shouldn't flee")
+}
+
+func Test_Follow(t *testing.T) {
+	scheduler, clock, zone := setup()
+	defer cleanup()
+
+	rooms := createRow(zone, 3)
+
+	npc := model.CreateNpc("dog", rooms[0])
+	npc.SetBehavior(database.BehaviorFollow)
+	scheduler.Add(npc)
+
+	player := createPlayer("player", rooms[0])
+
+	scheduler.Step()
+	model.MoveCharacterToRoom(player, rooms[2])
+
+	clock.Advance(watchDelay)
+	scheduler.Step()
+	tu.Assert(npc.GetRoomId() == rooms[1].GetId(), t, "NPC should be following the player")
+
+	clock.Advance(watchDelay)
+	scheduler.Step()
+	tu.Assert(npc.GetRoomId() == rooms[2].GetId(), t, "NPC should have caught up with the player")
+}
@@ -11,42 +11,46 @@ const (
 	RoamingProperty = "roaming"
 )
 
+const schedulerInterval = 250 * time.Millisecond
+
+var scheduler = NewScheduler(realClock{})
+
 func Start() {
 	for _, npc := range model.GetAllNpcs() {
-		manage(npc)
+		scheduler.Add(npc)
 	}
 
 	eventChannel := model.Register()
 
-	event := <-eventChannel
+	go func() {
+		for {
+			event := <-eventChannel
 
-	if event.Type() == model.CreateEventType {
-		/*
-		   createEvent := event.(model.CreateEvent)
+			model.UpdateQuests(event)
 
-		   go func() {
-		       for {
-		           npc := (<-npcChannel).(*database.Character)
-		           manage(npc)
-		       }
-		   }()
-		*/
-	}
-}
+			switch event.Type() {
+			case model.CreateEventType:
+				createEvent := event.(model.CreateEvent)
 
-func manage(npc *database.Character) {
-	go func() {
-		throttler := utils.NewThrottler(1 * time.Second)
+				npc, ok := createEvent.Object.(*database.Character)
+				if ok && npc.IsNpc() {
+					scheduler.Add(npc)
+				}
 
-		for {
-			if npc.GetRoaming() {
-				room := model.GetRoom(npc.GetRoomId())
-				exits := room.GetExits()
-				exitToTake := utils.Random(0, len(exits)-1)
-				model.MoveCharacter(npc, exits[exitToTake])
+			case model.TimerEventType:
+				maintainPopulations(time.Now())
+				restockShops(time.Now())
+				runTimerTriggers(time.Now())
 			}
+		}
+	}()
 
+	go func() {
+		throttler := utils.NewThrottler(schedulerInterval)
+
+		for {
 			throttler.Sync()
+			scheduler.Step()
 		}
 	}()
 }
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"kmud/database"
+	"labix.org/v2/mgo/bson"
+	"sync"
+	"time"
+)
+
+// Clock tells the scheduler what the time is. Tests swap in their own clock so
+// that they can control when NPCs act.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// brain holds everything the scheduler remembers about an NPC between turns
+type brain struct {
+	npc         *database.Character
+	next        time.Time
+	patrolIndex int
+	target      *database.Character
+}
+
+// Scheduler runs the behaviors of every NPC it manages. Each NPC acts when its
+// previous action's delay has passed.
+type Scheduler struct {
+	clock  Clock
+	mutex  sync.Mutex
+	brains map[bson.ObjectId]*brain
+}
+
+func NewScheduler(clock Clock) *Scheduler {
+	var scheduler Scheduler
+	scheduler.clock = clock
+	scheduler.brains = map[bson.ObjectId]*brain{}
+	return &scheduler
+}
+
+func (self *Scheduler) Add(npc *database.Character) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if _, found := self.brains[npc.GetId()]; !found {
+		self.brains[npc.GetId()] = &brain{npc: npc, next: self.clock.Now()}
+	}
+}
+
+func (self *Scheduler) Remove(npc *database.Character) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	delete(self.brains, npc.GetId())
+}
+
+// Step lets every NPC whose turn has come around act once. NPCs that have been
+// destroyed are dropped.
+func (self *Scheduler) Step() {
+	now := self.clock.Now()
+
+	var due []*brain
+
+	self.mutex.Lock()
+	for id, b := range self.brains {
+		if b.npc.IsDestroyed() {
+			delete(self.brains, id)
+		} else if !now.Before(b.next) {
+			due = append(due, b)
+		}
+	}
+	self.mutex.Unlock()
+
+	for _, b := range due {
+		b.next = now.Add(think(b))
+	}
+}
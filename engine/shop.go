@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"kmud/model"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// lastRestock holds the last time each shopkeeper's stock was topped up
+var lastRestock = map[bson.ObjectId]time.Time{}
+
+// restockShops tops up the stock of every shopkeeper whose restock interval
+// has passed. New shopkeepers are stocked straight away.
+func restockShops(now time.Time) {
+	for _, npc := range model.GetAllNpcs() {
+		shop := npc.GetShop()
+
+		if shop == nil {
+			continue
+		}
+
+		id := npc.GetId()
+		last, found := lastRestock[id]
+		interval := time.Duration(shop.Restock) * time.Second
+
+		if !found || now.Sub(last) >= interval {
+			model.Restock(npc)
+			lastRestock[id] = now
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"kmud/model"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Maps each spawner to the last time it either spawned an NPC or was seen to
+// be at full population
+var lastSpawn = map[bson.ObjectId]time.Time{}
+
+// maintainPopulations tops up any spawner that has fewer living NPCs than it
+// should. Each spawner brings back at most one NPC per respawn interval.
+func maintainPopulations(now time.Time) {
+	for _, spawner := range model.GetSpawners() {
+		id := spawner.GetId()
+
+		if len(model.SpawnedBy(spawner)) >= spawner.GetCount() {
+			lastSpawn[id] = now
+			continue
+		}
+
+		last, found := lastSpawn[id]
+		interval := time.Duration(spawner.GetInterval()) * time.Second
+
+		if !found || now.Sub(last) >= interval {
+			model.Spawn(spawner)
+			lastSpawn[id] = now
+		}
+	}
+}
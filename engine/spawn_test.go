@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"kmud/database"
+	"kmud/model"
+	tu "kmud/testutils"
+	"testing"
+	"time"
+)
+
+func Test_SpawnRefillsRoom(t *testing.T) {
+	_, clock, zone := setup()
+	defer cleanup()
+
+	room, _ := model.CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	template := model.CreateNpcTemplate("rat")
+	spawner := model.CreateRoomSpawner(template, room)
+	spawner.SetCount(2)
+	spawner.SetInterval(10)
+
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 1, t, "Spawner should have spawned straight away")
+	tu.Assert(len(model.NpcsIn(room)) == 1, t, "Spawned NPC should be in the spawner's room")
+
+	clock.Advance(5 * time.Second)
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 1, t, "Spawner shouldn't spawn again before its interval")
+
+	clock.Advance(5 * time.Second)
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 2, t, "Spawner should have refilled the room once its interval passed")
+
+	model.DeleteCharacter(model.SpawnedBy(spawner)[0])
+	clock.Advance(10 * time.Second)
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 2, t, "Spawner should replace an NPC that died")
+}
+
+func Test_SpawnLimit(t *testing.T) {
+	_, clock, zone := setup()
+	defer cleanup()
+
+	room, _ := model.CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	template := model.CreateNpcTemplate("rat")
+	spawner := model.CreateRoomSpawner(template, room)
+	spawner.SetCount(3)
+	spawner.SetInterval(1)
+
+	for i := 0; i < 20; i++ {
+		maintainPopulations(clock.Now())
+		tu.Assert(len(model.SpawnedBy(spawner)) <= 3, t, "Spawner shouldn't exceed its count")
+		clock.Advance(time.Second)
+	}
+
+	tu.Assert(len(model.SpawnedBy(spawner)) == 3, t, "Spawner should have reached its count")
+}
+
+func Test_SpawnWhenFull(t *testing.T) {
+	_, clock, zone := setup()
+	defer cleanup()
+
+	room, _ := model.CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	area, _ := model.CreateArea("area", zone)
+	room.SetAreaId(area.GetId())
+
+	template := model.CreateNpcTemplate("rat")
+	spawner := model.CreateAreaSpawner(template, area)
+	spawner.SetCount(1)
+	spawner.SetInterval(10)
+
+	npc := model.Spawn(spawner)
+	tu.Assert(npc != nil && npc.GetRoomId() == room.GetId(), t, "Failed to spawn into the area")
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Minute)
+		maintainPopulations(clock.Now())
+		tu.Assert(len(model.SpawnedBy(spawner)) == 1, t, "Nothing should spawn while the area is full")
+	}
+
+	model.DeleteCharacter(npc)
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 0, t, "Spawner should wait for its interval after being full")
+
+	clock.Advance(10 * time.Second)
+	maintainPopulations(clock.Now())
+	tu.Assert(len(model.SpawnedBy(spawner)) == 1, t, "Spawner should refill the area once its interval passed")
+}
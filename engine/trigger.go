@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"kmud/database"
+	"kmud/model"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+type timerKey struct {
+	roomId bson.ObjectId
+	index  int
+}
+
+// lastTimer holds the last time each timer trigger ran, keyed by room and the
+// trigger's position in the room's list
+var lastTimer = map[timerKey]time.Time{}
+
+// runTimerTriggers runs every timer trigger whose interval has passed. A
+// trigger's first interval starts when it is first seen.
+func runTimerTriggers(now time.Time) {
+	for _, room := range model.GetRooms() {
+		for i, trigger := range room.GetTriggers() {
+			if trigger.Type != database.TriggerTimer || trigger.Interval <= 0 {
+				continue
+			}
+
+			key := timerKey{roomId: room.GetId(), index: i}
+			last, found := lastTimer[key]
+			interval := time.Duration(trigger.Interval) * time.Second
+
+			if !found {
+				lastTimer[key] = now
+			} else if now.Sub(last) >= interval {
+				model.RunTrigger(room, nil, trigger)
+				lastTimer[key] = now
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"sync"
+	"time"
+)
+
+// channelHistoryLength is how many messages each channel remembers
+const channelHistoryLength = 20
+
+var channelHistoryMutex sync.Mutex
+var channelHistory = map[string][]ChannelEvent{}
+
+// hasRole returns true if the character is played by a user with at least the
+// given role
+func hasRole(character *database.Character, role database.Role) bool {
+	user := GetUser(character.GetUserId())
+	return user != nil && user.HasRole(role)
+}
+
+// ChannelAllowed returns true if the character's role lets them on the channel
+func ChannelAllowed(character *database.Character, channel *database.Channel) bool {
+	return hasRole(character, channel.Role)
+}
+
+// OnChannel returns true if the character hears what is said on the channel
+func OnChannel(character *database.Character, channel *database.Channel) bool {
+	membership := character.GetChannelMembership(channel)
+	return membership.Joined && !membership.Banned && ChannelAllowed(character, channel)
+}
+
+// JoinChannel puts the character on the channel
+func JoinChannel(character *database.Character, channel *database.Channel) error {
+	if !ChannelAllowed(character, channel) {
+		return errors.New("You aren't allowed on that channel")
+	}
+
+	membership := character.GetChannelMembership(channel)
+
+	if membership.Banned {
+		return fmt.Errorf("You have been banned from %s", channel.Name)
+	}
+
+	if membership.Joined {
+		return fmt.Errorf("You are already on %s", channel.Name)
+	}
+
+	membership.Joined = true
+	character.SetChannelMembership(channel, membership)
+	return nil
+}
+
+// LeaveChannel takes the character off the channel
+func LeaveChannel(character *database.Character, channel *database.Channel) error {
+	membership := character.GetChannelMembership(channel)
+
+	if !membership.Joined {
+		return fmt.Errorf("You aren't on %s", channel.Name)
+	}
+
+	membership.Joined = false
+	character.SetChannelMembership(channel, membership)
+	return nil
+}
+
+// ChannelSay sends a message to everyone on the channel
+func ChannelSay(character *database.Character, channel *database.Channel, message string) error {
+	if !OnChannel(character, channel) {
+		return fmt.Errorf("You aren't on %s", channel.Name)
+	}
+
+	if until := character.GetChannelMembership(channel).MutedUntil; time.Now().Before(until) {
+		return fmt.Errorf("You have been muted on %s for another %v minutes", channel.Name, int(until.Sub(time.Now()).Minutes()+1))
+	}
+
+	event := ChannelEvent{Channel: channel, From: character, Message: message, Time: time.Now()}
+
+	channelHistoryMutex.Lock()
+	history := append(channelHistory[channel.Name], event)
+	if len(history) > channelHistoryLength {
+		history = history[len(history)-channelHistoryLength:]
+	}
+	channelHistory[channel.Name] = history
+	channelHistoryMutex.Unlock()
+
+	queueEvent(event)
+	return nil
+}
+
+// ChannelHistory returns the most recent messages on the channel, oldest
+// first. Only characters on the channel can read it.
+func ChannelHistory(character *database.Character, channel *database.Channel) ([]ChannelEvent, error) {
+	if !OnChannel(character, channel) {
+		return nil, fmt.Errorf("You aren't on %s", channel.Name)
+	}
+
+	channelHistoryMutex.Lock()
+	defer channelHistoryMutex.Unlock()
+
+	return append([]ChannelEvent(nil), channelHistory[channel.Name]...), nil
+}
+
+// MuteOnChannel stops the target talking on the channel for a while. A zero
+// duration lifts an existing mute.
+func MuteOnChannel(moderator *database.Character, target *database.Character, channel *database.Channel, duration time.Duration) error {
+	if err := checkModerator(moderator, target, channel); err != nil {
+		return err
+	}
+
+	membership := target.GetChannelMembership(channel)
+
+	if duration > 0 {
+		membership.MutedUntil = time.Now().Add(duration)
+		queueEvent(MessageEvent{Character: target,
+			Message: fmt.Sprintf("You have been muted on %s for %v minutes", channel.Name, int(duration.Minutes()))})
+	} else {
+		membership.MutedUntil = time.Time{}
+		queueEvent(MessageEvent{Character: target, Message: fmt.Sprintf("You can talk on %s again", channel.Name)})
+	}
+
+	target.SetChannelMembership(channel, membership)
+	return nil
+}
+
+// BanFromChannel removes the target from the channel and stops them joining
+// it again, or lifts an existing ban
+func BanFromChannel(moderator *database.Character, target *database.Character, channel *database.Channel, banned bool) error {
+	if err := checkModerator(moderator, target, channel); err != nil {
+		return err
+	}
+
+	membership := target.GetChannelMembership(channel)
+	membership.Banned = banned
+
+	if banned {
+		membership.Joined = false
+		queueEvent(MessageEvent{Character: target, Message: fmt.Sprintf("You have been banned from %s", channel.Name)})
+	} else {
+		queueEvent(MessageEvent{Character: target, Message: fmt.Sprintf("You are no longer banned from %s", channel.Name)})
+	}
+
+	target.SetChannelMembership(channel, membership)
+	return nil
+}
+
+func checkModerator(moderator *database.Character, target *database.Character, channel *database.Channel) error {
+	if !hasRole(moderator, channel.Moderator) {
+		return errors.New("You aren't a moderator of that channel")
+	}
+
+	if !target.IsPlayer() {
+		return fmt.Errorf("%s isn't a player", target.GetName())
+	}
+
+	if target == moderator {
+		return errors.New("You can't do that to yourself")
+	}
+
+	return nil
+}
+
+// vim: nocindent
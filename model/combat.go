@@ -1,8 +1,9 @@
 package model
 
 import (
+	"errors"
 	"kmud/database"
-	"math/rand"
+	"kmud/utils"
 	"sync"
 	"time"
 )
@@ -11,40 +12,138 @@ var fightsMutex sync.RWMutex
 
 var fights map[*database.Character]*database.Character // Maps the attacker to the defender
 
-func StartFight(attacker *database.Character, defender *database.Character) {
+type CombatOutcome int
+
+const (
+	CombatMiss     CombatOutcome = iota
+	CombatHit      CombatOutcome = iota
+	CombatCritical CombatOutcome = iota
+)
+
+type CombatStopReason int
+
+const (
+	StopReasonNone         CombatStopReason = iota
+	StopReasonSeparated    CombatStopReason = iota
+	StopReasonAttackerFled CombatStopReason = iota
+	StopReasonDefenderFled CombatStopReason = iota
+	StopReasonDeath        CombatStopReason = iota
+)
+
+const (
+	baseHitChance  = 75 // Percent chance to hit an opponent with equal dexterity
+	minHitChance   = 5
+	maxHitChance   = 95
+	criticalChance = 5 // Rolls at or below this value are always critical hits
+	baseFleeChance = 50
+	minDamage      = 1
+	maxDamage      = 10
+	combatInterval = 3 * time.Second
+)
+
+// roll returns a random number between low and high, inclusive. All of the
+// random decisions made during combat go through it so that tests can make
+// combat deterministic.
+var roll = utils.Random
+
+// statBonus converts a raw stat value into the modifier applied to rolls
+func statBonus(stat int) int {
+	return (stat - database.DefaultStat) / 2
+}
+
+// StartFight makes the attacker start attacking the defender. If the defender
+// isn't already fighting someone they will automatically retaliate.
+func StartFight(attacker *database.Character, defender *database.Character) error {
+	if attacker.IsDead() {
+		return errors.New("You are in no condition to fight")
+	}
+
+	if defender.IsDead() {
+		return errors.New("They are already dead")
+	}
+
+	if attacker.GetRoomId() != defender.GetRoomId() {
+		return errors.New("They aren't here")
+	}
+
+	CancelTrade(attacker, "a fight broke out")
+	CancelTrade(defender, "a fight broke out")
+
+	// Being attacked wakes the defender up
+	for _, character := range []*database.Character{attacker, defender} {
+		if character.GetPosition() != database.PositionStanding {
+			Wake(character)
+		}
+	}
+
 	fightsMutex.Lock()
 	defer fightsMutex.Unlock()
 
 	oldDefender, found := fights[attacker]
 
 	if defender == oldDefender {
-		return
+		return nil
 	}
 
 	if found {
-		fightsMutex.Unlock()
-		StopFight(attacker)
-		fightsMutex.Lock()
+		stopFight(attacker, StopReasonNone)
 	}
 
 	fights[attacker] = defender
-
 	queueEvent(CombatStartEvent{Attacker: attacker, Defender: defender})
+
+	if _, fighting := fights[defender]; !fighting {
+		fights[defender] = attacker
+		queueEvent(CombatStartEvent{Attacker: defender, Defender: attacker})
+	}
+
+	return nil
 }
 
 func StopFight(attacker *database.Character) {
 	fightsMutex.Lock()
 	defer fightsMutex.Unlock()
 
+	stopFight(attacker, StopReasonNone)
+}
+
+// stopFight must be called with fightsMutex held for writing
+func stopFight(attacker *database.Character, reason CombatStopReason) {
 	defender := fights[attacker]
 
 	if defender != nil {
 		delete(fights, attacker)
-		queueEvent(CombatStopEvent{Attacker: attacker, Defender: defender})
+		queueEvent(CombatStopEvent{Attacker: attacker, Defender: defender, Reason: reason})
+	}
+}
+
+// endFightsWith ends every fight the given character is involved in. Anyone who
+// was attacking them switches over to another one of their own attackers if
+// they have one. Must be called with fightsMutex held for writing.
+func endFightsWith(character *database.Character, attackerReason, defenderReason CombatStopReason) {
+	stopFight(character, attackerReason)
+
+	for attacker, defender := range fights {
+		if defender != character {
+			continue
+		}
+
+		stopFight(attacker, defenderReason)
+
+		for other, target := range fights {
+			if target == attacker && other.GetRoomId() == attacker.GetRoomId() {
+				fights[attacker] = other
+				queueEvent(CombatStartEvent{Attacker: attacker, Defender: other})
+				break
+			}
+		}
 	}
 }
 
 func InCombat(character *database.Character) bool {
+	fightsMutex.RLock()
+	defer fightsMutex.RUnlock()
+
 	_, found := fights[character]
 
 	if found {
@@ -59,22 +158,224 @@ func InCombat(character *database.Character) bool {
 	return false
 }
 
-func combatLoop() {
-	for {
-		time.Sleep(3 * time.Second)
-
-		fightsMutex.RLock()
-		for a, d := range fights {
-			if a.GetRoomId() == d.GetRoomId() {
-				dmg := rand.Int()%10 + 1
-				queueEvent(CombatEvent{Attacker: a, Defender: d, Damage: dmg})
-			} else {
-				fightsMutex.RUnlock()
-				StopFight(a)
-				fightsMutex.RLock()
+// Opponents returns all of the characters that are currently attacking the
+// given character
+func Opponents(character *database.Character) []*database.Character {
+	fightsMutex.RLock()
+	defer fightsMutex.RUnlock()
+
+	var opponents []*database.Character
+
+	for attacker, defender := range fights {
+		if defender == character {
+			opponents = append(opponents, attacker)
+		}
+	}
+
+	return opponents
+}
+
+func hitChance(attacker *database.Character, defender *database.Character) int {
+	chance := baseHitChance + (statBonus(Dexterity(attacker))-statBonus(Dexterity(defender)))*5
+
+	if chance < minHitChance {
+		chance = minHitChance
+	} else if chance > maxHitChance {
+		chance = maxHitChance
+	}
+
+	return chance
+}
+
+// rollDice rolls dice notation using roll so that tests can control the
+// outcome. Invalid dice roll zero.
+func rollDice(dice string) int {
+	count, sides, bonus, err := utils.ParseDice(dice)
+
+	if err != nil {
+		return 0
+	}
+
+	total := bonus
+	for i := 0; i < count; i++ {
+		total += roll(1, sides)
+	}
+
+	return total
+}
+
+// rollDamage rolls the attacker's weapon damage, or their bare handed damage
+// if they aren't wielding a weapon
+func rollDamage(attacker *database.Character) int {
+	damage := 0
+
+	if dice := WeaponDamage(attacker); dice != "" {
+		damage = rollDice(dice)
+	} else {
+		damage = roll(minDamage, maxDamage)
+	}
+
+	damage += statBonus(Strength(attacker)) + enhancedDamage(attacker)
+
+	if damage < minDamage {
+		damage = minDamage
+	}
+
+	return damage
+}
+
+// resolveAttack rolls a single attack from the attacker against the defender
+// and applies any resulting damage to the defender. Passive skills are
+// practiced as they come in to play.
+func resolveAttack(attacker *database.Character, defender *database.Character) CombatEvent {
+	event := CombatEvent{Attacker: attacker, Defender: defender}
+
+	attackRoll := roll(1, 100)
+	chance := hitChance(attacker, defender)
+
+	if attackRoll <= criticalChance {
+		event.Outcome = CombatCritical
+		event.Damage = rollDamage(attacker) * 2
+	} else if attackRoll <= chance-dodgeChance(defender) {
+		event.Outcome = CombatHit
+		event.Damage = rollDamage(attacker)
+	} else {
+		event.Outcome = CombatMiss
+		event.Dodged = attackRoll <= chance
+	}
+
+	if event.Outcome != CombatMiss {
+		event.Damage -= ArmorClass(defender)
+
+		if event.Damage < minDamage {
+			event.Damage = minDamage
+		}
+	}
+
+	if event.Damage > 0 {
+		defender.Hit(event.Damage)
+	}
+
+	event.Killed = defender.IsDead()
+
+	if event.Dodged {
+		practice(defender, "dodge")
+	} else if event.Outcome != CombatMiss {
+		practice(attacker, "enhanced damage")
+	}
+
+	return event
+}
+
+// combatRound gives every character that is currently fighting a single
+// attack against their opponent
+func combatRound() {
+	fightsMutex.Lock()
+
+	type death struct {
+		victim *database.Character
+		killer *database.Character
+	}
+
+	var deaths []death
+
+	attackers := make([]*database.Character, 0, len(fights))
+	for attacker := range fights {
+		attackers = append(attackers, attacker)
+	}
+
+	for _, attacker := range attackers {
+		// The fight may have ended earlier on in this round
+		defender, found := fights[attacker]
+		if !found {
+			continue
+		}
+
+		if attacker.GetRoomId() != defender.GetRoomId() {
+			stopFight(attacker, StopReasonSeparated)
+			continue
+		}
+
+		event := resolveAttack(attacker, defender)
+		queueEvent(event)
+
+		if event.Killed {
+			endFightsWith(defender, StopReasonDeath, StopReasonDeath)
+			deaths = append(deaths, death{victim: defender, killer: attacker})
+		}
+	}
+
+	fightsMutex.Unlock()
+
+	for _, d := range deaths {
+		Kill(d.victim, d.killer)
+	}
+}
+
+// Flee attempts to escape from combat in the given direction. If no direction
+// is given then a random exit is chosen. Fleeing isn't guaranteed to succeed,
+// and an error is returned if the character failed to get away.
+func Flee(character *database.Character, direction database.Direction) (*database.Room, error) {
+	if !InCombat(character) {
+		return nil, errors.New("You aren't fighting anyone")
+	}
+
+	room := GetRoom(character.GetRoomId())
+
+	var exits []database.Direction
+	for _, exit := range room.GetExits() {
+		if door, _ := room.GetExit(exit); !door.IsOpen() {
+			continue
+		}
+
+		if ExitDestination(room, exit) != nil {
+			exits = append(exits, exit)
+		}
+	}
+
+	if len(exits) == 0 {
+		return nil, errors.New("There is nowhere to run!")
+	}
+
+	if direction == database.DirectionNone {
+		direction = exits[roll(0, len(exits)-1)]
+	} else {
+		found := false
+		for _, exit := range exits {
+			if exit == direction {
+				found = true
+				break
 			}
 		}
-		fightsMutex.RUnlock()
+
+		if !found {
+			return nil, errors.New("You can't flee that way")
+		}
+	}
+
+	if roll(1, 100) > baseFleeChance+statBonus(Dexterity(character))*5 {
+		return nil, errors.New("You fail to get away!")
+	}
+
+	newRoom, err := MoveCharacter(character, direction)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fightsMutex.Lock()
+	endFightsWith(character, StopReasonAttackerFled, StopReasonDefenderFled)
+	fightsMutex.Unlock()
+
+	return newRoom, nil
+}
+
+func combatLoop() {
+	throttler := utils.NewThrottler(combatInterval)
+
+	for {
+		throttler.Sync()
+		combatRound()
 	}
 }
 
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+)
+
+// Weight returns the weight of the item plus the weight of everything inside
+// of it
+func Weight(item *database.Item) int {
+	weight := item.GetWeight()
+
+	for _, content := range GetItems(item.GetItemIds()) {
+		if content != nil {
+			weight += Weight(content)
+		}
+	}
+
+	return weight
+}
+
+// contains returns true if the item is inside the container, at any depth
+func contains(container *database.Item, item *database.Item) bool {
+	for _, content := range GetItems(container.GetItemIds()) {
+		if content == item || (content != nil && contains(content, item)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PutItem moves an item from the character's inventory in to a container
+func PutItem(character *database.Character, item *database.Item, container *database.Item) error {
+	if !container.IsContainer() {
+		return fmt.Errorf("%s isn't a container", container.GetName())
+	}
+
+	if container.IsLocked() {
+		return fmt.Errorf("%s is locked", container.GetName())
+	}
+
+	if !character.HasItem(item) {
+		return errors.New("You aren't carrying that")
+	}
+
+	if item == container || contains(item, container) {
+		return fmt.Errorf("You can't put %s inside itself", item.GetName())
+	}
+
+	capacity := container.GetIntProperty(database.PropertyCapacity)
+	if capacity > 0 && len(container.GetItemIds()) >= capacity {
+		return fmt.Errorf("%s is full", container.GetName())
+	}
+
+	maxWeight := container.GetIntProperty(database.PropertyMaxWeight)
+	if maxWeight > 0 && Weight(container)-container.GetWeight()+Weight(item) > maxWeight {
+		return fmt.Errorf("%s can't hold that much weight", container.GetName())
+	}
+
+	if !takeItem(character, item) {
+		return errors.New("You aren't carrying that")
+	}
+
+	container.AddItem(item)
+
+	return nil
+}
+
+// TakeItemFrom moves an item out of a container and in to the character's
+// inventory
+func TakeItemFrom(character *database.Character, item *database.Item, container *database.Item) error {
+	if container.IsLocked() {
+		return fmt.Errorf("%s is locked", container.GetName())
+	}
+
+	return claim(character, item, func() bool {
+		if !container.HasItem(item) {
+			return false
+		}
+
+		container.RemoveItem(item)
+		return true
+	})
+}
+
+// FindKey returns the item the character is carrying that fits a lock with
+// the given code, or nil if they don't have one
+func FindKey(character *database.Character, code string) *database.Item {
+	for _, item := range GetItems(character.GetItemIds()) {
+		if item != nil && item.GetKind() == database.ItemKindKey && item.GetProperty(database.PropertyKey) == code {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// SetItemLocked locks or unlocks a container, provided the character is
+// carrying the right key
+func SetItemLocked(character *database.Character, container *database.Item, locked bool) error {
+	code := container.GetProperty(database.PropertyKey)
+
+	if !container.IsContainer() || code == "" {
+		return fmt.Errorf("%s doesn't have a lock", container.GetName())
+	}
+
+	if container.IsLocked() == locked {
+		if locked {
+			return fmt.Errorf("%s is already locked", container.GetName())
+		}
+		return fmt.Errorf("%s isn't locked", container.GetName())
+	}
+
+	if FindKey(character, code) == nil {
+		return errors.New("You don't have the key")
+	}
+
+	container.SetLocked(locked)
+	return nil
+}
+
+// vim: nocindent
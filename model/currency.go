@@ -0,0 +1,193 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"sync"
+)
+
+// claimMutex makes picking an item up out of a room or container, or taking
+// it out of an inventory, a single step so that the same item or coins can't
+// end up in two places
+var claimMutex sync.Mutex
+
+var transactionsMutex sync.RWMutex
+var _transactions []*database.Transaction
+
+// TransferCash moves cash from one character to another and records the
+// transfer. Either character may be nil, in which case the cash comes from or
+// goes to nowhere. Fails without moving anything if the giver doesn't have
+// enough.
+func TransferCash(from *database.Character, to *database.Character, amount int, reason string) error {
+	if amount <= 0 {
+		return errors.New("The amount must be more than zero")
+	}
+
+	if from != nil && !from.TakeCash(amount) {
+		return errors.New("You don't have that much cash")
+	}
+
+	if to != nil {
+		to.AddCash(amount)
+	}
+
+	recordTransaction(from, to, amount, reason)
+	return nil
+}
+
+func recordTransaction(from *database.Character, to *database.Character, amount int, reason string) {
+	transaction := database.NewTransaction(from, to, amount, reason)
+
+	transactionsMutex.Lock()
+	_transactions = append(_transactions, transaction)
+	transactionsMutex.Unlock()
+}
+
+// GiveCash transfers cash from one character to another and lets the
+// receiver know about it
+func GiveCash(from *database.Character, to *database.Character, amount int) error {
+	if from == to {
+		return errors.New("You can't give cash to yourself")
+	}
+
+	err := TransferCash(from, to, amount, "give")
+
+	if err == nil {
+		queueEvent(GiveCashEvent{From: from, To: to, Amount: amount})
+	}
+
+	return err
+}
+
+// GetTransactions returns the recorded cash transfers that involve the given
+// character, oldest first. If the character is nil all transfers are returned.
+func GetTransactions(character *database.Character) []*database.Transaction {
+	transactionsMutex.RLock()
+	defer transactionsMutex.RUnlock()
+
+	var transactions []*database.Transaction
+
+	for _, transaction := range _transactions {
+		if character == nil || transaction.Involves(character) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions
+}
+
+// IsCoins returns true if the item is a pile of coins
+func IsCoins(item *database.Item) bool {
+	return item.GetKind() == database.ItemKindCurrency
+}
+
+// createCoins creates a new pile of coins holding the given amount
+func createCoins(amount int) *database.Item {
+	coins := CreateItem("coins")
+	coins.SetKind(database.ItemKindCurrency)
+	coins.SetCash(amount)
+	return coins
+}
+
+// DropCash takes cash from the character and leaves it in the room as a pile
+// of coins, adding to any pile that is already there
+func DropCash(character *database.Character, room *database.Room, amount int) error {
+	err := TransferCash(character, nil, amount, "drop")
+
+	if err != nil {
+		return err
+	}
+
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	for _, item := range ItemsIn(room) {
+		if item != nil && IsCoins(item) {
+			item.SetCash(item.GetCash() + amount)
+			return nil
+		}
+	}
+
+	room.AddItem(createCoins(amount))
+	return nil
+}
+
+// claim moves an item out of a room or container and in to the character's
+// inventory. Coins are added to the character's cash instead. The remove
+// function is called with claimMutex held, it should return false if the item
+// has already gone.
+func claim(character *database.Character, item *database.Item, remove func() bool) error {
+	claimMutex.Lock()
+	removed := remove()
+	claimMutex.Unlock()
+
+	if !removed {
+		return fmt.Errorf("%s isn't there anymore", item.GetName())
+	}
+
+	if IsCoins(item) {
+		amount := item.GetCash()
+		DeleteItem(item)
+
+		if amount > 0 {
+			return shareCash(character, amount)
+		}
+
+		return nil
+	}
+
+	character.AddItem(item)
+	return nil
+}
+
+// takeItem removes the item from the character's inventory, returning false
+// if they aren't carrying it anymore. Items should only leave an inventory
+// through here, or through removeItem with claimMutex held.
+func takeItem(character *database.Character, item *database.Item) bool {
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	return removeItem(character, item)
+}
+
+// removeItem is takeItem for callers that already hold claimMutex
+func removeItem(character *database.Character, item *database.Item) bool {
+	if !character.HasItem(item) {
+		return false
+	}
+
+	character.RemoveItem(item)
+	return true
+}
+
+// DropItem moves an item from the character's inventory in to the room
+func DropItem(character *database.Character, item *database.Item, room *database.Room) error {
+	if !takeItem(character, item) {
+		return errors.New("You aren't carrying that")
+	}
+
+	room.AddItem(item)
+	return nil
+}
+
+// PickupItem moves an item from the room in to the character's inventory
+func PickupItem(character *database.Character, item *database.Item, room *database.Room) error {
+	err := claim(character, item, func() bool {
+		if !room.HasItem(item) {
+			return false
+		}
+
+		room.RemoveItem(item)
+		return true
+	})
+
+	if err == nil {
+		queueEvent(GetItemEvent{Character: character, Item: item, Room: room})
+		scriptOnGet(item, character)
+	}
+
+	return err
+}
+
+// vim: nocindent
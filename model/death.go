@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"kmud/database"
+	"time"
+)
+
+const (
+	corpseDecayTime  = 5 * time.Minute
+	deathCashPenalty = 10 // Percentage of their cash that a player loses when they die
+)
+
+// Kill handles the death of the given character. NPCs leave behind a corpse
+// holding everything they were carrying, players are sent back to the recall
+// room of the zone they died in. The killer may be nil.
+func Kill(victim *database.Character, killer *database.Character) {
+	fightsMutex.Lock()
+	endFightsWith(victim, StopReasonDeath, StopReasonDeath)
+	fightsMutex.Unlock()
+
+	statusMutex.Lock()
+	victim.SetStatuses(nil)
+	statusMutex.Unlock()
+
+	room := GetRoom(victim.GetRoomId())
+
+	if victim.IsNpc() {
+		if killer != nil && killer.IsPlayer() {
+			shareExperience(killer, victim)
+		}
+
+		corpse := makeCorpse(victim, room)
+		queueEvent(DeathEvent{Character: victim, Killer: killer, Room: room, Corpse: corpse})
+		DeleteCharacter(victim)
+	} else {
+		queueEvent(DeathEvent{Character: victim, Killer: killer, Room: room})
+		respawn(victim, room)
+	}
+}
+
+// makeCorpse creates a corpse in the given room and moves all of the
+// character's items and cash in to it
+func makeCorpse(character *database.Character, room *database.Room) *database.Item {
+	corpse := CreateItem(fmt.Sprintf("Corpse of %s", character.GetName()))
+	corpse.SetKind(database.ItemKindContainer)
+
+	for _, item := range GetItems(character.GetItemIds()) {
+		if item != nil && takeItem(character, item) {
+			corpse.AddItem(item)
+		}
+	}
+
+	cash := character.GetCash()
+	if cash > 0 && TransferCash(character, nil, cash, "death") == nil {
+		corpse.AddItem(createCoins(cash))
+	}
+
+	corpse.SetDecay(time.Now().Add(corpseDecayTime))
+	room.AddItem(corpse)
+
+	return corpse
+}
+
+// respawn sends a dead player to their recall room, minus some of their cash
+func respawn(player *database.Character, room *database.Room) {
+	penalty := player.GetCash() * deathCashPenalty / 100
+	if penalty > 0 {
+		TransferCash(player, nil, penalty, "death")
+	}
+
+	hitpoints := player.GetHealth() / 4
+	if hitpoints < 1 {
+		hitpoints = 1
+	}
+	player.SetHitPoints(hitpoints)
+	player.SetPosition(database.PositionStanding)
+
+	recallRoom := RecallRoom(room)
+	MoveCharacterToRoom(player, recallRoom)
+
+	queueEvent(RespawnEvent{Character: player, Room: recallRoom, CashLost: penalty})
+}
+
+// RecallRoom returns the room that players who die in the given room are
+// sent to. If the room's zone doesn't have a recall room then the room itself
+// is returned.
+func RecallRoom(room *database.Room) *database.Room {
+	zone := GetZone(room.GetZoneId())
+
+	if zone != nil {
+		recallRoom := GetRoom(zone.GetRecallRoomId())
+
+		if recallRoom != nil {
+			return recallRoom
+		}
+	}
+
+	return room
+}
+
+// decayItems destroys any items whose decay time has passed
+func decayItems(now time.Time) {
+	var decayed []*database.Item
+
+	mutex.RLock()
+	for _, item := range _items {
+		decay := item.GetDecay()
+
+		if !decay.IsZero() && now.After(decay) {
+			decayed = append(decayed, item)
+		}
+	}
+	mutex.RUnlock()
+
+	for _, item := range decayed {
+		for _, room := range GetRooms() {
+			if room.HasItem(item) {
+				room.RemoveItem(item)
+				queueEvent(DecayEvent{Item: item, Room: room})
+			}
+		}
+
+		for _, char := range GetCharacters() {
+			takeItem(char, item)
+		}
+
+		DeleteItem(item)
+	}
+}
+
+// vim: nocindent
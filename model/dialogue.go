@@ -0,0 +1,149 @@
+package model
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/utils"
+	"strings"
+)
+
+// ConditionsMet returns true if the character satisfies all of the given
+// conditions
+func ConditionsMet(character *database.Character, conditions []database.Condition) bool {
+	for _, condition := range conditions {
+		if !conditionMet(character, condition) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func conditionMet(character *database.Character, condition database.Condition) bool {
+	switch condition.Type {
+	case database.ConditionHasItem:
+		return findItem(character, condition.Value) != nil
+	case database.ConditionCash:
+		return character.GetCash() >= condition.Amount
+	case database.ConditionFlag:
+		return character.HasFlag(condition.Value)
+	case database.ConditionNoFlag:
+		return !character.HasFlag(condition.Value)
+	case database.ConditionQuest:
+		quest := GetQuestByName(condition.Value)
+		return quest != nil && IsQuestComplete(character, quest)
+	}
+
+	return false
+}
+
+// findItem returns the item in the character's inventory with the given name,
+// or nil if they don't have one
+func findItem(character *database.Character, name string) *database.Item {
+	for _, item := range GetItems(character.GetItemIds()) {
+		if item != nil && strings.EqualFold(item.GetName(), name) {
+			return item
+		}
+	}
+
+	return nil
+}
+
+// ApplyEffects carries out the effects of a dialogue choice the player made
+// while talking to the NPC. A description of each effect the player would
+// notice is returned.
+func ApplyEffects(player *database.Character, npc *database.Character, effects []database.Effect) []string {
+	var messages []string
+
+	for _, effect := range effects {
+		switch effect.Type {
+		case database.EffectGiveItem:
+			item := findItem(npc, effect.Value)
+			if item == nil || !takeItem(npc, item) {
+				item = CreateItem(effect.Value)
+			}
+			player.AddItem(item)
+			messages = append(messages, fmt.Sprintf("%s gives you %s", npc.GetName(), item.GetName()))
+
+		case database.EffectTakeItem:
+			item := findItem(player, effect.Value)
+			if item != nil && takeItem(player, item) {
+				npc.AddItem(item)
+				messages = append(messages, fmt.Sprintf("You give %s to %s", item.GetName(), npc.GetName()))
+			}
+
+		case database.EffectGiveCash:
+			if TransferCash(nil, player, effect.Amount, "dialogue") == nil {
+				messages = append(messages, fmt.Sprintf("%s gives you %v cash", npc.GetName(), effect.Amount))
+			}
+
+		case database.EffectTakeCash:
+			amount := effect.Amount
+			if amount > player.GetCash() {
+				amount = player.GetCash()
+			}
+			if TransferCash(player, npc, amount, "dialogue") == nil {
+				messages = append(messages, fmt.Sprintf("You give %v cash to %s", amount, npc.GetName()))
+			}
+
+		case database.EffectSetFlag:
+			player.SetFlag(effect.Value)
+
+		case database.EffectClearFlag:
+			player.ClearFlag(effect.Value)
+
+		case database.EffectQuest:
+			quest := GetQuestByName(effect.Value)
+			if quest != nil && StartQuest(player, quest) == nil {
+				messages = append(messages, fmt.Sprintf("You have started the quest: %s", quest.GetName()))
+			}
+		}
+	}
+
+	return messages
+}
+
+// Talk lets the NPC know the player is talking to it. Returns true if the
+// NPC's script took care of the conversation.
+func Talk(player *database.Character, npc *database.Character) bool {
+	queueEvent(TalkEvent{Character: player, Npc: npc})
+	return TalkScript(player, npc)
+}
+
+// AskAbout returns what the NPC has to say to the player about the given
+// topic. An empty string is returned if the NPC doesn't know anything about it.
+func AskAbout(player *database.Character, npc *database.Character, topic string) string {
+	var available []database.Topic
+	var keywords []string
+
+	for _, t := range npc.GetTopics() {
+		if ConditionsMet(player, t.Conditions) {
+			available = append(available, t)
+			keywords = append(keywords, t.Keyword)
+		}
+	}
+
+	index := utils.BestMatch(topic, keywords)
+
+	if index < 0 {
+		return ""
+	}
+
+	return available[index].Response
+}
+
+// AvailableChoices returns the choices from the dialogue node that the player
+// is able to pick
+func AvailableChoices(player *database.Character, node database.DialogueNode) []database.DialogueChoice {
+	var choices []database.DialogueChoice
+
+	for _, choice := range node.Choices {
+		if ConditionsMet(player, choice.Conditions) {
+			choices = append(choices, choice)
+		}
+	}
+
+	return choices
+}
+
+// vim: nocindent
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"strings"
+	"sync"
+)
+
+// doorMutex stops two characters from changing the same door at once
+var doorMutex sync.Mutex
+
+// SetExit replaces one of the room's exits. If the room on the other side has
+// an exit leading back its door is kept in sync, so that both sides of a door
+// are always open, closed or locked together.
+func SetExit(room *database.Room, dir database.Direction, exit database.Exit) {
+	room.SetExit(dir, exit)
+
+	other := ExitDestination(room, dir)
+
+	if other != nil && ExitDestination(other, dir.Opposite()) == room {
+		otherExit, found := other.GetExit(dir.Opposite())
+
+		if found {
+			otherExit.Door = exit.Door
+			otherExit.Key = exit.Key
+			otherExit.Difficulty = exit.Difficulty
+			other.SetExit(dir.Opposite(), otherExit)
+		}
+	}
+}
+
+func doorDirection(dir database.Direction) string {
+	switch dir {
+	case database.DirectionUp:
+		return "above"
+	case database.DirectionDown:
+		return "below"
+	}
+
+	return "to the " + strings.ToLower(database.DirectionToString(dir))
+}
+
+// changeDoor moves the door in the given direction to a new state, telling the
+// room what the character did. The check function is called with the door's
+// current state and may veto the change by returning an error.
+func changeDoor(character *database.Character, dir database.Direction, state database.DoorState,
+	verb string, check func(database.Exit) error) error {
+	room := GetRoom(character.GetRoomId())
+
+	if room == nil {
+		return errors.New("Character doesn't appear to be in any room")
+	}
+
+	doorMutex.Lock()
+	defer doorMutex.Unlock()
+
+	exit, found := room.GetExit(dir)
+
+	if !found || !exit.HasDoor() {
+		return fmt.Errorf("There is no door %s", doorDirection(dir))
+	}
+
+	err := check(exit)
+
+	if err != nil {
+		return err
+	}
+
+	exit.Door = state
+	SetExit(room, dir, exit)
+
+	queueEvent(DoorEvent{Character: character, Room: room, Direction: dir, Verb: verb})
+
+	return nil
+}
+
+func OpenDoor(character *database.Character, dir database.Direction) error {
+	return changeDoor(character, dir, database.DoorOpen, "open", func(exit database.Exit) error {
+		if exit.IsLocked() {
+			return errors.New("The door is locked")
+		} else if exit.IsOpen() {
+			return errors.New("The door is already open")
+		}
+		return nil
+	})
+}
+
+func CloseDoor(character *database.Character, dir database.Direction) error {
+	return changeDoor(character, dir, database.DoorClosed, "close", func(exit database.Exit) error {
+		if !exit.IsOpen() {
+			return errors.New("The door is already closed")
+		}
+		return nil
+	})
+}
+
+// LockDoor locks a closed door, provided the character is carrying the key
+func LockDoor(character *database.Character, dir database.Direction) error {
+	return changeDoor(character, dir, database.DoorLocked, "lock", func(exit database.Exit) error {
+		if !exit.HasLock() {
+			return errors.New("The door doesn't have a lock")
+		} else if exit.IsLocked() {
+			return errors.New("The door is already locked")
+		} else if exit.IsOpen() {
+			return errors.New("You need to close the door first")
+		} else if FindKey(character, exit.Key) == nil {
+			return errors.New("You don't have the key")
+		}
+		return nil
+	})
+}
+
+// UnlockDoor unlocks a door, provided the character is carrying the key
+func UnlockDoor(character *database.Character, dir database.Direction) error {
+	return changeDoor(character, dir, database.DoorClosed, "unlock", func(exit database.Exit) error {
+		if !exit.IsLocked() {
+			return errors.New("The door isn't locked")
+		} else if FindKey(character, exit.Key) == nil {
+			return errors.New("You don't have the key")
+		}
+		return nil
+	})
+}
+
+// PickDoor tries to unlock a door without the key. The chance of failing is
+// the lock's difficulty.
+func PickDoor(character *database.Character, dir database.Direction) error {
+	return changeDoor(character, dir, database.DoorClosed, "pick the lock on", func(exit database.Exit) error {
+		if !exit.IsLocked() {
+			return errors.New("The door isn't locked")
+		} else if roll(1, 100) <= exit.Difficulty {
+			return errors.New("You fail to pick the lock")
+		}
+		return nil
+	})
+}
+
+// vim: nocindent
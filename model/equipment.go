@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"kmud/utils"
+)
+
+// Equip puts one of the character's items in to the slot that its type
+// dictates. The slot is returned if it succeeded.
+func Equip(character *database.Character, item *database.Item) (database.EquipmentSlot, error) {
+	if !character.HasItem(item) {
+		return "", errors.New("You don't have that")
+	}
+
+	slot, ok := item.EquipSlot()
+
+	if !ok {
+		return "", fmt.Errorf("You can't equip %s", item.GetName())
+	}
+
+	if _, equipped := character.EquippedSlot(item); equipped {
+		return "", fmt.Errorf("You are already using %s", item.GetName())
+	}
+
+	current := GetItem(character.GetEquipped(slot))
+
+	if current != nil {
+		return "", fmt.Errorf("You are already using %s, remove it first", current.GetName())
+	}
+
+	character.Equip(slot, item)
+	return slot, nil
+}
+
+// Unequip takes the given item off, leaving it in the character's inventory
+func Unequip(character *database.Character, item *database.Item) error {
+	slot, equipped := character.EquippedSlot(item)
+
+	if !equipped {
+		return fmt.Errorf("You aren't using %s", item.GetName())
+	}
+
+	character.Unequip(slot)
+	return nil
+}
+
+// EquippedItem returns the item the character has in the given slot, or nil
+func EquippedItem(character *database.Character, slot database.EquipmentSlot) *database.Item {
+	return GetItem(character.GetEquipped(slot))
+}
+
+// ArmorClass returns the total armor class of everything the character is
+// wearing, plus any armor given by their status effects
+func ArmorClass(character *database.Character) int {
+	_, _, armor := statusModifiers(character)
+
+	for _, slot := range database.EquipmentSlots {
+		item := EquippedItem(character, slot)
+
+		if item != nil {
+			armor += item.GetIntProperty(database.PropertyArmorClass)
+		}
+	}
+
+	return armor
+}
+
+// WeaponDamage returns the damage dice of the character's wielded weapon. An
+// empty string is returned if they aren't wielding anything with valid dice.
+func WeaponDamage(character *database.Character) string {
+	weapon := EquippedItem(character, database.SlotWielded)
+
+	if weapon == nil {
+		return ""
+	}
+
+	dice := weapon.GetProperty(database.PropertyDamage)
+
+	if _, _, _, err := utils.ParseDice(dice); err != nil {
+		return ""
+	}
+
+	return dice
+}
+
+// vim: nocindent
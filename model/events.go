@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"kmud/database"
 	"kmud/utils"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,6 +20,9 @@ func Login(character *database.Character) {
 }
 
 func Logout(character *database.Character) {
+	CancelTrade(character, character.GetName()+" has left")
+	logoutParty(character)
+	forgetFollowers(character)
 	character.SetOnline(false)
 	queueEvent(LogoutEvent{character})
 }
@@ -45,8 +49,6 @@ func Unregister(listenerToUnregsiter chan Event) {
 }
 
 func eventLoop() {
-	_eventQueueChannel = make(chan Event, 100)
-
 	var m sync.Mutex
 	cond := sync.NewCond(&m)
 
@@ -68,6 +70,7 @@ func eventLoop() {
 
 		for {
 			throttler.Sync()
+			tick(time.Now())
 			queueEvent(TimerEvent{})
 		}
 	}()
@@ -85,6 +88,13 @@ func eventLoop() {
 	}
 }
 
+// tick performs the periodic upkeep of the model that is driven by the game timer
+func tick(now time.Time) {
+	decayItems(now)
+	tickStatuses(now)
+	regenerate(now)
+}
+
 func queueEvent(event Event) {
 	_eventQueueChannel <- event
 }
@@ -115,6 +125,21 @@ const (
 	CombatStopEventType  EventType = iota
 	CombatEventType      EventType = iota
 	TimerEventType       EventType = iota
+	DeathEventType       EventType = iota
+	RespawnEventType     EventType = iota
+	DecayEventType       EventType = iota
+	GiveCashEventType    EventType = iota
+	TradeEventType       EventType = iota
+	DoorEventType        EventType = iota
+	MessageEventType     EventType = iota
+	RoomMessageEventType EventType = iota
+	GetItemEventType     EventType = iota
+	TalkEventType        EventType = iota
+	StatusEventType      EventType = iota
+	SpellEventType       EventType = iota
+	PositionEventType    EventType = iota
+	PartyEventType       EventType = iota
+	ChannelEventType     EventType = iota
 )
 
 type Event interface {
@@ -124,11 +149,11 @@ type Event interface {
 }
 
 type CreateEvent struct {
-	Object *database.DbObject
+	Object database.Identifiable
 }
 
 type DestroyEvent struct {
-	Object *database.DbObject
+	Object database.Identifiable
 }
 
 type BroadcastEvent struct {
@@ -184,17 +209,113 @@ type CombatStartEvent struct {
 type CombatStopEvent struct {
 	Attacker *database.Character
 	Defender *database.Character
+	Reason   CombatStopReason
 }
 
 type CombatEvent struct {
 	Attacker *database.Character
 	Defender *database.Character
+	Outcome  CombatOutcome
 	Damage   int
+	Killed   bool
+	Skill    string // Active skill the attacker used, if any
+	Dodged   bool   // The defender dodged out of the way of the attack
 }
 
 type TimerEvent struct {
 }
 
+type DeathEvent struct {
+	Character *database.Character
+	Killer    *database.Character
+	Room      *database.Room
+	Corpse    *database.Item
+}
+
+type RespawnEvent struct {
+	Character *database.Character
+	Room      *database.Room
+	CashLost  int
+}
+
+type DecayEvent struct {
+	Item *database.Item
+	Room *database.Room
+}
+
+type GiveCashEvent struct {
+	From   *database.Character
+	To     *database.Character
+	Amount int
+}
+
+type DoorEvent struct {
+	Character *database.Character
+	Room      *database.Room
+	Direction database.Direction
+	Verb      string
+}
+
+type MessageEvent struct {
+	Character *database.Character
+	Message   string
+}
+
+type RoomMessageEvent struct {
+	Room    *database.Room
+	Message string
+}
+
+type GetItemEvent struct {
+	Character *database.Character
+	Item      *database.Item
+	Room      *database.Room
+}
+
+type TalkEvent struct {
+	Character *database.Character
+	Npc       *database.Character
+}
+
+type ChannelEvent struct {
+	Channel *database.Channel
+	From    *database.Character
+	Message string
+	Time    time.Time
+}
+
+// PartyEvent is a message to the members of a party. From is nil for news
+// about the party itself.
+type PartyEvent struct {
+	Members []*database.Character
+	From    *database.Character
+	Message string
+}
+
+type PositionEvent struct {
+	Character *database.Character
+	Position  database.Position
+}
+
+type StatusEvent struct {
+	Character *database.Character
+	Status    *database.Status
+	Damage    int  // Damage done by a periodic tick, negative for healing
+	Ended     bool // The status has worn off
+}
+
+type SpellEvent struct {
+	Caster *database.Character
+	Target *database.Character
+	Spell  string
+	Room   *database.Room
+}
+
+type TradeEvent struct {
+	Character *database.Character
+	Message   string
+}
+
 func (self BroadcastEvent) Type() EventType {
 	return BroadcastEventType
 }
@@ -205,7 +326,7 @@ func (self BroadcastEvent) ToString(receiver *database.Character) string {
 }
 
 func (self BroadcastEvent) IsFor(receiver *database.Character) bool {
-	return true
+	return !receiver.IsIgnoring(self.Character)
 }
 
 // Say
@@ -226,7 +347,7 @@ func (self SayEvent) ToString(receiver *database.Character) string {
 }
 
 func (self SayEvent) IsFor(receiver *database.Character) bool {
-	return receiver.GetRoomId() == self.Character.GetRoomId()
+	return receiver.GetRoomId() == self.Character.GetRoomId() && !receiver.IsIgnoring(self.Character)
 }
 
 // Emote
@@ -239,7 +360,7 @@ func (self EmoteEvent) ToString(receiver *database.Character) string {
 }
 
 func (self EmoteEvent) IsFor(receiver *database.Character) bool {
-	return receiver.GetRoomId() == self.Character.GetRoomId()
+	return receiver.GetRoomId() == self.Character.GetRoomId() && !receiver.IsIgnoring(self.Character)
 }
 
 // Tell
@@ -253,7 +374,7 @@ func (self TellEvent) ToString(receiver *database.Character) string {
 }
 
 func (self TellEvent) IsFor(receiver *database.Character) bool {
-	return receiver.GetId() == self.To.GetId()
+	return receiver.GetId() == self.To.GetId() && receiver.AcceptsTellFrom(self.From)
 }
 
 // Enter
@@ -366,10 +487,26 @@ func (self CombatStopEvent) Type() EventType {
 }
 
 func (self CombatStopEvent) ToString(receiver *database.Character) string {
-	if receiver == self.Attacker {
-		return utils.Colorize(utils.ColorGreen, fmt.Sprintf("You stopped attacking %s", self.Defender.GetName()))
-	} else if receiver == self.Defender {
-		return utils.Colorize(utils.ColorGreen, fmt.Sprintf("%s has stopped attacking you", self.Attacker.GetName()))
+	switch self.Reason {
+	case StopReasonDeath:
+		// The CombatEvent that caused the death has already been reported
+		return ""
+	case StopReasonAttackerFled:
+		if receiver == self.Attacker {
+			return utils.Colorize(utils.ColorGreen, fmt.Sprintf("You flee from %s", self.Defender.GetName()))
+		} else if receiver == self.Defender {
+			return utils.Colorize(utils.ColorGreen, fmt.Sprintf("%s flees from you", self.Attacker.GetName()))
+		}
+	case StopReasonDefenderFled:
+		if receiver == self.Attacker {
+			return utils.Colorize(utils.ColorGreen, fmt.Sprintf("%s has fled", self.Defender.GetName()))
+		}
+	default:
+		if receiver == self.Attacker {
+			return utils.Colorize(utils.ColorGreen, fmt.Sprintf("You stopped attacking %s", self.Defender.GetName()))
+		} else if receiver == self.Defender {
+			return utils.Colorize(utils.ColorGreen, fmt.Sprintf("%s has stopped attacking you", self.Attacker.GetName()))
+		}
 	}
 
 	return ""
@@ -385,13 +522,59 @@ func (self CombatEvent) Type() EventType {
 }
 
 func (self CombatEvent) ToString(receiver *database.Character) string {
+	var message string
+
 	if receiver == self.Attacker {
-		return utils.Colorize(utils.ColorRed, fmt.Sprintf("You hit %s for %v damage", self.Defender.GetName(), self.Damage))
+		attack := "You"
+		if self.Skill != "" {
+			attack = "Your " + self.Skill
+		}
+
+		switch {
+		case self.Dodged:
+			message = fmt.Sprintf("%s dodges your attack", self.Defender.GetName())
+		case self.Outcome == CombatMiss && self.Skill != "":
+			message = fmt.Sprintf("%s misses %s", attack, self.Defender.GetName())
+		case self.Outcome == CombatMiss:
+			message = fmt.Sprintf("You miss %s", self.Defender.GetName())
+		case self.Outcome == CombatHit && self.Skill != "":
+			message = fmt.Sprintf("%s hits %s for %v damage", attack, self.Defender.GetName(), self.Damage)
+		case self.Outcome == CombatHit:
+			message = fmt.Sprintf("You hit %s for %v damage", self.Defender.GetName(), self.Damage)
+		case self.Outcome == CombatCritical:
+			message = fmt.Sprintf("You critically hit %s for %v damage!", self.Defender.GetName(), self.Damage)
+		}
+
+		if self.Killed {
+			message = message + fmt.Sprintf("\r\nYou have killed %s!", self.Defender.GetName())
+		}
 	} else if receiver == self.Defender {
-		return utils.Colorize(utils.ColorRed, fmt.Sprintf("%s hits you for %v damage", self.Attacker.GetName(), self.Damage))
+		attack := self.Attacker.GetName()
+		if self.Skill != "" {
+			attack = fmt.Sprintf("%s's %s", self.Attacker.GetName(), self.Skill)
+		}
+
+		switch {
+		case self.Dodged:
+			message = fmt.Sprintf("You dodge %s's attack", self.Attacker.GetName())
+		case self.Outcome == CombatMiss:
+			message = fmt.Sprintf("%s misses you", attack)
+		case self.Outcome == CombatHit:
+			message = fmt.Sprintf("%s hits you for %v damage", attack, self.Damage)
+		case self.Outcome == CombatCritical:
+			message = fmt.Sprintf("%s critically hits you for %v damage!", attack, self.Damage)
+		}
+
+		if self.Killed {
+			message = message + fmt.Sprintf("\r\n>> You have been killed by %s <<", self.Attacker.GetName())
+		}
 	}
 
-	return ""
+	if message == "" {
+		return ""
+	}
+
+	return utils.Colorize(utils.ColorRed, message)
 }
 
 func (self CombatEvent) IsFor(receiver *database.Character) bool {
@@ -411,6 +594,300 @@ func (self TimerEvent) IsFor(receiver *database.Character) bool {
 	return true
 }
 
+// Death
+func (self DeathEvent) Type() EventType {
+	return DeathEventType
+}
+
+func (self DeathEvent) ToString(receiver *database.Character) string {
+	if receiver == self.Character || receiver == self.Killer {
+		// Already told by the CombatEvent that did the killing
+		return ""
+	}
+
+	if self.Killer == nil {
+		return utils.Colorize(utils.ColorRed, fmt.Sprintf("%s has died", self.Character.GetName()))
+	}
+
+	return utils.Colorize(utils.ColorRed, fmt.Sprintf("%s has been killed by %s", self.Character.GetName(), self.Killer.GetName()))
+}
+
+func (self DeathEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId() || receiver == self.Character
+}
+
+// Respawn
+func (self RespawnEvent) Type() EventType {
+	return RespawnEventType
+}
+
+func (self RespawnEvent) ToString(receiver *database.Character) string {
+	message := "You have returned from the dead"
+
+	if self.CashLost > 0 {
+		message = message + fmt.Sprintf(", losing %v monies along the way", self.CashLost)
+	}
+
+	return utils.Colorize(utils.ColorWhite, message)
+}
+
+func (self RespawnEvent) IsFor(receiver *database.Character) bool {
+	return receiver == self.Character
+}
+
+// Decay
+func (self DecayEvent) Type() EventType {
+	return DecayEventType
+}
+
+func (self DecayEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(utils.ColorWhite, fmt.Sprintf("%s crumbles to dust", self.Item.GetName()))
+}
+
+func (self DecayEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId()
+}
+
+// Give cash
+func (self GiveCashEvent) Type() EventType {
+	return GiveCashEventType
+}
+
+func (self GiveCashEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(utils.ColorWhite, fmt.Sprintf("%s gives you %v coins", self.From.GetName(), self.Amount))
+}
+
+func (self GiveCashEvent) IsFor(receiver *database.Character) bool {
+	return receiver == self.To
+}
+
+// Door
+func (self DoorEvent) Type() EventType {
+	return DoorEventType
+}
+
+func (self DoorEvent) ToString(receiver *database.Character) string {
+	if receiver == self.Character {
+		return utils.Colorize(utils.ColorWhite, fmt.Sprintf("You %s the door %s", self.Verb, doorDirection(self.Direction)))
+	}
+
+	words := strings.SplitN(self.Verb, " ", 2)
+	words[0] = words[0] + "s"
+
+	return utils.Colorize(utils.ColorWhite, fmt.Sprintf("%s %s the door %s",
+		self.Character.GetName(), strings.Join(words, " "), doorDirection(self.Direction)))
+}
+
+func (self DoorEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId()
+}
+
+// Message
+func (self MessageEvent) Type() EventType {
+	return MessageEventType
+}
+
+func (self MessageEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(utils.ColorWhite, self.Message)
+}
+
+func (self MessageEvent) IsFor(receiver *database.Character) bool {
+	return receiver == self.Character
+}
+
+// Room message
+func (self RoomMessageEvent) Type() EventType {
+	return RoomMessageEventType
+}
+
+func (self RoomMessageEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(utils.ColorWhite, self.Message)
+}
+
+func (self RoomMessageEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId()
+}
+
+// Get item
+func (self GetItemEvent) Type() EventType {
+	return GetItemEventType
+}
+
+func (self GetItemEvent) ToString(receiver *database.Character) string {
+	if receiver == self.Character {
+		return ""
+	}
+
+	return utils.Colorize(utils.ColorWhite, fmt.Sprintf("%s picks up %s", self.Character.GetName(), self.Item.GetName()))
+}
+
+func (self GetItemEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId()
+}
+
+// Talk
+func (self TalkEvent) Type() EventType {
+	return TalkEventType
+}
+
+func (self TalkEvent) ToString(receiver *database.Character) string {
+	if receiver == self.Character || receiver == self.Npc {
+		return ""
+	}
+
+	return utils.Colorize(utils.ColorWhite, fmt.Sprintf("%s talks to %s", self.Character.GetName(), self.Npc.GetName()))
+}
+
+func (self TalkEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Npc.GetRoomId()
+}
+
+// Status
+func (self StatusEvent) Type() EventType {
+	return StatusEventType
+}
+
+func (self StatusEvent) ToString(receiver *database.Character) string {
+	var message string
+
+	switch {
+	case self.Ended:
+		message = self.Status.EndMessage
+	case self.Damage > 0:
+		message = fmt.Sprintf("%s hurts you for %v damage", utils.FormatName(self.Status.Name), self.Damage)
+	case self.Damage < 0:
+		message = fmt.Sprintf("%s heals you for %v", utils.FormatName(self.Status.Name), -self.Damage)
+	default:
+		message = self.Status.StartMessage
+	}
+
+	if message == "" {
+		return ""
+	}
+
+	return utils.Colorize(utils.ColorMagenta, message)
+}
+
+func (self StatusEvent) IsFor(receiver *database.Character) bool {
+	return receiver == self.Character
+}
+
+// Spell
+func (self SpellEvent) Type() EventType {
+	return SpellEventType
+}
+
+func (self SpellEvent) ToString(receiver *database.Character) string {
+	var message string
+
+	if receiver == self.Caster {
+		if self.Target == self.Caster {
+			message = fmt.Sprintf("You cast %s", self.Spell)
+		} else {
+			message = fmt.Sprintf("You cast %s on %s", self.Spell, self.Target.GetName())
+		}
+	} else if receiver == self.Target {
+		message = fmt.Sprintf("%s casts %s on you", self.Caster.GetName(), self.Spell)
+	} else if self.Target == self.Caster {
+		message = fmt.Sprintf("%s casts %s", self.Caster.GetName(), self.Spell)
+	} else {
+		message = fmt.Sprintf("%s casts %s on %s", self.Caster.GetName(), self.Spell, self.Target.GetName())
+	}
+
+	return utils.Colorize(utils.ColorMagenta, message)
+}
+
+func (self SpellEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Room.GetId() || receiver == self.Target
+}
+
+// Position
+func (self PositionEvent) Type() EventType {
+	return PositionEventType
+}
+
+func (self PositionEvent) ToString(receiver *database.Character) string {
+	you := receiver == self.Character
+	name := self.Character.GetName()
+
+	switch self.Position {
+	case database.PositionResting:
+		if you {
+			return "You sit down and rest"
+		}
+		return name + " sits down and rests"
+	case database.PositionSleeping:
+		if you {
+			return "You go to sleep"
+		}
+		return name + " goes to sleep"
+	default:
+		if you {
+			return "You stand up"
+		}
+		return name + " stands up"
+	}
+}
+
+func (self PositionEvent) IsFor(receiver *database.Character) bool {
+	return receiver.GetRoomId() == self.Character.GetRoomId()
+}
+
+// Channel
+func (self ChannelEvent) Type() EventType {
+	return ChannelEventType
+}
+
+func (self ChannelEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(self.Channel.Color, fmt.Sprintf("[%s] %s: ", self.Channel.Name, self.From.GetName())) +
+		utils.Colorize(utils.ColorWhite, self.Message)
+}
+
+func (self ChannelEvent) IsFor(receiver *database.Character) bool {
+	return OnChannel(receiver, self.Channel) && !receiver.IsIgnoring(self.From)
+}
+
+// Party
+func (self PartyEvent) Type() EventType {
+	return PartyEventType
+}
+
+func (self PartyEvent) ToString(receiver *database.Character) string {
+	if self.From == nil {
+		return utils.Colorize(utils.ColorGreen, "[Party] "+self.Message)
+	}
+
+	return utils.Colorize(utils.ColorGreen, "[Party] "+self.From.GetName()+": ") +
+		utils.Colorize(utils.ColorWhite, self.Message)
+}
+
+func (self PartyEvent) IsFor(receiver *database.Character) bool {
+	if self.From != nil && receiver.IsIgnoring(self.From) {
+		return false
+	}
+
+	for _, member := range self.Members {
+		if member == receiver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Trade
+func (self TradeEvent) Type() EventType {
+	return TradeEventType
+}
+
+func (self TradeEvent) ToString(receiver *database.Character) string {
+	return utils.Colorize(utils.ColorYellow, self.Message)
+}
+
+func (self TradeEvent) IsFor(receiver *database.Character) bool {
+	return receiver == self.Character
+}
+
 // Create
 func (self CreateEvent) Type() EventType {
 	return CreateEventType
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"sync"
+)
+
+// followMutex guards both of the maps below, which are always kept in step
+var followMutex sync.RWMutex
+var leaders = map[*database.Character]*database.Character{}     // Follower to who they follow
+var followers = map[*database.Character][]*database.Character{} // Leader to their followers, in the order they started following
+
+// Follow makes the follower move along with the leader whenever the leader
+// moves. Following someone who is directly or indirectly following the
+// follower would form a loop and isn't allowed.
+func Follow(follower *database.Character, leader *database.Character) error {
+	if follower == leader {
+		return errors.New("You can't follow yourself")
+	}
+
+	if follower.GetRoomId() != leader.GetRoomId() {
+		return errors.New("They aren't here")
+	}
+
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	if leaders[follower] == leader {
+		return fmt.Errorf("You are already following %s", leader.GetName())
+	}
+
+	for next := leaders[leader]; next != nil; next = leaders[next] {
+		if next == follower {
+			return fmt.Errorf("%s is already following you", leader.GetName())
+		}
+	}
+
+	unfollow(follower)
+	leaders[follower] = leader
+	followers[leader] = append(followers[leader], follower)
+
+	queueEvent(MessageEvent{Character: follower, Message: fmt.Sprintf("You are now following %s", leader.GetName())})
+	queueEvent(MessageEvent{Character: leader, Message: fmt.Sprintf("%s is now following you", follower.GetName())})
+	return nil
+}
+
+// StopFollowing stops the character following whoever they are following
+func StopFollowing(character *database.Character) error {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	leader := leaders[character]
+
+	if leader == nil {
+		return errors.New("You aren't following anyone")
+	}
+
+	unfollow(character)
+
+	queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You stop following %s", leader.GetName())})
+	queueEvent(MessageEvent{Character: leader, Message: fmt.Sprintf("%s stops following you", character.GetName())})
+	return nil
+}
+
+// Following returns who the character is following, or nil
+func Following(character *database.Character) *database.Character {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+
+	return leaders[character]
+}
+
+// Followers returns the characters that are following the character, in the
+// order they started following
+func Followers(character *database.Character) []*database.Character {
+	followMutex.RLock()
+	defer followMutex.RUnlock()
+
+	return append([]*database.Character(nil), followers[character]...)
+}
+
+// stopFollowingLeader stops the character following the leader, if that is
+// who they are following
+func stopFollowingLeader(character *database.Character, leader *database.Character) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	if leaders[character] == leader {
+		unfollow(character)
+		queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You stop following %s", leader.GetName())})
+	}
+}
+
+// transferFollowers makes those of the characters who were following the old
+// leader follow the new one instead. The new leader stops following the old
+// one, as does anyone who would form a loop by following the new leader.
+func transferFollowers(oldLeader *database.Character, newLeader *database.Character, characters []*database.Character) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	for _, character := range characters {
+		if leaders[character] != oldLeader {
+			continue
+		}
+
+		unfollow(character)
+
+		if character == newLeader {
+			queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You stop following %s", oldLeader.GetName())})
+			continue
+		}
+
+		loop := false
+		for next := leaders[newLeader]; next != nil; next = leaders[next] {
+			if next == character {
+				loop = true
+				break
+			}
+		}
+
+		if loop {
+			queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You stop following %s", oldLeader.GetName())})
+			continue
+		}
+
+		leaders[character] = newLeader
+		followers[newLeader] = append(followers[newLeader], character)
+		queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You are now following %s", newLeader.GetName())})
+	}
+}
+
+// forgetFollowers breaks every follow involving the character, for when they
+// leave the world
+func forgetFollowers(character *database.Character) {
+	followMutex.Lock()
+	defer followMutex.Unlock()
+
+	unfollow(character)
+
+	for _, follower := range followers[character] {
+		delete(leaders, follower)
+		queueEvent(MessageEvent{Character: follower, Message: fmt.Sprintf("You stop following %s", character.GetName())})
+	}
+
+	delete(followers, character)
+}
+
+// unfollow removes the character from their leader's followers. Must be
+// called with followMutex held.
+func unfollow(character *database.Character) {
+	leader := leaders[character]
+
+	if leader == nil {
+		return
+	}
+
+	delete(leaders, character)
+
+	list := followers[leader]
+	for i, follower := range list {
+		if follower == character {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+
+	if len(list) == 0 {
+		delete(followers, leader)
+	} else {
+		followers[leader] = list
+	}
+}
+
+// moveFollowers moves the leader's followers that were in the room the leader
+// just left, in the order they started following. The move function moves a
+// single follower the same way the leader went. Followers who are themselves
+// being followed bring their own followers along.
+func moveFollowers(leader *database.Character, from *database.Room, move func(*database.Character) error) {
+	for _, follower := range Followers(leader) {
+		if follower.GetRoomId() != from.GetId() {
+			continue
+		}
+
+		if err := move(follower); err != nil {
+			queueEvent(MessageEvent{Character: follower,
+				Message: fmt.Sprintf("You can't follow %s: %s", leader.GetName(), err.Error())})
+		}
+	}
+}
+
+// vim: nocindent
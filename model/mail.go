@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"sort"
+	"sync"
+)
+
+var mailMutex sync.RWMutex
+var _mail = map[bson.ObjectId]*database.Mail{}
+
+// IsPostOffice returns true if mail attachments can be collected in the room
+func IsPostOffice(room *database.Room) bool {
+	return room != nil && room.GetProperty(database.RoomPropertyPostOffice) == "true"
+}
+
+// SendMail delivers mail to another player's mailbox, whether they are online
+// or not. Any cash and items attached are taken from the sender straight away.
+func SendMail(from *database.Character, to *database.Character, subject string, body string,
+	cash int, items []*database.Item) (*database.Mail, error) {
+
+	if !to.IsPlayer() || to.IsNpcTemplate() {
+		return nil, fmt.Errorf("%s can't receive mail", to.GetName())
+	}
+
+	if to.IsIgnoring(from) {
+		return nil, fmt.Errorf("%s isn't accepting mail from you", to.GetName())
+	}
+
+	if subject == "" {
+		return nil, errors.New("Mail needs a subject")
+	}
+
+	items = uniqueItems(items)
+
+	// The attachments are taken in one step so that nothing else can move them
+	// between being checked and being attached
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	for _, item := range items {
+		if _, equipped := from.EquippedSlot(item); equipped {
+			return nil, fmt.Errorf("You need to remove %s first", item.GetName())
+		}
+	}
+
+	// Anything already taken is handed back if the send fails
+	var taken []*database.Item
+	giveBack := func() {
+		for _, item := range taken {
+			from.AddItem(item)
+		}
+	}
+
+	var itemIds []bson.ObjectId
+	for _, item := range items {
+		if !removeItem(from, item) {
+			giveBack()
+			return nil, fmt.Errorf("You aren't carrying %s", item.GetName())
+		}
+
+		taken = append(taken, item)
+		itemIds = append(itemIds, item.GetId())
+	}
+
+	if cash > 0 {
+		if err := TransferCash(from, nil, cash, "mail to "+to.GetName()); err != nil {
+			giveBack()
+			return nil, err
+		}
+	}
+
+	mail := database.NewMail(from, to, subject, body)
+	mail.SetCash(cash)
+	mail.SetItemIds(itemIds)
+
+	mailMutex.Lock()
+	_mail[mail.GetId()] = mail
+	mailMutex.Unlock()
+
+	queueEvent(MessageEvent{Character: to, Message: fmt.Sprintf("You have new mail from %s", from.GetName())})
+	return mail, nil
+}
+
+// uniqueItems returns the items with any repeats removed, keeping their order
+func uniqueItems(items []*database.Item) []*database.Item {
+	var unique []*database.Item
+	seen := map[*database.Item]bool{}
+
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			unique = append(unique, item)
+		}
+	}
+
+	return unique
+}
+
+// Mailbox returns the mail that has been sent to the character, oldest first
+func Mailbox(character *database.Character) []*database.Mail {
+	mailMutex.RLock()
+	defer mailMutex.RUnlock()
+
+	var mailbox []*database.Mail
+
+	for _, mail := range _mail {
+		if mail.GetToId() == character.GetId() {
+			mailbox = append(mailbox, mail)
+		}
+	}
+
+	sort.Sort(database.MailByTime(mailbox))
+	return mailbox
+}
+
+// UnreadMail returns how many messages in the character's mailbox they
+// haven't read yet
+func UnreadMail(character *database.Character) int {
+	unread := 0
+
+	for _, mail := range Mailbox(character) {
+		if !mail.IsRead() {
+			unread++
+		}
+	}
+
+	return unread
+}
+
+// DeleteMail removes mail from the character's mailbox. Mail with attachments
+// has to be collected first so that nothing is lost.
+func DeleteMail(character *database.Character, mail *database.Mail) error {
+	if mail.GetToId() != character.GetId() {
+		return errors.New("That isn't your mail")
+	}
+
+	if mail.HasAttachments() {
+		return errors.New("Collect the attachments at a post office first")
+	}
+
+	deleteMail(mail)
+	return nil
+}
+
+// CollectAttachments gives the character the cash and items attached to their
+// mail. It can only be done in a post office.
+func CollectAttachments(character *database.Character, mail *database.Mail) error {
+	if mail.GetToId() != character.GetId() {
+		return errors.New("That isn't your mail")
+	}
+
+	if !mail.HasAttachments() {
+		return errors.New("There is nothing attached to that mail")
+	}
+
+	if !IsPostOffice(GetRoom(character.GetRoomId())) {
+		return errors.New("You need to be at a post office to collect attachments")
+	}
+
+	mailMutex.Lock()
+	cash := mail.GetCash()
+	items := GetItems(mail.GetItemIds())
+	mail.SetCash(0)
+	mail.SetItemIds(nil)
+	mailMutex.Unlock()
+
+	if cash > 0 {
+		utils.HandleError(TransferCash(nil, character, cash, "mail from "+mail.GetFromName()))
+	}
+
+	for _, item := range items {
+		if item != nil {
+			character.AddItem(item)
+		}
+	}
+
+	return nil
+}
+
+// deleteMailTo deletes the mailbox of a character that is being deleted,
+// along with anything attached to it
+func deleteMailTo(character *database.Character) {
+	for _, mail := range Mailbox(character) {
+		for _, item := range GetItems(mail.GetItemIds()) {
+			if item != nil {
+				DeleteItem(item)
+			}
+		}
+
+		deleteMail(mail)
+	}
+}
+
+func deleteMail(mail *database.Mail) {
+	mailMutex.Lock()
+	delete(_mail, mail.GetId())
+	mailMutex.Unlock()
+
+	utils.HandleError(database.DeleteObject(mail))
+}
+
+// vim: nocindent
@@ -6,6 +6,7 @@ import (
 	"kmud/database"
 	"kmud/utils"
 	"labix.org/v2/mgo/bson"
+	"sort"
 	"sync"
 )
 
@@ -15,6 +16,7 @@ var _zones map[bson.ObjectId]*database.Zone
 var _areas map[bson.ObjectId]*database.Area
 var _rooms map[bson.ObjectId]*database.Room
 var _items map[bson.ObjectId]*database.Item
+var _spawners map[bson.ObjectId]*database.Spawner
 
 var mutex sync.RWMutex
 
@@ -113,6 +115,21 @@ func GetCharacterByName(name string) *database.Character {
 	return nil
 }
 
+// GetCharacters returns all of the characters in the model, including NPCs and
+// NPC templates
+func GetCharacters() []*database.Character {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var characters []*database.Character
+
+	for _, character := range _chars {
+		characters = append(characters, character)
+	}
+
+	return characters
+}
+
 func GetAllNpcs() []*database.Character {
 	mutex.RLock()
 	defer mutex.RUnlock()
@@ -283,6 +300,12 @@ func DeleteCharacterId(id bson.ObjectId) {
 // DeleteCharacter removes the character (either NPC or player-controlled)
 // associated with the given id from the model and from the database
 func DeleteCharacter(character *database.Character) {
+	forgetFollowers(character)
+
+	if character.IsPlayer() {
+		deleteMailTo(character)
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -479,6 +502,176 @@ func DeleteArea(area *database.Area) {
 	utils.HandleError(database.DeleteObject(area))
 }
 
+// GetRoomsInArea returns a slice containing all of the rooms that belong to
+// the given area
+func GetRoomsInArea(area *database.Area) []*database.Room {
+	allRooms := GetRooms()
+
+	var rooms []*database.Room
+
+	for _, room := range allRooms {
+		if room.GetAreaId() == area.GetId() {
+			rooms = append(rooms, room)
+		}
+	}
+
+	return rooms
+}
+
+// CreateRoomSpawner creates a spawner that keeps NPCs built from the given
+// template alive in the given room
+func CreateRoomSpawner(template *database.Character, room *database.Room) *database.Spawner {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	spawner := database.NewSpawner(template.GetId(), room.GetId(), "")
+	_spawners[spawner.GetId()] = spawner
+
+	return spawner
+}
+
+// CreateAreaSpawner creates a spawner that keeps NPCs built from the given
+// template alive anywhere within the given area
+func CreateAreaSpawner(template *database.Character, area *database.Area) *database.Spawner {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	spawner := database.NewSpawner(template.GetId(), "", area.GetId())
+	_spawners[spawner.GetId()] = spawner
+
+	return spawner
+}
+
+func GetSpawner(id bson.ObjectId) *database.Spawner {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return _spawners[id]
+}
+
+// GetSpawners returns all of the spawners in the model
+func GetSpawners() []*database.Spawner {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var spawners []*database.Spawner
+
+	for _, spawner := range _spawners {
+		spawners = append(spawners, spawner)
+	}
+
+	return spawners
+}
+
+// RoomSpawners returns the spawners that are attached to the given room
+func RoomSpawners(room *database.Room) []*database.Spawner {
+	var spawners []*database.Spawner
+
+	for _, spawner := range GetSpawners() {
+		if spawner.GetRoomId() == room.GetId() {
+			spawners = append(spawners, spawner)
+		}
+	}
+
+	return spawners
+}
+
+// AreaSpawners returns the spawners that are attached to the given area
+func AreaSpawners(area *database.Area) []*database.Spawner {
+	var spawners []*database.Spawner
+
+	for _, spawner := range GetSpawners() {
+		if spawner.GetAreaId() == area.GetId() {
+			spawners = append(spawners, spawner)
+		}
+	}
+
+	return spawners
+}
+
+func DeleteSpawner(spawner *database.Spawner) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(_spawners, spawner.GetId())
+	utils.HandleError(database.DeleteObject(spawner))
+}
+
+// SpawnedBy returns all of the living NPCs that were created by the given spawner
+func SpawnedBy(spawner *database.Spawner) []*database.Character {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var npcs []*database.Character
+
+	for _, char := range _chars {
+		if char.GetSpawnerId() == spawner.GetId() && char.IsNpc() {
+			npcs = append(npcs, char)
+		}
+	}
+
+	return npcs
+}
+
+// CreateNpcFromTemplate creates a new NPC in the given room, based on the
+// given template
+func CreateNpcFromTemplate(template *database.Character, room *database.Room) *database.Character {
+	mutex.Lock()
+	npc := database.NewNpcFromTemplate(template, room.GetId())
+	_chars[npc.GetId()] = npc
+	mutex.Unlock()
+
+	for _, loot := range template.GetLootTable() {
+		itemTemplate := GetItem(loot.TemplateId)
+
+		if itemTemplate != nil && roll(1, 100) <= loot.Chance {
+			npc.AddItem(CreateItemFromTemplate(itemTemplate))
+		}
+	}
+
+	queueEvent(CreateEvent{Object: npc})
+
+	return npc
+}
+
+// Spawn creates a new NPC for the given spawner. Area spawners place the NPC
+// in a random room within their area. Returns nil if the spawner's template or
+// location no longer exists.
+func Spawn(spawner *database.Spawner) *database.Character {
+	template := GetCharacter(spawner.GetTemplateId())
+
+	if template == nil || !template.IsNpcTemplate() {
+		return nil
+	}
+
+	room := GetRoom(spawner.GetRoomId())
+
+	if room == nil {
+		area := GetArea(spawner.GetAreaId())
+
+		if area == nil {
+			return nil
+		}
+
+		rooms := GetRoomsInArea(area)
+
+		if len(rooms) == 0 {
+			return nil
+		}
+
+		room = rooms[utils.Random(0, len(rooms)-1)]
+	}
+
+	npc := CreateNpcFromTemplate(template, room)
+	npc.SetSpawnerId(spawner.GetId())
+
+	if spawner.GetRoaming() {
+		npc.SetRoaming(true)
+	}
+
+	return npc
+}
+
 // DeleteRoom removes the given room object from the model and the database. It
 // also disables all exits in neighboring rooms that lead to the given room.
 func DeleteRoom(room *database.Room) {
@@ -489,6 +682,8 @@ func DeleteRoom(room *database.Room) {
 	mutex.Unlock()
 
 	// Disconnect all exits leading to this room
+	unlinkRoom(room)
+
 	loc := room.GetLocation()
 
 	updateRoom := func(dir database.Direction) {
@@ -496,7 +691,9 @@ func DeleteRoom(room *database.Room) {
 		room := GetRoomByLocation(next, GetZone(room.GetZoneId()))
 
 		if room != nil {
-			room.SetExitEnabled(dir.Opposite(), false)
+			if exit, _ := room.GetExit(dir.Opposite()); !exit.IsLinked() {
+				room.SetExitEnabled(dir.Opposite(), false)
+			}
 		}
 	}
 
@@ -533,6 +730,71 @@ func CreateItem(name string) *database.Item {
 	return item
 }
 
+func CreateItemTemplate(name string) *database.Item {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	template := database.NewItemTemplate(name)
+	_items[template.GetId()] = template
+
+	return template
+}
+
+// GetItemTemplates returns all of the item templates
+func GetItemTemplates() []*database.Item {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var templates []*database.Item
+
+	for _, item := range _items {
+		if item.IsTemplate() {
+			templates = append(templates, item)
+		}
+	}
+
+	return templates
+}
+
+// CreateItemFromTemplate creates a new copy of the given item template
+func CreateItemFromTemplate(template *database.Item) *database.Item {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	item := database.NewItemFromTemplate(template)
+	_items[item.GetId()] = item
+
+	return item
+}
+
+// ItemInstances returns all of the items that were created from the template
+func ItemInstances(template *database.Item) []*database.Item {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var instances []*database.Item
+
+	for _, item := range _items {
+		if item.GetTemplateId() == template.GetId() {
+			instances = append(instances, item)
+		}
+	}
+
+	return instances
+}
+
+// UpdateInstances copies the template's current settings on to every item
+// that was created from it. Returns the number of items updated.
+func UpdateInstances(template *database.Item) int {
+	instances := ItemInstances(template)
+
+	for _, item := range instances {
+		item.CopyFromTemplate(template)
+	}
+
+	return len(instances)
+}
+
 // GetItem returns the Item object associated the given id
 func GetItem(id bson.ObjectId) *database.Item {
 	mutex.RLock()
@@ -562,8 +824,14 @@ func DeleteItemId(itemId bson.ObjectId) {
 }
 
 // DeleteItem removes the item associated with the given id from the
-// model and from the database
+// model and from the database, along with everything inside of it
 func DeleteItem(item *database.Item) {
+	for _, content := range GetItems(item.GetItemIds()) {
+		if content != nil {
+			DeleteItem(content)
+		}
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -582,6 +850,7 @@ func Init(session database.Session) error {
 	_areas = map[bson.ObjectId]*database.Area{}
 	_rooms = map[bson.ObjectId]*database.Room{}
 	_items = map[bson.ObjectId]*database.Item{}
+	_spawners = map[bson.ObjectId]*database.Spawner{}
 
 	users := []*database.User{}
 	err := database.RetrieveObjects(database.UserType, &users)
@@ -596,6 +865,7 @@ func Init(session database.Session) error {
 	utils.HandleError(err)
 
 	for _, character := range characters {
+		character.MigrateResources()
 		_chars[character.GetId()] = character
 	}
 
@@ -620,6 +890,7 @@ func Init(session database.Session) error {
 	utils.HandleError(err)
 
 	for _, room := range rooms {
+		room.MigrateExits()
 		_rooms[room.GetId()] = room
 	}
 
@@ -631,7 +902,60 @@ func Init(session database.Session) error {
 		_items[item.GetId()] = item
 	}
 
-	// Start the event loop
+	spawners := []*database.Spawner{}
+	err = database.RetrieveObjects(database.SpawnerType, &spawners)
+	utils.HandleError(err)
+
+	for _, spawner := range spawners {
+		_spawners[spawner.GetId()] = spawner
+	}
+
+	transactions := []*database.Transaction{}
+	err = database.RetrieveObjects(database.TransactionType, &transactions)
+	utils.HandleError(err)
+
+	sort.Sort(database.TransactionsByTime(transactions))
+
+	transactionsMutex.Lock()
+	_transactions = transactions
+	transactionsMutex.Unlock()
+
+	scripts := []*database.Script{}
+	err = database.RetrieveObjects(database.ScriptType, &scripts)
+	utils.HandleError(err)
+
+	scriptsMutex.Lock()
+	_scripts = map[bson.ObjectId]*database.Script{}
+	for _, script := range scripts {
+		_scripts[script.GetId()] = script
+	}
+	scriptsMutex.Unlock()
+
+	quests := []*database.Quest{}
+	err = database.RetrieveObjects(database.QuestType, &quests)
+	utils.HandleError(err)
+
+	questsMutex.Lock()
+	_quests = map[bson.ObjectId]*database.Quest{}
+	for _, quest := range quests {
+		_quests[quest.GetId()] = quest
+	}
+	questsMutex.Unlock()
+
+	mail := []*database.Mail{}
+	err = database.RetrieveObjects(database.MailType, &mail)
+	utils.HandleError(err)
+
+	mailMutex.Lock()
+	_mail = map[bson.ObjectId]*database.Mail{}
+	for _, message := range mail {
+		_mail[message.GetId()] = message
+	}
+	mailMutex.Unlock()
+
+	// Start the event loop. The queue is created up front so that events
+	// can't be queued on a nil channel before the loop gets going.
+	_eventQueueChannel = make(chan Event, 100)
 	go eventLoop()
 
 	fights = map[*database.Character]*database.Character{}
@@ -655,6 +979,8 @@ func MoveCharacterToLocation(character *database.Character, zone *database.Zone,
 
 // MoveCharacterTo room moves the character to the given room
 func MoveCharacterToRoom(character *database.Character, newRoom *database.Room) {
+	CancelTrade(character, character.GetName()+" has left")
+
 	oldRoomId := character.GetRoomId()
 	character.SetRoomId(newRoom.GetId())
 
@@ -662,13 +988,41 @@ func MoveCharacterToRoom(character *database.Character, newRoom *database.Room)
 
 	queueEvent(EnterEvent{Character: character, Room: newRoom, SourceRoom: oldRoom})
 	queueEvent(LeaveEvent{Character: character, Room: oldRoom, DestRoom: newRoom})
+
+	// Enter and leave triggers are written for players, NPCs wandering about
+	// shouldn't keep setting them off
+	if character.IsPlayer() {
+		if oldRoom != nil {
+			RunTriggers(oldRoom, character, database.TriggerLeave)
+		}
+		RunTriggers(newRoom, character, database.TriggerEnter)
+	}
+
+	if oldRoom != nil {
+		scriptsOnLeave(oldRoom, character)
+	}
+	scriptsOnEnter(newRoom, character)
 }
 
 // MoveCharacter moves the given character in the given direction. If there is
 // no exit in that direction, and error is returned. If there is an exit, but no
 // room connected to it, then a room is automatically created for the character
-// to move in to.
+// to move in to. Party members following the character go with them.
 func MoveCharacter(character *database.Character, direction database.Direction) (*database.Room, error) {
+	from := GetRoom(character.GetRoomId())
+	room, err := moveCharacter(character, direction)
+
+	if err == nil {
+		moveFollowers(character, from, func(follower *database.Character) error {
+			_, err := MoveCharacter(follower, direction)
+			return err
+		})
+	}
+
+	return room, err
+}
+
+func moveCharacter(character *database.Character, direction database.Direction) (*database.Room, error) {
 	room := GetRoom(character.GetRoomId())
 
 	if room == nil {
@@ -679,6 +1033,20 @@ func MoveCharacter(character *database.Character, direction database.Direction)
 		return room, errors.New("Attempted to move through an exit that the room does not contain")
 	}
 
+	if character.GetPosition() != database.PositionStanding {
+		return room, errors.New("You need to stand up first")
+	}
+
+	exit, _ := room.GetExit(direction)
+
+	if exit.IsLinked() {
+		return followExit(character, room, exit)
+	}
+
+	if !exit.IsOpen() {
+		return room, errors.New("The door is closed")
+	}
+
 	newLocation := room.NextLocation(direction)
 	newRoom := GetRoomByLocation(newLocation, GetZone(room.GetZoneId()))
 
@@ -729,9 +1097,15 @@ func BroadcastMessage(from *database.Character, message string) {
 	queueEvent(BroadcastEvent{from, message})
 }
 
-// Tell sends a message to the specified character
-func Tell(from *database.Character, to *database.Character, message string) {
+// Tell sends a message to the specified character, unless they aren't
+// accepting tells from the sender
+func Tell(from *database.Character, to *database.Character, message string) error {
+	if !to.AcceptsTellFrom(from) {
+		return fmt.Errorf("%s isn't accepting tells from you", to.GetName())
+	}
+
 	queueEvent(TellEvent{from, to, message})
+	return nil
 }
 
 // Say sends a message to all characters in the given character's room
@@ -5,6 +5,8 @@ import (
 	"kmud/database/dbtest"
 	"kmud/testutils"
 	tu "kmud/testutils"
+	"labix.org/v2/mgo/bson"
+	"strings"
 	"testing"
 	"time"
 )
@@ -162,6 +164,7 @@ func Test_EventLoop(t *testing.T) {
 	char := CreatePlayer("char", user, room)
 
 	eventChannel := Register()
+	defer Unregister(eventChannel)
 
 	message := "hey how are yah"
 	queueEvent(TellEvent{char, char, message})
@@ -187,6 +190,24 @@ func Test_EventLoop(t *testing.T) {
 	_cleanup(t)
 }
 
+// stubRolls replaces the combat dice with a function that returns the given
+// values in order. The returned function restores the real dice.
+func stubRolls(t *testing.T, values ...int) func() {
+	original := roll
+
+	roll = func(low, high int) int {
+		if len(values) == 0 {
+			t.Fatal("Ran out of stubbed rolls")
+		}
+
+		value := values[0]
+		values = values[1:]
+		return value
+	}
+
+	return func() { roll = original }
+}
+
 func Test_CombatLoop(t *testing.T) {
 	zone, _ := CreateZone("zone")
 	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
@@ -195,37 +216,1324 @@ func Test_CombatLoop(t *testing.T) {
 	char1 := CreatePlayer("char1", user, room)
 	char2 := CreatePlayer("char2", user, room)
 
-	eventChannel1 := Register()
-	// eventChannel2 := Register(char2)
+	eventChannel := Register()
+	defer Unregister(eventChannel)
 
 	StartFight(char1, char2)
-	// StartFight(char2, char1)
-
-	verifyEvents := func(eventChannel chan Event) {
-		timeout := testutils.Timeout(3 * time.Second)
-		expectedTypes := make(map[EventType]bool)
-		expectedTypes[CombatEventType] = true
-		expectedTypes[CombatStartEventType] = true
-
-		for {
-			select {
-			case event := <-eventChannel1:
-				if event.Type() != TimerEventType {
-					tu.Assert(expectedTypes[event.Type()] == true, t, "Unexpected event type:", event.Type())
-					delete(expectedTypes, event.Type())
-				}
-			case <-timeout:
-				tu.Assert(false, t, "Timed out waiting for combat event")
-			}
 
-			if len(expectedTypes) == 0 {
-				break
+	timeout := testutils.Timeout(2 * combatInterval)
+	expectedTypes := make(map[EventType]bool)
+	expectedTypes[CombatEventType] = true
+	expectedTypes[CombatStartEventType] = true
+
+	for len(expectedTypes) > 0 {
+		select {
+		case event := <-eventChannel:
+			if event.Type() != TimerEventType {
+				tu.Assert(event.Type() == CombatEventType || event.Type() == CombatStartEventType, t, "Unexpected event type:", event.Type())
+				delete(expectedTypes, event.Type())
 			}
+		case <-timeout:
+			tu.Assert(false, t, "Timed out waiting for combat event")
+			return
+		}
+	}
+
+	StopFight(char1)
+	StopFight(char2)
+
+	_cleanup(t)
+}
+
+func Test_CombatResolution(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	attacker := CreatePlayer("attacker", user, room)
+	defender := CreatePlayer("defender", user, room)
+
+	restore := stubRolls(t, 50, 6, 90, 3, 4)
+	defer restore()
+
+	event := resolveAttack(attacker, defender)
+	tu.Assert(event.Outcome == CombatHit, t, "Expected a hit", event.Outcome)
+	tu.Assert(event.Damage == 6, t, "Wrong damage for a hit", event.Damage)
+	tu.Assert(defender.GetHitPoints() == 94, t, "Hit wasn't applied to the defender", defender.GetHitPoints())
+
+	event = resolveAttack(attacker, defender)
+	tu.Assert(event.Outcome == CombatMiss, t, "Expected a miss", event.Outcome)
+	tu.Assert(event.Damage == 0, t, "Misses shouldn't do damage", event.Damage)
+
+	event = resolveAttack(attacker, defender)
+	tu.Assert(event.Outcome == CombatCritical, t, "Expected a critical hit", event.Outcome)
+	tu.Assert(event.Damage == 8, t, "Critical hits should do double damage", event.Damage)
+	tu.Assert(!event.Killed, t, "Defender shouldn't have been killed")
+
+	attacker.SetDexterity(40)
+	tu.Assert(hitChance(attacker, defender) == maxHitChance, t, "Hit chance should be capped", hitChance(attacker, defender))
+	tu.Assert(hitChance(defender, attacker) == minHitChance, t, "Hit chance should have a floor", hitChance(defender, attacker))
+
+	attacker.SetStrength(14)
+	restore = stubRolls(t, 50, 5)
+	event = resolveAttack(attacker, defender)
+	tu.Assert(event.Damage == 7, t, "Strength should add to damage", event.Damage)
+
+	defender.SetHitPoints(3)
+	restore = stubRolls(t, 50, 5)
+	event = resolveAttack(attacker, defender)
+	tu.Assert(event.Killed, t, "Defender should have been killed")
+
+	_cleanup(t)
+}
+
+func Test_CombatParticipants(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	char1 := CreatePlayer("char1", user, room)
+	char2 := CreatePlayer("char2", user, room)
+	npc := CreateNpc("npc", room)
+
+	tu.Assert(StartFight(char1, char2) == nil, t, "Failed to start fight")
+	tu.Assert(fights[char2] == char1, t, "Defender should have retaliated")
+
+	tu.Assert(StartFight(npc, char2) == nil, t, "Failed to start second fight")
+	tu.Assert(fights[char2] == char1, t, "Defender shouldn't switch targets when attacked by someone else")
+	tu.Assert(len(Opponents(char2)) == 2, t, "Defender should have two opponents", len(Opponents(char2)))
+
+	// char2 kills char1 and should turn on the NPC
+	char1.SetHitPoints(1)
+	restore := stubRolls(t, 50, 5, 50, 5, 50, 5)
+	defer restore()
+
+	combatRound()
+
+	tu.Assert(char1.GetHitPoints() == char1.GetHealth()/4, t, "Dead player should have respawned", char1.GetHitPoints())
+	tu.Assert(!InCombat(char1), t, "Dead characters shouldn't be in combat")
+	tu.Assert(fights[char2] == npc, t, "Defender should have switched to their remaining attacker", fights[char2])
+
+	// Kill the NPC, which should remove it from the world
+	npc.SetHitPoints(1)
+	restore = stubRolls(t, 50, 5, 50, 5)
+	combatRound()
+
+	tu.Assert(GetCharacter(npc.GetId()) == nil, t, "Dead NPCs should be removed")
+	tu.Assert(!InCombat(char2), t, "Fight should be over")
+
+	char1.SetHitPoints(0)
+	tu.Assert(StartFight(char2, char1) != nil, t, "Shouldn't be able to attack a dead character")
+
+	_cleanup(t)
+}
+
+func Test_Flee(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+	room1.SetExitEnabled(database.DirectionSouth, true)
+	room2.SetExitEnabled(database.DirectionNorth, true)
+
+	user := CreateUser("user", "password")
+	char1 := CreatePlayer("char1", user, room1)
+	char2 := CreatePlayer("char2", user, room1)
+
+	_, err := Flee(char1, database.DirectionSouth)
+	tu.Assert(err != nil, t, "Shouldn't be able to flee when not in combat")
+
+	StartFight(char2, char1)
+
+	_, err = Flee(char1, database.DirectionNorth)
+	tu.Assert(err != nil, t, "Shouldn't be able to flee through a missing exit")
+
+	restore := stubRolls(t, 99, 10, 10)
+	defer restore()
+
+	_, err = Flee(char1, database.DirectionSouth)
+	tu.Assert(err != nil, t, "Flee should have failed")
+	tu.Assert(InCombat(char1), t, "Failed flee shouldn't end combat")
+
+	room1.SetExit(database.DirectionSouth, database.Exit{Door: database.DoorClosed})
+	_, err = Flee(char1, database.DirectionSouth)
+	tu.Assert(err != nil, t, "Shouldn't be able to flee through a closed door")
+	_, err = Flee(char1, database.DirectionNone)
+	tu.Assert(err != nil && char1.GetRoomId() == room1.GetId(), t, "Shouldn't pick a closed door to flee through")
+	tu.Assert(InCombat(char1), t, "Being unable to flee shouldn't end combat")
+	room1.SetExit(database.DirectionSouth, database.Exit{Door: database.DoorOpen})
+
+	newRoom, err := Flee(char1, database.DirectionSouth)
+	tu.Assert(err == nil, t, "Flee should have succeeded", err)
+	tu.Assert(newRoom == room2 && char1.GetRoomId() == room2.GetId(), t, "Fleeing character should have moved")
+	tu.Assert(!InCombat(char1) && !InCombat(char2), t, "Fleeing should end the fight")
+
+	// Linked exits lead wherever they point, not to the next room on the grid
+	room3, _ := CreateRoom(zone, database.Coordinate{X: 5, Y: 5, Z: 0})
+	LinkExit(room2, database.DirectionEast, room3)
+	MoveCharacterToRoom(char2, room2)
+	StartFight(char2, char1)
+
+	newRoom, err = Flee(char1, database.DirectionEast)
+	tu.Assert(err == nil && newRoom == room3, t, "Should be able to flee through a linked exit", err)
+
+	_cleanup(t)
+}
+
+func Test_Death(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	recallRoom, _ := CreateRoom(zone, database.Coordinate{X: 5, Y: 5, Z: 0})
+	user := CreateUser("user", "password")
+
+	player := CreatePlayer("player", user, room)
+	npc := CreateNpc("npc", room)
+
+	item := CreateItem("sword")
+	npc.AddItem(item)
+	npc.SetCash(50)
+
+	Kill(npc, player)
+
+	tu.Assert(GetCharacter(npc.GetId()) == nil, t, "Dead NPC should have been removed")
+
+	items := ItemsIn(room)
+	tu.Assert(len(items) == 1, t, "NPC should have left a corpse behind", len(items))
+
+	corpse := items[0]
+	tu.Assert(corpse.HasItem(item), t, "Corpse should hold the NPC's inventory")
+
+	coins := 0
+	for _, content := range GetItems(corpse.GetItemIds()) {
+		if IsCoins(content) {
+			coins += content.GetCash()
 		}
 	}
+	tu.Assert(coins == 50, t, "Corpse should hold the NPC's cash", coins)
+
+	decayItems(corpse.GetDecay().Add(time.Second))
+	tu.Assert(GetItem(corpse.GetId()) == nil, t, "Corpse should have decayed")
+	tu.Assert(GetItem(item.GetId()) == nil, t, "Corpse contents should have decayed with it")
+	tu.Assert(len(room.GetItemIds()) == 0, t, "Decayed corpse should have been removed from the room")
+
+	player.SetCash(100)
+	player.SetHitPoints(0)
+	Kill(player, nil)
+
+	tu.Assert(player.GetRoomId() == room.GetId(), t, "Player should respawn in place when the zone has no recall room")
+
+	zone.SetRecallRoomId(recallRoom.GetId())
+	player.SetHitPoints(0)
+	Kill(player, nil)
+
+	tu.Assert(player.GetRoomId() == recallRoom.GetId(), t, "Player should have been sent to the recall room")
+	tu.Assert(player.GetCash() == 81, t, "Player should have lost cash for dying", player.GetCash())
+	tu.Assert(!player.IsDead(), t, "Player should have been brought back to life")
+
+	_cleanup(t)
+}
+
+func Test_Dialogue(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	player := CreatePlayer("player", user, room)
+	npc := CreateNpc("npc", room)
+
+	key := CreateItem("key")
+	npc.AddItem(key)
+
+	npc.SetTopics([]database.Topic{
+		{Keyword: "treasure", Response: "It's buried", Conditions: []database.Condition{
+			{Type: database.ConditionFlag, Value: "map"},
+		}},
+		{Keyword: "weather", Response: "Sunny"},
+	})
+
+	tu.Assert(AskAbout(player, npc, "weath") == "Sunny", t, "Should have matched the topic keyword")
+	tu.Assert(AskAbout(player, npc, "treasure") == "", t, "Topic with unmet conditions shouldn't be available")
+
+	player.SetCash(20)
+
+	cashCondition := []database.Condition{{Type: database.ConditionCash, Amount: 30}}
+	tu.Assert(!ConditionsMet(player, cashCondition), t, "Player shouldn't have enough cash")
+
+	ApplyEffects(player, npc, []database.Effect{
+		{Type: database.EffectTakeCash, Amount: 10},
+		{Type: database.EffectGiveItem, Value: "key"},
+		{Type: database.EffectSetFlag, Value: "Map"},
+	})
+
+	tu.Assert(player.GetCash() == 10 && npc.GetCash() == 10, t, "Cash should have changed hands", player.GetCash(), npc.GetCash())
+	tu.Assert(player.HasItem(key) && !npc.HasItem(key), t, "NPC should have handed over its key")
+	tu.Assert(AskAbout(player, npc, "treasure") == "It's buried", t, "Setting the flag should unlock the topic")
+
+	node := database.DialogueNode{Text: "Hello", Choices: []database.DialogueChoice{
+		{Text: "Give key", Conditions: []database.Condition{{Type: database.ConditionHasItem, Value: "Key"}}},
+		{Text: "Leave"},
+		{Text: "Hide", Conditions: []database.Condition{{Type: database.ConditionNoFlag, Value: "map"}}},
+	}}
+
+	choices := AvailableChoices(player, node)
+	tu.Assert(len(choices) == 2 && choices[0].Text == "Give key", t, "Wrong choices available", choices)
+
+	_cleanup(t)
+}
+
+func Test_Equipment(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	attacker := CreatePlayer("attacker", user, room)
+	defender := CreatePlayer("defender", user, room)
+
+	sword := CreateItem("sword")
+	sword.SetKind(database.ItemKindWeapon)
+	sword.SetProperty(database.PropertyDamage, "2d6+1")
+
+	dagger := CreateItem("dagger")
+	dagger.SetKind(database.ItemKindWeapon)
+
+	helmet := CreateItem("helmet")
+	helmet.SetKind(database.ItemKindArmor)
+	helmet.SetProperty(database.PropertySlot, string(database.SlotHead))
+	helmet.SetIntProperty(database.PropertyArmorClass, 3)
+
+	bread := CreateItem("bread")
+	bread.SetKind(database.ItemKindFood)
+
+	_, err := Equip(attacker, sword)
+	tu.Assert(err != nil, t, "Shouldn't be able to equip an item you aren't carrying")
+
+	attacker.AddItem(sword)
+	attacker.AddItem(dagger)
+	attacker.AddItem(bread)
+	defender.AddItem(helmet)
+
+	slot, err := Equip(attacker, sword)
+	tu.Assert(err == nil && slot == database.SlotWielded, t, "Failed to wield sword", err)
+
+	_, err = Equip(attacker, dagger)
+	tu.Assert(err != nil, t, "Shouldn't be able to wield two weapons")
+
+	_, err = Equip(attacker, bread)
+	tu.Assert(err != nil, t, "Shouldn't be able to equip food")
+
+	slot, err = Equip(defender, helmet)
+	tu.Assert(err == nil && slot == database.SlotHead, t, "Helmet should be worn on the head", slot, err)
+	tu.Assert(ArmorClass(defender) == 3, t, "Wrong armor class", ArmorClass(defender))
+
+	// Hit roll, then two damage dice: 3 + 4 + 1 (bonus) - 3 (armor) = 5
+	restore := stubRolls(t, 50, 3, 4)
+	event := resolveAttack(attacker, defender)
+	restore()
+
+	tu.Assert(event.Damage == 5, t, "Weapon damage should have been reduced by armor", event.Damage)
+
+	attacker.RemoveItem(sword)
+	_, equipped := attacker.EquippedSlot(sword)
+	tu.Assert(!equipped, t, "Removing an item from the inventory should unequip it")
+
+	tu.Assert(Unequip(defender, helmet) == nil, t, "Failed to remove helmet")
+	tu.Assert(defender.HasItem(helmet) && ArmorClass(defender) == 0, t, "Removed helmet should stay in the inventory")
+
+	_cleanup(t)
+}
+
+func Test_Containers(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room)
+
+	bag := CreateItem("bag")
+	bag.SetKind(database.ItemKindContainer)
+	bag.SetIntProperty(database.PropertyCapacity, 2)
+	bag.SetIntProperty(database.PropertyMaxWeight, 10)
+	bag.SetProperty(database.PropertyKey, "bagkey")
+
+	pouch := CreateItem("pouch")
+	pouch.SetKind(database.ItemKindContainer)
+
+	rock := CreateItem("rock")
+	rock.SetIntProperty(database.PropertyWeight, 8)
+
+	anvil := CreateItem("anvil")
+	anvil.SetIntProperty(database.PropertyWeight, 50)
+
+	coin := CreateItem("coin")
+
+	key := CreateItem("key")
+	key.SetKind(database.ItemKindKey)
+	key.SetProperty(database.PropertyKey, "bagkey")
+
+	for _, item := range []*database.Item{bag, pouch, rock, anvil, coin} {
+		player.AddItem(item)
+	}
+
+	tu.Assert(PutItem(player, coin, rock) != nil, t, "Shouldn't be able to put things in a non-container")
+	tu.Assert(PutItem(player, anvil, bag) != nil, t, "Bag shouldn't be able to hold the anvil")
+	tu.Assert(PutItem(player, rock, bag) == nil, t, "Failed to put rock in bag")
+	tu.Assert(!player.HasItem(rock) && bag.HasItem(rock), t, "Rock should have moved in to the bag")
+	tu.Assert(Weight(bag) == 8, t, "Bag should weigh as much as its contents", Weight(bag))
+
+	tu.Assert(PutItem(player, pouch, pouch) != nil, t, "Shouldn't be able to put a container inside itself")
+	tu.Assert(PutItem(player, pouch, bag) == nil, t, "Failed to put pouch in bag")
+	tu.Assert(PutItem(player, coin, bag) != nil, t, "Bag should be full")
+
+	tu.Assert(SetItemLocked(player, bag, true) != nil, t, "Shouldn't be able to lock without the key")
+	player.AddItem(key)
+	tu.Assert(SetItemLocked(player, bag, true) == nil, t, "Failed to lock bag")
+	tu.Assert(TakeItemFrom(player, rock, bag) != nil, t, "Shouldn't be able to take from a locked bag")
+
+	tu.Assert(SetItemLocked(player, bag, false) == nil, t, "Failed to unlock bag")
+	tu.Assert(TakeItemFrom(player, rock, bag) == nil, t, "Failed to take rock from bag")
+	tu.Assert(player.HasItem(rock) && !bag.HasItem(rock), t, "Rock should be back in the inventory")
+
+	DeleteItem(bag)
+	tu.Assert(GetItem(pouch.GetId()) == nil, t, "Deleting a container should delete its contents")
+
+	_cleanup(t)
+}
+
+func Test_ItemTemplates(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+
+	template := CreateItemTemplate("sword")
+	template.SetKind(database.ItemKindWeapon)
+	template.SetProperty(database.PropertyDamage, "1d6")
+
+	tu.Assert(len(GetItemTemplates()) == 1, t, "Template should be listed")
+
+	sword := CreateItemFromTemplate(template)
+	tu.Assert(!sword.IsTemplate() && sword.GetTemplateId() == template.GetId(), t, "Copy should reference its template")
+	tu.Assert(sword.GetKind() == database.ItemKindWeapon && sword.GetProperty(database.PropertyDamage) == "1d6", t, "Copy should match its template")
+
+	template.SetProperty(database.PropertyDamage, "2d6")
+	tu.Assert(sword.GetProperty(database.PropertyDamage) == "1d6", t, "Copies shouldn't share properties with the template")
+
+	tu.Assert(UpdateInstances(template) == 1, t, "One copy should have been updated")
+	tu.Assert(sword.GetProperty(database.PropertyDamage) == "2d6", t, "Copy should have been updated")
+
+	npcTemplate := CreateNpcTemplate("orc")
+	npcTemplate.SetLootTable([]database.LootEntry{
+		{TemplateId: template.GetId(), Chance: 50},
+		{TemplateId: template.GetId(), Chance: 50},
+	})
+
+	restore := stubRolls(t, 50, 51)
+	npc := CreateNpcFromTemplate(npcTemplate, room)
+	restore()
+
+	tu.Assert(len(npc.GetItemIds()) == 1, t, "NPC should have rolled one piece of loot", len(npc.GetItemIds()))
+
+	_cleanup(t)
+}
+
+func Test_Currency(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	player1 := CreatePlayer("player1", user, room)
+	player2 := CreatePlayer("player2", user, room)
+
+	TransferCash(nil, player1, 100, "test")
+
+	tu.Assert(GiveCash(player1, player2, 200) != nil, t, "Shouldn't be able to give more than you have")
+	tu.Assert(GiveCash(player1, player2, -5) != nil, t, "Shouldn't be able to give a negative amount")
+	tu.Assert(GiveCash(player1, player2, 30) == nil, t, "Failed to give cash")
+	tu.Assert(player1.GetCash() == 70 && player2.GetCash() == 30, t, "Cash should have moved", player1.GetCash(), player2.GetCash())
+	tu.Assert(len(GetTransactions(player2)) == 1, t, "Transfer should have been recorded", len(GetTransactions(player2)))
+
+	tu.Assert(DropCash(player1, room, 20) == nil, t, "Failed to drop cash")
+	tu.Assert(DropCash(player1, room, 30) == nil, t, "Failed to drop more cash")
+
+	items := ItemsIn(room)
+	tu.Assert(len(items) == 1 && IsCoins(items[0]), t, "Dropped coins should form a single pile", len(items))
+	tu.Assert(items[0].GetCash() == 50 && player1.GetCash() == 20, t, "Pile should hold the dropped cash", items[0].GetCash())
+
+	pile := items[0]
+	results := make(chan error)
+
+	for _, player := range []*database.Character{player1, player2} {
+		go func(player *database.Character) {
+			results <- PickupItem(player, pile, room)
+		}(player)
+	}
+
+	err1 := <-results
+	err2 := <-results
+
+	tu.Assert((err1 == nil) != (err2 == nil), t, "Exactly one character should get the coins", err1, err2)
+	tu.Assert(player1.GetCash()+player2.GetCash() == 100, t, "Picking up coins shouldn't create or lose cash")
+	tu.Assert(len(ItemsIn(room)) == 0 && GetItem(pile.GetId()) == nil, t, "Picked up coins should be removed")
+
+	_cleanup(t)
+}
+
+func Test_Trade(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	otherRoom, _ := CreateRoom(zone, database.Coordinate{X: 1, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	player1 := CreatePlayer("player1", user, room)
+	player2 := CreatePlayer("player2", user, room)
+	player3 := CreatePlayer("player3", user, room)
+
+	for _, player := range []*database.Character{player1, player2, player3} {
+		player.SetOnline(true)
+	}
+
+	sword := CreateItem("sword")
+	shield := CreateItem("shield")
+	player1.AddItem(sword)
+	player2.AddItem(shield)
+
+	TransferCash(nil, player1, 100, "test")
+	TransferCash(nil, player2, 50, "test")
+
+	_, err := StartTrade(player1, player1)
+	tu.Assert(err != nil, t, "Shouldn't be able to trade with yourself")
+
+	trade, err := StartTrade(player1, player2)
+	tu.Assert(err == nil && trade != nil, t, "Failed to start trade", err)
+	tu.Assert(GetTrade(player1) == trade && GetTrade(player2) == trade, t, "Both sides should be in the trade")
+
+	_, err = StartTrade(player3, player2)
+	tu.Assert(err != nil, t, "Shouldn't be able to join a trade already in progress")
+
+	tu.Assert(trade.OfferItem(player1, shield) != nil, t, "Shouldn't be able to offer an item you don't carry")
+	tu.Assert(trade.OfferCash(player2, 80) != nil, t, "Shouldn't be able to offer more cash than you have")
+	tu.Assert(trade.OfferItem(player3, shield) != nil, t, "Outsiders shouldn't be able to change the trade")
+
+	tu.Assert(trade.OfferItem(player1, sword) == nil, t, "Failed to offer item")
+	tu.Assert(trade.OfferItem(player2, shield) == nil, t, "Failed to offer item")
+	tu.Assert(trade.OfferCash(player2, 30) == nil, t, "Failed to offer cash")
+
+	done, err := trade.Confirm(player1)
+	tu.Assert(!done && err == nil, t, "Trade shouldn't complete with one confirmation", err)
+	tu.Assert(trade.IsConfirmed(player1), t, "Confirmation should be recorded")
+
+	trade.OfferCash(player2, 20)
+	tu.Assert(!trade.IsConfirmed(player1) && !trade.IsConfirmed(player2), t, "Changing an offer should clear confirmations")
+
+	trade.Confirm(player2)
+	tu.Assert(player1.HasItem(sword) && player2.HasItem(shield), t, "Nothing should move before both sides confirm")
+
+	done, err = trade.Confirm(player1)
+	tu.Assert(done && err == nil, t, "Trade should complete once both sides confirm", err)
+	tu.Assert(player1.HasItem(shield) && !player1.HasItem(sword), t, "player1 should have received the shield")
+	tu.Assert(player2.HasItem(sword) && !player2.HasItem(shield), t, "player2 should have received the sword")
+	tu.Assert(player1.GetCash() == 120 && player2.GetCash() == 30, t, "Cash should have moved", player1.GetCash(), player2.GetCash())
+	tu.Assert(GetTrade(player1) == nil && GetTrade(player2) == nil, t, "Finished trade should be cleared")
+	tu.Assert(trade.OfferCash(player1, 10) != nil, t, "Shouldn't be able to change a finished trade")
+
+	// An item that leaves an inventory after being offered aborts the exchange
+	trade, _ = StartTrade(player1, player2)
+	trade.OfferItem(player1, shield)
+	tu.Assert(DropItem(player1, shield, room) == nil, t, "Failed to drop item")
+	tu.Assert(DropItem(player1, shield, room) != nil, t, "Shouldn't be able to drop an item twice")
+	trade.Confirm(player2)
+	done, err = trade.Confirm(player1)
+	tu.Assert(!done && err != nil, t, "Trade should fail when an offered item is gone")
+	tu.Assert(!player2.HasItem(shield) && room.HasItem(shield) && GetTrade(player1) == nil, t, "Failed trade shouldn't move anything")
+
+	// Moving cancels the trade
+	trade, _ = StartTrade(player1, player2)
+	MoveCharacterToRoom(player2, otherRoom)
+	tu.Assert(GetTrade(player1) == nil && GetTrade(player2) == nil, t, "Moving should cancel the trade")
+	MoveCharacterToRoom(player2, room)
+
+	// Fighting cancels the trade
+	trade, _ = StartTrade(player1, player2)
+	tu.Assert(StartFight(player3, player1) == nil, t, "Failed to start fight")
+	tu.Assert(GetTrade(player1) == nil, t, "Combat should cancel the trade")
+	_, err = StartTrade(player1, player2)
+	tu.Assert(err != nil, t, "Shouldn't be able to trade during a fight")
+	StopFight(player1)
+	StopFight(player3)
+
+	// Logging out cancels the trade
+	trade, _ = StartTrade(player1, player2)
+	Logout(player2)
+	tu.Assert(GetTrade(player1) == nil, t, "Logging out should cancel the trade")
+
+	_cleanup(t)
+}
+
+func Test_Shops(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room)
+
+	tu.Assert(Shopkeeper(room) == nil, t, "Room shouldn't have a shopkeeper yet")
+
+	template := CreateItemTemplate("potion")
+	template.SetIntProperty(database.PropertyValue, 10)
+
+	shop := database.NewShop()
+	shop.Stock = []database.StockEntry{{TemplateId: template.GetId(), Count: 2}}
+
+	shopkeeper := CreateNpc("shopkeeper", room)
+	shopkeeper.SetShop(shop)
+	tu.Assert(Shopkeeper(room) == shopkeeper, t, "Failed to find shopkeeper")
+
+	Restock(shopkeeper)
+	stock := ShopStock(shopkeeper)
+	tu.Assert(len(stock) == 2, t, "Shop should have been stocked", len(stock))
+	tu.Assert(Price(shopkeeper, stock[0]) == 15, t, "Price should include the markup", Price(shopkeeper, stock[0]))
+
+	_, err := Buy(player, shopkeeper, stock[0])
+	tu.Assert(err != nil, t, "Shouldn't be able to buy without cash")
+
+	TransferCash(nil, player, 20, "test")
+	price, err := Buy(player, shopkeeper, stock[0])
+	tu.Assert(err == nil && price == 15, t, "Failed to buy item", err, price)
+	tu.Assert(player.HasItem(stock[0]) && !shopkeeper.HasItem(stock[0]), t, "Bought item should change hands")
+	tu.Assert(player.GetCash() == 5, t, "Price should be paid", player.GetCash())
+
+	_, err = Buy(player, shopkeeper, stock[0])
+	tu.Assert(err != nil, t, "Shouldn't be able to buy an item twice")
+
+	offer, err := Offer(shopkeeper, stock[0])
+	tu.Assert(err == nil && offer == 5, t, "Offer should use the buyback rate", err, offer)
+
+	junk := CreateItem("junk")
+	player.AddItem(junk)
+	_, err = Sell(player, shopkeeper, junk)
+	tu.Assert(err != nil && player.HasItem(junk), t, "Shopkeeper shouldn't buy worthless items")
+
+	amount, err := Sell(player, shopkeeper, stock[0])
+	tu.Assert(err == nil && amount == 5, t, "Failed to sell item", err, amount)
+	tu.Assert(shopkeeper.HasItem(stock[0]) && player.GetCash() == 10, t, "Sold item should change hands")
+
+	Restock(shopkeeper)
+	tu.Assert(len(ShopStock(shopkeeper)) == 2, t, "Restock shouldn't exceed the stock level", len(ShopStock(shopkeeper)))
+
+	Buy(player, shopkeeper, stock[1])
+	Restock(shopkeeper)
+	tu.Assert(len(ShopStock(shopkeeper)) == 2, t, "Restock should replace sold items", len(ShopStock(shopkeeper)))
+
+	_cleanup(t)
+}
+
+func Test_Doors(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+
+	room1.ExitSouth = true
+	tu.Assert(room1.MigrateExits() && room1.HasExit(database.DirectionSouth), t, "Legacy exit should have been migrated")
+	tu.Assert(!room1.MigrateExits(), t, "Migrated room shouldn't need migrating again")
+	room2.SetExitEnabled(database.DirectionNorth, true)
+
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room1)
+
+	tu.Assert(OpenDoor(player, database.DirectionSouth) != nil, t, "Shouldn't be able to open a door that isn't there")
+
+	SetExit(room1, database.DirectionSouth, database.Exit{Door: database.DoorClosed, Key: "brass", Difficulty: 50})
+
+	exit, _ := room2.GetExit(database.DirectionNorth)
+	tu.Assert(exit.Door == database.DoorClosed && exit.Key == "brass", t, "Door should be mirrored in the adjacent room")
+
+	_, err := MoveCharacter(player, database.DirectionSouth)
+	tu.Assert(err != nil && player.GetRoomId() == room1.GetId(), t, "Shouldn't be able to walk through a closed door")
+
+	tu.Assert(LockDoor(player, database.DirectionSouth) != nil, t, "Shouldn't be able to lock a door without the key")
 
-	verifyEvents(eventChannel1)
-	// verifyEvents(eventChannel2)
+	key := CreateItem("key")
+	key.SetKind(database.ItemKindKey)
+	key.SetProperty(database.PropertyKey, "brass")
+	player.AddItem(key)
+
+	tu.Assert(LockDoor(player, database.DirectionSouth) == nil, t, "Failed to lock door")
+	tu.Assert(OpenDoor(player, database.DirectionSouth) != nil, t, "Shouldn't be able to open a locked door")
+
+	exit, _ = room2.GetExit(database.DirectionNorth)
+	tu.Assert(exit.IsLocked(), t, "Locking should lock both sides of the door")
+
+	restore := stubRolls(t, 30, 80)
+	defer restore()
+
+	tu.Assert(PickDoor(player, database.DirectionSouth) != nil, t, "Picking should fail with a low roll")
+	tu.Assert(PickDoor(player, database.DirectionSouth) == nil, t, "Picking should succeed with a high roll")
+	tu.Assert(UnlockDoor(player, database.DirectionSouth) != nil, t, "Picked door shouldn't be locked")
+
+	tu.Assert(OpenDoor(player, database.DirectionSouth) == nil, t, "Failed to open door")
+	exit, _ = room2.GetExit(database.DirectionNorth)
+	tu.Assert(exit.IsOpen(), t, "Opening should open both sides of the door")
+
+	newRoom, err := MoveCharacter(player, database.DirectionSouth)
+	tu.Assert(err == nil && newRoom == room2, t, "Should be able to walk through an open door", err)
+
+	tu.Assert(CloseDoor(player, database.DirectionNorth) == nil, t, "Failed to close door from the other side")
+	exit, _ = room1.GetExit(database.DirectionSouth)
+	tu.Assert(!exit.IsOpen(), t, "Closing should close both sides of the door")
+
+	_cleanup(t)
+}
+
+func Test_Portals(t *testing.T) {
+	zone1, _ := CreateZone("zone1")
+	zone2, _ := CreateZone("zone2")
+	room1, _ := CreateRoom(zone1, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone2, database.Coordinate{X: 5, Y: 5, Z: 0})
+
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room1)
+
+	LinkExit(room1, database.DirectionEast, room2)
+	LinkExit(room2, database.DirectionWest, room1)
+	tu.Assert(ExitDestination(room1, database.DirectionEast) == room2, t, "Linked exit should lead to the linked room")
+
+	newRoom, err := MoveCharacter(player, database.DirectionEast)
+	tu.Assert(err == nil && newRoom == room2 && player.GetRoomId() == room2.GetId(), t, "Failed to move across zones", err)
+
+	LinkNamedExit(room2, "Enter Portal", room1)
+	tu.Assert(len(room2.GetNamedExits()) == 1 && room2.GetNamedExits()[0] == "enter portal", t, "Named exit should have been added")
+
+	_, err = TakeNamedExit(player, "climb ladder")
+	tu.Assert(err != nil, t, "Shouldn't be able to take a named exit that doesn't exist")
+
+	newRoom, err = TakeNamedExit(player, "enter portal")
+	tu.Assert(err == nil && newRoom == room1, t, "Failed to take named exit", err)
+
+	SetExit(room1, database.DirectionEast, database.Exit{Destination: room2.GetId(), Door: database.DoorClosed})
+	exit, _ := room2.GetExit(database.DirectionWest)
+	tu.Assert(exit.Door == database.DoorClosed, t, "Door on a linked exit should be mirrored in the linked room")
+
+	DeleteRoom(room1)
+	tu.Assert(!room2.HasExit(database.DirectionWest) && len(room2.GetNamedExits()) == 0, t, "Deleting a room should remove the exits linked to it")
+
+	_cleanup(t)
+}
+
+func Test_Triggers(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+	room3, _ := CreateRoom(zone, database.Coordinate{X: 5, Y: 5, Z: 0})
+	room1.SetExitEnabled(database.DirectionSouth, true)
+	room2.SetExitEnabled(database.DirectionNorth, true)
+	SetExit(room1, database.DirectionSouth, database.Exit{Door: database.DoorClosed})
+
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room1)
+
+	room1.SetTriggers([]database.Trigger{
+		{
+			Type:       database.TriggerCommand,
+			Verb:       "pull lever",
+			Conditions: []database.Condition{{Type: database.ConditionNoFlag, Value: "pulled"}},
+			Actions: []database.TriggerAction{
+				{Type: database.ActionOpenDoor, Value: "south"},
+				{Type: database.ActionGiveItem, Value: "lever"},
+			},
+		},
+	})
+
+	tu.Assert(!RunCommandTriggers(player, "push lever"), t, "Unknown command shouldn't run any triggers")
+	tu.Assert(RunCommandTriggers(player, "Pull Lever"), t, "Command trigger should have run")
+
+	exit, _ := room1.GetExit(database.DirectionSouth)
+	tu.Assert(exit.IsOpen(), t, "Trigger should have opened the door")
+	tu.Assert(findItem(player, "lever") != nil, t, "Trigger should have given an item")
+
+	player.SetFlag("pulled")
+	RunCommandTriggers(player, "pull lever")
+	tu.Assert(len(player.GetItemIds()) == 1, t, "Trigger shouldn't run when its conditions aren't met")
+
+	room2.SetTriggers([]database.Trigger{
+		{
+			Type: database.TriggerEnter,
+			Actions: []database.TriggerAction{
+				{Type: database.ActionTakeItem, Value: "lever"},
+				{Type: database.ActionTeleport, Value: room3.GetId().Hex()},
+			},
+		},
+	})
+
+	// Rooms that teleport characters back and forth shouldn't loop forever
+	room3.SetTriggers([]database.Trigger{
+		{Type: database.TriggerEnter, Actions: []database.TriggerAction{{Type: database.ActionTeleport, Value: room2.GetId().Hex()}}},
+	})
+
+	npc := CreateNpc("npc", room1)
+	npc.AddItem(CreateItem("lever"))
+	MoveCharacter(npc, database.DirectionSouth)
+	tu.Assert(findItem(npc, "lever") != nil && npc.GetRoomId() == room2.GetId(), t, "Enter triggers shouldn't run for NPCs")
+
+	MoveCharacter(player, database.DirectionSouth)
+	tu.Assert(findItem(player, "lever") == nil, t, "Enter trigger should have taken the item")
+	tu.Assert(player.GetRoomId() == room2.GetId() || player.GetRoomId() == room3.GetId(), t, "Teleports should have stopped")
+
+	room3.SetTriggers([]database.Trigger{
+		{Type: database.TriggerTimer, Interval: 1, Actions: []database.TriggerAction{
+			{Type: database.ActionTeleport, Value: room1.GetId().Hex()},
+		}},
+	})
+
+	MoveCharacterToRoom(player, room3)
+	RunTriggers(room3, nil, database.TriggerTimer)
+	tu.Assert(player.GetRoomId() == room3.GetId(), t, "Timer triggers shouldn't run actions that need a character")
+
+	_cleanup(t)
+}
+
+func Test_Scripts(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+	room1.SetExitEnabled(database.DirectionSouth, true)
+	room2.SetExitEnabled(database.DirectionNorth, true)
+
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room1)
+
+	tu.Assert(CheckScript("function on_enter(") != nil, t, "Syntax errors should be reported")
+
+	script := CreateScript("test")
+	script.SetSource(`
+function on_enter(who)
+	set_flag(who, "visited " .. name(here))
+end
+
+function on_command(who, command)
+	if command == "pray" then
+		give_cash(who, 10)
+		return true
+	end
+	if command == "hang" then
+		while true do end
+	end
+	if command == "hog" then
+		return string.rep("x", 1e9) ~= nil
+	end
+	if command == "pad" then
+		return string.format("%999999999d", 1) ~= nil
+	end
+	if command == "small" then
+		return string.rep("ab", 3, "") == "ababab" and string.format("%5.2f", 1) == " 1.00"
+	end
+	if command == "escape" then
+		return os ~= nil or io ~= nil or dofile ~= nil or require ~= nil
+	end
+	return false
+end`)
+	tu.Assert(CheckScript(script.GetSource()) == nil, t, "Script should compile")
+	tu.Assert(GetScript(script.GetId()) == script && len(GetScripts()) == 1, t, "Script should be stored")
+
+	room2.SetScriptId(script.GetId())
+	room2.SetTitle("Chapel")
+
+	MoveCharacter(player, database.DirectionSouth)
+	tu.Assert(player.HasFlag("visited Chapel"), t, "on_enter should have run")
+
+	tu.Assert(RunCommandScripts(player, "pray") && player.GetCash() == 10, t, "on_command should have handled the command")
+	tu.Assert(!RunCommandScripts(player, "dance"), t, "Unhandled commands should fall through")
+	tu.Assert(!RunCommandScripts(player, "escape"), t, "Scripts shouldn't be able to reach the host")
+
+	tu.Assert(RunCommandScripts(player, "small"), t, "Small strings should still be allowed")
+	start := time.Now()
+	tu.Assert(!RunCommandScripts(player, "hog"), t, "Huge strings should be refused")
+	tu.Assert(!RunCommandScripts(player, "pad"), t, "Huge format widths should be refused")
+	tu.Assert(time.Since(start) < 100*time.Millisecond, t, "Huge strings should be refused before they are built", time.Since(start))
+
+	start = time.Now()
+	tu.Assert(!RunCommandScripts(player, "hang"), t, "Runaway scripts should be stopped")
+	tu.Assert(time.Since(start) < time.Second, t, "Runaway scripts should be stopped quickly", time.Since(start))
+
+	runSource := func(source string) error {
+		state := newScriptState()
+		defer state.Close()
+
+		return runLimited(state, func() error { return state.DoString(source) })
+	}
+
+	err := runSource(`local s = "x" for i = 1, 40 do s = s .. s end`)
+	tu.Assert(err != nil && strings.Contains(err.Error(), "memory"), t, "Doubling a string should run out of memory", err)
+
+	err = runSource(`local t = {} for i = 1, 2000 do t[i] = string.rep("x", 1000) end return table.concat(t)`)
+	tu.Assert(err != nil && strings.Contains(err.Error(), "table.concat"), t, "Concatenating a large table should be refused", err)
+
+	err = runSource(`local t = {} for i = 1, 100 do t[i] = i end return table.concat(t, ",")`)
+	tu.Assert(err == nil, t, "Concatenating a small table should be allowed", err)
+
+	npcScript := CreateScript("npc")
+	npcScript.SetSource(`function on_talk(who) create_item("gift", who) return true end`)
+
+	template := CreateNpcTemplate("priest")
+	template.SetScriptId(npcScript.GetId())
+	npc := CreateNpcFromTemplate(template, room2)
+
+	tu.Assert(TalkScript(player, npc) && findItem(player, "gift") != nil, t, "on_talk should have run for the template's NPCs")
+
+	DeleteScript(script)
+	tu.Assert(GetScript(script.GetId()) == nil && room2.GetScriptId() == "", t, "Deleted script should be detached")
+	DeleteScript(npcScript)
+
+	_cleanup(t)
+}
+
+func Test_Quests(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+
+	user := CreateUser("user", "password")
+	player := CreatePlayer("player", user, room1)
+
+	giver := CreateNpcFromTemplate(CreateNpcTemplate("giver"), room1)
+	ratTemplate := CreateNpcTemplate("rat")
+	apple := CreateItemTemplate("apple")
+	sword := CreateItemTemplate("sword")
+
+	quest := CreateQuest("Pest Control")
+	quest.SetOrdered(true)
+	quest.SetObjectives([]database.Objective{
+		{Type: database.ObjectiveTalk, TargetId: giver.GetTemplateId(), Count: 1},
+		{Type: database.ObjectiveKill, TargetId: ratTemplate.GetId(), Count: 2},
+		{Type: database.ObjectiveCollect, TargetId: apple.GetId(), Count: 1},
+		{Type: database.ObjectiveVisit, TargetId: room2.GetId(), Count: 1},
+	})
+	quest.SetReward(database.Reward{Cash: 50, Experience: 100, Items: []bson.ObjectId{sword.GetId()}})
+
+	player.AddItem(CreateItemFromTemplate(apple))
+
+	tu.Assert(StartQuest(player, quest) == nil, t, "Failed to start quest")
+	tu.Assert(StartQuest(player, quest) != nil, t, "Shouldn't be able to start a quest twice")
+
+	counts := func() []int {
+		progress, _ := player.GetQuestProgress(quest.GetId())
+		return progress.Counts
+	}
+
+	kill := CombatEvent{Attacker: player, Defender: CreateNpcFromTemplate(ratTemplate, room1), Killed: true}
+
+	UpdateQuests(kill)
+	tu.Assert(counts()[1] == 0, t, "Ordered objectives shouldn't count before the earlier ones are done")
+
+	UpdateQuests(TalkEvent{Character: player, Npc: giver})
+	tu.Assert(counts()[0] == 1, t, "Talking to the giver should have counted")
+
+	UpdateQuests(kill)
+	UpdateQuests(CombatEvent{Attacker: player, Defender: kill.Defender})
+	tu.Assert(counts()[1] == 1, t, "Only kills should count")
+
+	UpdateQuests(kill)
+	tu.Assert(counts()[1] == 2 && counts()[2] == 1, t, "Carried items should count once their objective is reached")
+	tu.Assert(!IsQuestComplete(player, quest), t, "Quest shouldn't be complete yet")
+
+	UpdateQuests(EnterEvent{Character: player, Room: room2, SourceRoom: room1})
+	tu.Assert(IsQuestComplete(player, quest), t, "Quest should be complete")
+	tu.Assert(player.GetCash() == 50 && player.GetExperience() == 100, t, "Cash and experience should have been paid out")
+	tu.Assert(countCarried(player, sword.GetId()) == 1, t, "Reward item should have been given")
+
+	UpdateQuests(EnterEvent{Character: player, Room: room2, SourceRoom: room1})
+	tu.Assert(player.GetCash() == 50, t, "Rewards should only be paid once")
+	tu.Assert(StartQuest(player, quest) != nil, t, "Shouldn't be able to restart a completed quest")
+
+	tu.Assert(ConditionsMet(player, []database.Condition{{Type: database.ConditionQuest, Value: "pest control"}}), t,
+		"Quest complete condition should be met")
+
+	DeleteQuest(quest)
+	tu.Assert(GetQuest(quest.GetId()) == nil, t, "Failed to delete quest")
+
+	_cleanup(t)
+}
+
+func Test_Skills(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	warrior := CreatePlayer("warrior", user, room)
+	rogue := CreatePlayer("rogue", user, room)
+	SetClass(warrior, database.FindClass("warrior"))
+	SetClass(rogue, database.FindClass("Rogue"))
+
+	tu.Assert(warrior.GetClass() == "Warrior" && warrior.GetHealth() == 120 && warrior.GetStrength() == 14, t, "Class stats weren't applied")
+	tu.Assert(warrior.GetSkill("bash") == database.StartingProficiency, t, "Class skills weren't learned")
+
+	skills := KnownSkills(rogue)
+	tu.Assert(len(skills) == 3 && skills[0].Name == "backstab" && skills[2].Name == "kick", t, "Known skills should be sorted by name")
+
+	tu.Assert(UseSkill(warrior, "backstab", rogue) != nil, t, "Shouldn't be able to use a skill that hasn't been learned")
+	tu.Assert(UseSkill(warrior, "enhanced damage", rogue) != nil, t, "Shouldn't be able to use a passive skill")
+	tu.Assert(UseSkill(warrior, "kick", nil) != nil, t, "Skills need a target outside of combat")
+
+	// Proficiency roll, damage dice and then a practice roll that improves the skill
+	restore := stubRolls(t, 10, 4, 90)
+	defer restore()
+
+	tu.Assert(UseSkill(warrior, "kick", rogue) == nil, t, "Failed to kick")
+	StopFight(warrior)
+	StopFight(rogue)
+
+	tu.Assert(rogue.GetHitPoints() == 84, t, "Kick should have done its damage plus the strength bonus", rogue.GetHitPoints())
+	tu.Assert(warrior.GetMovement() == 95, t, "Kick should have used movement", warrior.GetMovement())
+	tu.Assert(warrior.GetSkill("kick") == database.StartingProficiency+1, t, "Kick should have improved with use")
+	tu.Assert(Cooldown(warrior, "kick") > 0 && UseSkill(warrior, "kick", rogue) != nil, t, "Kick should be cooling down")
+
+	StartFight(warrior, rogue)
+	tu.Assert(UseSkill(rogue, "backstab", warrior) != nil, t, "Backstab should only work on someone who isn't fighting")
+	StopFight(warrior)
+	StopFight(rogue)
+
+	// Rolls between the hit chance with and without dodging are dodged
+	restore = stubRolls(t, 64, 90)
+	event := resolveAttack(warrior, rogue)
+	tu.Assert(event.Outcome == CombatMiss && event.Dodged, t, "Attack should have been dodged")
+	tu.Assert(rogue.GetSkill("dodge") == database.StartingProficiency+1, t, "Dodge should have improved with use")
+
+	restore = stubRolls(t, 50, 5, 10)
+	event = resolveAttack(warrior, rogue)
+	tu.Assert(event.Damage == 8, t, "Enhanced damage should add to damage", event.Damage)
+	tu.Assert(warrior.GetSkill("enhanced damage") == database.StartingProficiency, t, "Enhanced damage shouldn't have improved")
+
+	_cleanup(t)
+}
+
+func Test_Statuses(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+	char := CreatePlayer("char", user, room)
+
+	tu.Assert(!ApplyStatus(char, "not a status"), t, "Shouldn't be able to apply an unknown status")
+
+	for i := 0; i < 4; i++ {
+		tu.Assert(ApplyStatus(char, "poison"), t, "Poison should always stack or refresh")
+	}
+
+	statuses := char.GetStatuses()
+	tu.Assert(len(statuses) == 1 && statuses[0].Stacks == 3, t, "Poison should stack up to its maximum", statuses)
+
+	tu.Assert(ApplyStatus(char, "stone skin"), t, "Failed to apply stone skin")
+	tu.Assert(!ApplyStatus(char, "stone skin"), t, "Stone skin shouldn't stack")
+	tu.Assert(ArmorClass(char) == 3, t, "Stone skin should add to armor", ArmorClass(char))
+
+	ApplyStatus(char, "weakness")
+	tu.Assert(Strength(char) == char.GetStrength()-4 && Dexterity(char) == char.GetDexterity()-2, t, "Weakness should lower stats")
+
+	hitPoints := char.GetHitPoints()
+	tickStatuses(time.Now().Add(3 * time.Second))
+	tu.Assert(char.GetHitPoints() == hitPoints-9, t, "Each stack of poison should do damage", char.GetHitPoints())
+
+	tickStatuses(time.Now().Add(31 * time.Second))
+	statuses = char.GetStatuses()
+	tu.Assert(len(statuses) == 1 && statuses[0].Name == "stone skin", t, "Expired statuses should have been removed", statuses)
+
+	_cleanup(t)
+}
+
+func Test_Spells(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	mage := CreatePlayer("mage", user, room)
+	cleric := CreatePlayer("cleric", user, room)
+	SetClass(mage, database.FindClass("Mage"))
+	SetClass(cleric, database.FindClass("Cleric"))
+
+	tu.Assert(len(KnownSpells(mage)) == 3 && mage.GetMana() == 150, t, "Class spells and mana weren't applied")
+	tu.Assert(Cast(cleric, "magic missile", mage) != nil, t, "Shouldn't be able to cast a spell of another class")
+	tu.Assert(Cast(mage, "magic missile", nil) != nil, t, "Offensive spells need a target outside of combat")
+	tu.Assert(Cast(mage, "stone skin", cleric) != nil, t, "Self only spells can't be cast on others")
+
+	restore := stubRolls(t, 3, 4)
+	defer restore()
+
+	tu.Assert(Cast(mage, "magic missile", cleric) == nil, t, "Failed to cast magic missile")
+	StopFight(mage)
+	StopFight(cleric)
+
+	tu.Assert(cleric.GetHitPoints() == 93, t, "Magic missile should have done its damage", cleric.GetHitPoints())
+	tu.Assert(mage.GetMana() == 140, t, "Casting should have used mana", mage.GetMana())
+
+	tu.Assert(Cast(cleric, "giant strength", mage) == nil, t, "Failed to cast giant strength")
+	tu.Assert(Strength(mage) == 12, t, "Giant strength should have been applied to the target", Strength(mage))
+
+	restore = stubRolls(t, 5, 2)
+	tu.Assert(Cast(cleric, "heal", nil) == nil, t, "Failed to cast heal")
+	tu.Assert(cleric.GetHitPoints() == 100, t, "Defensive spells should be cast on the caster by default", cleric.GetHitPoints())
+
+	mage.SetMana(5)
+	tu.Assert(Cast(mage, "stone skin", nil) != nil, t, "Shouldn't be able to cast without enough mana")
+
+	_cleanup(t)
+}
+
+func Test_Regeneration(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+	char := CreatePlayer("char", user, room)
+	other := CreatePlayer("other", user, room)
+
+	var timer regenerationTimer
+	now := time.Now()
+	tu.Assert(timer.due(now), t, "First tick should regenerate")
+	tu.Assert(!timer.due(now.Add(time.Second)), t, "Shouldn't regenerate again within the interval")
+	tu.Assert(timer.due(now.Add(regenerationInterval)), t, "Should regenerate once the interval has passed")
+
+	char.SetHitPoints(10)
+	char.SetMana(0)
+	tu.Assert(regenerationRate(char) == 2, t, "Standing characters should regenerate slowly", regenerationRate(char))
+
+	tu.Assert(Rest(char) == nil && char.GetPosition() == database.PositionResting, t, "Failed to rest")
+	tu.Assert(Rest(char) != nil, t, "Shouldn't be able to rest while already resting")
+	tu.Assert(regenerationRate(char) == 5, t, "Resting should speed up regeneration", regenerationRate(char))
+
+	_, err := MoveCharacter(char, database.DirectionNorth)
+	tu.Assert(err != nil, t, "Shouldn't be able to move while resting")
+
+	tu.Assert(Sleep(char) == nil, t, "Failed to sleep")
+	room.SetProperty(database.RoomPropertyRegeneration, "200")
+	tu.Assert(regenerationRate(char) == 20, t, "Room should have doubled regeneration", regenerationRate(char))
+
+	regenerateCharacter(char)
+	tu.Assert(char.GetHitPoints() == 30 && char.GetMana() == 20, t, "Failed to regenerate", char.GetHitPoints(), char.GetMana())
+
+	StartFight(other, char)
+	tu.Assert(char.GetPosition() == database.PositionStanding, t, "Being attacked should wake the character up")
+	tu.Assert(regenerationRate(char) == 0, t, "Characters shouldn't regenerate while fighting")
+	tu.Assert(Rest(char) != nil, t, "Shouldn't be able to rest while fighting")
+	StopFight(other)
+
+	_cleanup(t)
+}
+
+func Test_Party(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+	room1.SetExitEnabled(database.DirectionSouth, true)
+	room2.SetExitEnabled(database.DirectionNorth, true)
+	user := CreateUser("user", "password")
+
+	leader := CreatePlayer("leader", user, room1)
+	member := CreatePlayer("member", user, room1)
+	other := CreatePlayer("other", user, room1)
+	leader.SetOnline(true)
+	member.SetOnline(true)
+	other.SetOnline(true)
+
+	tu.Assert(AcceptPartyInvite(member) != nil, t, "Shouldn't be able to accept without an invitation")
+	tu.Assert(InviteToParty(leader, member) == nil, t, "Failed to invite to party")
+	tu.Assert(AcceptPartyInvite(member) == nil, t, "Failed to accept party invitation")
+
+	members := PartyMembers(member)
+	tu.Assert(len(members) == 2 && members[0] == leader && PartyLeader(member) == leader, t, "Party should have been formed with the inviter as leader")
+	tu.Assert(InviteToParty(member, other) != nil, t, "Only the leader should be able to invite")
+
+	tu.Assert(FollowLeader(member) == nil, t, "Failed to follow the leader")
+	MoveCharacter(leader, database.DirectionSouth)
+	tu.Assert(member.GetRoomId() == room2.GetId(), t, "Member should have followed the leader")
+
+	npc := CreateNpc("rat", room2)
+	Kill(npc, leader)
+	share := npc.GetHealth() / 2
+	tu.Assert(leader.GetExperience() == share && member.GetExperience() == share, t, "Experience should have been shared", leader.GetExperience(), member.GetExperience())
+
+	tu.Assert(SetLootRule(member, LootSplitCash) != nil, t, "Only the leader should be able to change the loot rule")
+	tu.Assert(SetLootRule(leader, LootSplitCash) == nil, t, "Failed to change the loot rule")
+
+	coins := createCoins(11)
+	room2.AddItem(coins)
+	tu.Assert(PickupItem(leader, coins, room2) == nil, t, "Failed to pick up coins")
+	tu.Assert(leader.GetCash() == 6 && member.GetCash() == 5, t, "Coins should have been split", leader.GetCash(), member.GetCash())
+
+	MoveCharacterToRoom(other, room2)
+	InviteToParty(leader, other)
+	AcceptPartyInvite(other)
+	tu.Assert(FollowLeader(other) == nil, t, "Failed to follow the leader")
+	tu.Assert(KickFromParty(leader, other) == nil && Following(other) == nil, t, "Kicked members should stop following the leader")
+	MoveCharacter(leader, database.DirectionNorth)
+	tu.Assert(member.GetRoomId() == room1.GetId() && other.GetRoomId() == room2.GetId(), t, "Kicked members shouldn't move with the leader")
+
+	InviteToParty(leader, other)
+	AcceptPartyInvite(other)
+	tu.Assert(KickFromParty(member, other) != nil, t, "Only the leader should be able to kick")
+	tu.Assert(LeaveParty(leader) == nil && PartyLeader(other) == member, t, "Leadership should have passed on")
+	tu.Assert(Following(member) == nil, t, "The new leader should stop following the old one")
+
+	Logout(other)
+	tu.Assert(PartyMembers(member) == nil, t, "Party should have been disbanded on logout")
+
+	_cleanup(t)
+}
+
+func Test_Follow(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room1, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	room2, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 1, Z: 0})
+	room1.SetExitEnabled(database.DirectionSouth, true)
+	room2.SetExitEnabled(database.DirectionNorth, true)
+	user := CreateUser("user", "password")
+
+	leader := CreatePlayer("leader", user, room1)
+	follower := CreatePlayer("follower", user, room1)
+	npc := CreateNpc("dog", room1)
+
+	tu.Assert(Follow(leader, leader) != nil, t, "Shouldn't be able to follow yourself")
+	tu.Assert(Follow(follower, leader) == nil, t, "Failed to follow")
+	tu.Assert(Follow(npc, follower) == nil, t, "NPCs should be able to follow players")
+	tu.Assert(Follow(leader, npc) != nil, t, "Following should never form a loop")
+	tu.Assert(Following(npc) == follower && len(Followers(leader)) == 1, t, "Follow relationships weren't recorded")
+
+	MoveCharacter(leader, database.DirectionSouth)
+	tu.Assert(follower.GetRoomId() == room2.GetId() && npc.GetRoomId() == room2.GetId(), t, "Followers should have moved with the leader")
+
+	tu.Assert(StopFollowing(follower) == nil && Following(follower) == nil, t, "Failed to stop following")
+	MoveCharacter(leader, database.DirectionNorth)
+	tu.Assert(follower.GetRoomId() == room2.GetId(), t, "Character shouldn't follow after they've stopped")
+
+	DeleteCharacter(follower)
+	tu.Assert(Following(npc) == nil, t, "Deleting a character should stop others following them")
+
+	_cleanup(t)
+}
+
+func Test_Channels(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	adminUser := CreateUser("admin", "password")
+	playerUser := CreateUser("player", "password")
+	adminUser.SetRole(database.RoleAdmin)
+
+	admin := CreatePlayer("admin", adminUser, room)
+	player := CreatePlayer("player", playerUser, room)
+	npc := CreateNpc("npc", room)
+
+	ooc := database.FindChannel("ooc")
+	adminChannel := database.FindChannel("admin")
+
+	tu.Assert(OnChannel(player, ooc) && OnChannel(admin, adminChannel), t, "Characters should start on the default channels")
+	tu.Assert(!OnChannel(player, adminChannel) && JoinChannel(player, adminChannel) != nil, t, "Players shouldn't be allowed on the admin channel")
+	tu.Assert(!OnChannel(npc, ooc), t, "NPCs shouldn't be on channels")
+
+	tu.Assert(ChannelSay(player, ooc, "hello") == nil, t, "Failed to talk on a channel")
+	event := ChannelEvent{Channel: ooc, From: player, Message: "hello"}
+	tu.Assert(event.IsFor(admin) && !event.IsFor(npc), t, "Channel event went to the wrong characters")
+
+	history, err := ChannelHistory(admin, ooc)
+	tu.Assert(err == nil && len(history) == 1 && history[0].Message == "hello", t, "Message should be in the channel history")
+
+	tu.Assert(LeaveChannel(player, ooc) == nil && !OnChannel(player, ooc), t, "Failed to leave channel")
+	tu.Assert(ChannelSay(player, ooc, "hello") != nil, t, "Shouldn't be able to talk on a channel after leaving it")
+	tu.Assert(JoinChannel(player, ooc) == nil && OnChannel(player, ooc), t, "Failed to join channel")
+
+	tu.Assert(MuteOnChannel(player, admin, ooc, time.Minute) != nil, t, "Players shouldn't be able to moderate")
+	tu.Assert(MuteOnChannel(admin, player, ooc, time.Minute) == nil, t, "Failed to mute")
+	tu.Assert(ChannelSay(player, ooc, "hello") != nil, t, "Muted players shouldn't be able to talk")
+	tu.Assert(MuteOnChannel(admin, player, ooc, 0) == nil && ChannelSay(player, ooc, "hello") == nil, t, "Failed to unmute")
+
+	tu.Assert(BanFromChannel(admin, player, ooc, true) == nil && !OnChannel(player, ooc), t, "Failed to ban")
+	tu.Assert(JoinChannel(player, ooc) != nil, t, "Banned players shouldn't be able to rejoin")
+	tu.Assert(BanFromChannel(admin, player, ooc, false) == nil && JoinChannel(player, ooc) == nil, t, "Failed to unban")
+
+	_cleanup(t)
+}
+
+func Test_Ignore(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	user := CreateUser("user", "password")
+
+	player := CreatePlayer("player", user, room)
+	pest := CreatePlayer("pest", user, room)
+	friend := CreatePlayer("friend", user, room)
+
+	player.SetIgnoring(pest, true)
+	tu.Assert(player.IsIgnoring(pest) && !player.IsIgnoring(friend), t, "Failed to ignore")
+	tu.Assert(Tell(pest, player, "hello") != nil, t, "Ignored characters shouldn't be able to send tells")
+	tu.Assert(!(SayEvent{Character: pest, Message: "hello"}).IsFor(player), t, "Says from ignored characters should be hidden")
+	tu.Assert(!(EmoteEvent{Character: pest, Emote: "waves"}).IsFor(player), t, "Emotes from ignored characters should be hidden")
+	tu.Assert((SayEvent{Character: friend, Message: "hello"}).IsFor(player), t, "Says from everyone else should still be seen")
+
+	ooc := database.FindChannel("ooc")
+	tu.Assert(!(ChannelEvent{Channel: ooc, From: pest, Message: "hello"}).IsFor(player), t, "Channel messages from ignored characters should be hidden")
+
+	player.SetIgnoring(pest, false)
+	tu.Assert(Tell(pest, player, "hello") == nil, t, "Unignored characters should be able to send tells")
+
+	player.SetTellPreference(database.TellsFromFriends)
+	player.SetFriend(friend, true)
+	tu.Assert(Tell(pest, player, "hello") != nil && Tell(friend, player, "hello") == nil, t, "Only friends should be able to send tells")
+
+	player.SetTellPreference(database.TellsFromNobody)
+	tu.Assert(!(TellEvent{From: friend, To: player, Message: "hello"}).IsFor(player), t, "Nobody should be able to send tells")
+
+	_cleanup(t)
+}
+
+func Test_Mail(t *testing.T) {
+	zone, _ := CreateZone("zone")
+	room, _ := CreateRoom(zone, database.Coordinate{X: 0, Y: 0, Z: 0})
+	postOffice, _ := CreateRoom(zone, database.Coordinate{X: 1, Y: 0, Z: 0})
+	postOffice.SetProperty(database.RoomPropertyPostOffice, "true")
+	user := CreateUser("user", "password")
+
+	sender := CreatePlayer("sender", user, room)
+	receiver := CreatePlayer("receiver", user, room)
+
+	_, err := SendMail(sender, receiver, "", "body", 0, nil)
+	tu.Assert(err != nil, t, "Mail without a subject shouldn't be sent")
+
+	mail, err := SendMail(sender, receiver, "Hello", "line one\nline two", 0, nil)
+	tu.Assert(err == nil, t, "Failed to send mail", err)
+	tu.Assert(len(Mailbox(receiver)) == 1 && Mailbox(receiver)[0] == mail, t, "Mail should be in the receiver's mailbox")
+	tu.Assert(len(Mailbox(sender)) == 0, t, "Mail shouldn't be in the sender's mailbox")
+	tu.Assert(UnreadMail(receiver) == 1, t, "Mail should be unread")
+
+	mail.SetRead(true)
+	tu.Assert(UnreadMail(receiver) == 0, t, "Mail should have been read")
+
+	tu.Assert(DeleteMail(sender, mail) != nil, t, "Only the receiver should be able to delete mail")
+	tu.Assert(DeleteMail(receiver, mail) == nil && len(Mailbox(receiver)) == 0, t, "Failed to delete mail")
+
+	item := CreateItem("gift")
+	sender.AddItem(item)
+	sender.AddCash(50)
+
+	_, err = SendMail(sender, receiver, "Gift", "", 100, []*database.Item{item})
+	tu.Assert(err != nil && sender.HasItem(item), t, "Mail with more cash than the sender has shouldn't be sent")
+
+	missing := CreateItem("missing")
+	_, err = SendMail(sender, receiver, "Gift", "", 30, []*database.Item{item, missing})
+	tu.Assert(err != nil && sender.HasItem(item) && sender.GetCash() == 50, t, "Nothing should be taken if an attachment can't be")
+
+	mail, err = SendMail(sender, receiver, "Gift", "", 30, []*database.Item{item, item})
+	tu.Assert(err == nil, t, "Failed to send mail with attachments", err)
+	tu.Assert(!sender.HasItem(item) && sender.GetCash() == 20, t, "Attachments should be taken from the sender")
+	tu.Assert(len(mail.GetItemIds()) == 1, t, "The same item shouldn't be attached twice")
+	tu.Assert(mail.HasAttachments(), t, "Mail should have attachments")
+	tu.Assert(DeleteMail(receiver, mail) != nil, t, "Mail shouldn't be deleted while it has attachments")
+
+	tu.Assert(CollectAttachments(receiver, mail) != nil, t, "Attachments should only be collected at a post office")
+	receiver.SetRoomId(postOffice.GetId())
+	tu.Assert(CollectAttachments(receiver, mail) == nil, t, "Failed to collect attachments")
+	tu.Assert(receiver.HasItem(item) && receiver.GetCash() == 30, t, "Attachments should be given to the receiver")
+	tu.Assert(!mail.HasAttachments() && DeleteMail(receiver, mail) == nil, t, "Mail should be deletable once collected")
+
+	receiver.SetIgnoring(sender, true)
+	_, err = SendMail(sender, receiver, "Hello", "", 0, nil)
+	tu.Assert(err != nil, t, "Ignored characters shouldn't be able to send mail")
+
+	receiver.SetIgnoring(sender, false)
+	SendMail(sender, receiver, "Hello", "", 0, nil)
+	DeleteCharacter(receiver)
+	tu.Assert(len(_mail) == 0, t, "Deleting a character should delete their mail")
+
+	_cleanup(t)
 }
 
 // vim: nocindent
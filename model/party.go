@@ -0,0 +1,399 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"strings"
+	"sync"
+)
+
+// LootRule decides what happens to the loot that party members pick up
+type LootRule string
+
+const (
+	LootFreeForAll LootRule = "free for all" // Whoever picks something up keeps it
+	LootSplitCash  LootRule = "split cash"   // Coins are shared between the members in the room
+)
+
+var LootRules = []LootRule{LootFreeForAll, LootSplitCash}
+
+// Party is a group of players who adventure together. Members can follow the
+// leader, talk privately, and share the experience of their kills. Parties
+// only exist while their members are online.
+type Party struct {
+	leader  *database.Character
+	members []*database.Character // In the order they joined, including the leader
+	loot    LootRule
+}
+
+// partiesMutex guards every party, as well as the maps below
+var partiesMutex sync.RWMutex
+var parties = map[*database.Character]*Party{}
+var partyInvites = map[*database.Character]*database.Character{} // Invitee to the player that invited them
+
+// PartyMembers returns everyone in the character's party with the leader
+// first, or nil if they aren't in one
+func PartyMembers(character *database.Character) []*database.Character {
+	partiesMutex.RLock()
+	defer partiesMutex.RUnlock()
+
+	party := parties[character]
+
+	if party == nil {
+		return nil
+	}
+
+	members := []*database.Character{party.leader}
+	for _, member := range party.members {
+		if member != party.leader {
+			members = append(members, member)
+		}
+	}
+
+	return members
+}
+
+// PartyLeader returns the leader of the character's party, or nil if they
+// aren't in one
+func PartyLeader(character *database.Character) *database.Character {
+	partiesMutex.RLock()
+	defer partiesMutex.RUnlock()
+
+	if party := parties[character]; party != nil {
+		return party.leader
+	}
+
+	return nil
+}
+
+// PartyLootRule returns the loot rule of the character's party
+func PartyLootRule(character *database.Character) LootRule {
+	partiesMutex.RLock()
+	defer partiesMutex.RUnlock()
+
+	if party := parties[character]; party != nil {
+		return party.loot
+	}
+
+	return LootFreeForAll
+}
+
+// InviteToParty invites another player to join the inviter's party. A new
+// party is formed when they accept if the inviter isn't already in one.
+func InviteToParty(inviter *database.Character, invitee *database.Character) error {
+	if inviter == invitee {
+		return errors.New("You can't invite yourself")
+	}
+
+	if !invitee.IsPlayer() || !invitee.IsOnline() {
+		return fmt.Errorf("%s can't join a party", invitee.GetName())
+	}
+
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	if party := parties[inviter]; party != nil && party.leader != inviter {
+		return errors.New("Only the party leader can invite people")
+	}
+
+	if parties[invitee] != nil {
+		return fmt.Errorf("%s is already in a party", invitee.GetName())
+	}
+
+	partyInvites[invitee] = inviter
+
+	queueEvent(MessageEvent{Character: invitee,
+		Message: fmt.Sprintf("%s invites you to join their party. Type 'group accept' to join.", inviter.GetName())})
+
+	return nil
+}
+
+// AcceptPartyInvite adds the character to the party of whoever last invited
+// them
+func AcceptPartyInvite(character *database.Character) error {
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	inviter := partyInvites[character]
+	delete(partyInvites, character)
+
+	if inviter == nil || !inviter.IsOnline() {
+		return errors.New("You haven't been invited to a party")
+	}
+
+	if parties[character] != nil {
+		return errors.New("You are already in a party")
+	}
+
+	party := parties[inviter]
+
+	if party == nil {
+		party = &Party{leader: inviter, members: []*database.Character{inviter}, loot: LootFreeForAll}
+		parties[inviter] = party
+	} else if party.leader != inviter {
+		return errors.New("That invitation is no longer valid")
+	}
+
+	party.members = append(party.members, character)
+	parties[character] = party
+
+	party.notify(fmt.Sprintf("%s has joined the party", character.GetName()))
+	return nil
+}
+
+// LeaveParty takes the character out of their party. If they were leading it
+// the longest serving member takes over, and a party with only one member
+// left is disbanded.
+func LeaveParty(character *database.Character) error {
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	party := parties[character]
+
+	if party == nil {
+		return errors.New("You aren't in a party")
+	}
+
+	party.remove(character, fmt.Sprintf("%s has left the party", character.GetName()))
+	return nil
+}
+
+// KickFromParty lets the party leader remove another member
+func KickFromParty(leader *database.Character, member *database.Character) error {
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	party := parties[leader]
+
+	if party == nil {
+		return errors.New("You aren't in a party")
+	}
+
+	if party.leader != leader {
+		return errors.New("Only the party leader can do that")
+	}
+
+	if member == leader {
+		return errors.New("You can't kick yourself, leave the party instead")
+	}
+
+	if parties[member] != party {
+		return fmt.Errorf("%s isn't in your party", member.GetName())
+	}
+
+	queueEvent(MessageEvent{Character: member, Message: "You have been kicked out of the party"})
+	party.remove(member, fmt.Sprintf("%s has been kicked out of the party", member.GetName()))
+	return nil
+}
+
+// SetLootRule lets the party leader change how loot is shared
+func SetLootRule(leader *database.Character, rule LootRule) error {
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	party := parties[leader]
+
+	if party == nil {
+		return errors.New("You aren't in a party")
+	}
+
+	if party.leader != leader {
+		return errors.New("Only the party leader can do that")
+	}
+
+	party.loot = rule
+	party.notify(fmt.Sprintf("Loot is now %s", rule))
+	return nil
+}
+
+// PartySay sends a message to every member of the character's party
+func PartySay(character *database.Character, message string) error {
+	partiesMutex.RLock()
+	defer partiesMutex.RUnlock()
+
+	party := parties[character]
+
+	if party == nil {
+		return errors.New("You aren't in a party")
+	}
+
+	queueEvent(PartyEvent{Members: party.copyMembers(), From: character, Message: message})
+	return nil
+}
+
+// FollowLeader makes the character follow their party leader whenever they
+// move
+func FollowLeader(character *database.Character) error {
+	leader := PartyLeader(character)
+
+	if leader == nil {
+		return errors.New("You aren't in a party")
+	}
+
+	if leader == character {
+		return errors.New("You are leading the party")
+	}
+
+	return Follow(character, leader)
+}
+
+// logoutParty disbands or leaves the character's party and forgets their
+// invitations when they log out
+func logoutParty(character *database.Character) {
+	partiesMutex.Lock()
+	defer partiesMutex.Unlock()
+
+	delete(partyInvites, character)
+
+	for invitee, inviter := range partyInvites {
+		if inviter == character {
+			delete(partyInvites, invitee)
+		}
+	}
+
+	if party := parties[character]; party != nil {
+		party.remove(character, fmt.Sprintf("%s has left the game", character.GetName()))
+	}
+}
+
+// membersIn returns the members of the character's party that are in the same
+// room as them, or just the character if they aren't in a party
+func membersIn(character *database.Character) []*database.Character {
+	partiesMutex.RLock()
+	defer partiesMutex.RUnlock()
+
+	party := parties[character]
+
+	if party == nil {
+		return []*database.Character{character}
+	}
+
+	var members []*database.Character
+
+	for _, member := range party.members {
+		if member.GetRoomId() == character.GetRoomId() && !member.IsDead() {
+			members = append(members, member)
+		}
+	}
+
+	return members
+}
+
+// shareExperience gives out the experience for a kill, split evenly between
+// the members of the killer's party who were there
+func shareExperience(killer *database.Character, victim *database.Character) {
+	members := membersIn(killer)
+	share := victim.GetHealth() / len(members)
+
+	if share < 1 {
+		share = 1
+	}
+
+	for _, member := range members {
+		member.AddExperience(share)
+		queueEvent(MessageEvent{Character: member, Message: fmt.Sprintf("You gain %v experience", share)})
+	}
+}
+
+// shareCash gives the character the coins they picked up, splitting them with
+// the members of their party in the room if that is the party's loot rule.
+// Whoever picked the coins up keeps anything that doesn't split evenly.
+func shareCash(character *database.Character, amount int) error {
+	members := []*database.Character{character}
+
+	if PartyLootRule(character) == LootSplitCash {
+		members = membersIn(character)
+	}
+
+	share := amount / len(members)
+	remainder := amount - share*len(members)
+
+	for _, member := range members {
+		cash := share
+		if member == character {
+			cash += remainder
+		}
+
+		if cash == 0 {
+			continue
+		}
+
+		if err := TransferCash(nil, member, cash, "pick up"); err != nil {
+			return err
+		}
+
+		if member != character {
+			queueEvent(MessageEvent{Character: member,
+				Message: fmt.Sprintf("You receive %v coins from %s", cash, character.GetName())})
+		}
+	}
+
+	return nil
+}
+
+// remove takes the member out of the party, handing leadership on and
+// disbanding it when needed. Anyone following the party leader because of
+// the party stops following, or follows the new leader. Must be called with
+// partiesMutex held.
+func (self *Party) remove(member *database.Character, message string) {
+	if self.leader != member {
+		stopFollowingLeader(member, self.leader)
+	}
+
+	for i, other := range self.members {
+		if other == member {
+			self.members = append(self.members[:i:i], self.members[i+1:]...)
+			break
+		}
+	}
+
+	delete(parties, member)
+
+	if len(self.members) == 1 {
+		last := self.members[0]
+		delete(parties, last)
+
+		if self.leader == member {
+			stopFollowingLeader(last, member)
+		}
+
+		queueEvent(MessageEvent{Character: last, Message: message + ", the party has been disbanded"})
+		queueEvent(MessageEvent{Character: member, Message: "The party has been disbanded"})
+		return
+	}
+
+	queueEvent(MessageEvent{Character: member, Message: "You are no longer in a party"})
+
+	if self.leader == member {
+		self.leader = self.members[0]
+		transferFollowers(member, self.leader, self.members)
+		message += fmt.Sprintf(", %s is now the leader", self.leader.GetName())
+	}
+
+	self.notify(message)
+}
+
+// notify tells every member of the party about something that happened. Must
+// be called with partiesMutex held.
+func (self *Party) notify(message string) {
+	queueEvent(PartyEvent{Members: self.copyMembers(), Message: message})
+}
+
+func (self *Party) copyMembers() []*database.Character {
+	return append([]*database.Character(nil), self.members...)
+}
+
+// FindLootRule returns the loot rule with the given name, or an empty rule if
+// there isn't one
+func FindLootRule(name string) LootRule {
+	for _, rule := range LootRules {
+		if strings.EqualFold(string(rule), name) {
+			return rule
+		}
+	}
+
+	return ""
+}
+
+// vim: nocindent
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"errors"
+	"kmud/database"
+)
+
+// ExitDestination returns the room that the exit in the given direction leads
+// to, or nil if it doesn't lead anywhere yet
+func ExitDestination(room *database.Room, dir database.Direction) *database.Room {
+	exit, found := room.GetExit(dir)
+
+	if !found {
+		return nil
+	}
+
+	if exit.IsLinked() {
+		return GetRoom(exit.Destination)
+	}
+
+	return GetRoomByLocation(room.NextLocation(dir), GetZone(room.GetZoneId()))
+}
+
+// LinkExit points the exit in the given direction at a specific room, which
+// may be in another zone. The exit is created if it doesn't already exist.
+func LinkExit(from *database.Room, dir database.Direction, to *database.Room) {
+	exit, _ := from.GetExit(dir)
+	exit.Destination = to.GetId()
+	from.SetExit(dir, exit)
+}
+
+// LinkNamedExit creates or repoints an exit that is taken by typing its name,
+// e.g. "climb ladder"
+func LinkNamedExit(from *database.Room, name string, to *database.Room) {
+	exit, _ := from.GetNamedExit(name)
+	exit.Destination = to.GetId()
+	from.SetNamedExit(name, exit)
+}
+
+// TakeNamedExit moves the character through one of their room's named exits
+func TakeNamedExit(character *database.Character, name string) (*database.Room, error) {
+	room := GetRoom(character.GetRoomId())
+
+	if room == nil {
+		return nil, errors.New("Character doesn't appear to be in any room")
+	}
+
+	exit, found := room.GetNamedExit(name)
+
+	if !found {
+		return room, errors.New("You can't go that way")
+	}
+
+	newRoom, err := followExit(character, room, exit)
+
+	if err == nil {
+		moveFollowers(character, room, func(follower *database.Character) error {
+			_, err := TakeNamedExit(follower, name)
+			return err
+		})
+	}
+
+	return newRoom, err
+}
+
+// followExit moves the character through an exit that has a destination
+func followExit(character *database.Character, room *database.Room, exit database.Exit) (*database.Room, error) {
+	if character.GetPosition() != database.PositionStanding {
+		return room, errors.New("You need to stand up first")
+	}
+
+	if !exit.IsOpen() {
+		return room, errors.New("The door is closed")
+	}
+
+	destination := GetRoom(exit.Destination)
+
+	if destination == nil {
+		return room, errors.New("That way doesn't lead anywhere")
+	}
+
+	MoveCharacterToRoom(character, destination)
+	return destination, nil
+}
+
+// unlinkRoom removes every linked exit that leads to the given room
+func unlinkRoom(room *database.Room) {
+	for _, other := range GetRooms() {
+		for _, dir := range other.GetExits() {
+			if exit, _ := other.GetExit(dir); exit.Destination == room.GetId() {
+				other.SetExitEnabled(dir, false)
+			}
+		}
+
+		for _, name := range other.GetNamedExits() {
+			if exit, _ := other.GetNamedExit(name); exit.Destination == room.GetId() {
+				other.RemoveNamedExit(name)
+			}
+		}
+	}
+}
+
+// vim: nocindent
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"strings"
+	"sync"
+)
+
+var questsMutex sync.RWMutex
+var _quests = map[bson.ObjectId]*database.Quest{}
+
+// questProgressMutex stops two events updating the same player's quests at
+// the same time
+var questProgressMutex sync.Mutex
+
+func CreateQuest(name string) *database.Quest {
+	quest := database.NewQuest(name)
+
+	questsMutex.Lock()
+	_quests[quest.GetId()] = quest
+	questsMutex.Unlock()
+
+	return quest
+}
+
+func GetQuest(id bson.ObjectId) *database.Quest {
+	questsMutex.RLock()
+	defer questsMutex.RUnlock()
+
+	return _quests[id]
+}
+
+func GetQuests() []*database.Quest {
+	questsMutex.RLock()
+	defer questsMutex.RUnlock()
+
+	var quests []*database.Quest
+
+	for _, quest := range _quests {
+		quests = append(quests, quest)
+	}
+
+	return quests
+}
+
+func GetQuestByName(name string) *database.Quest {
+	for _, quest := range GetQuests() {
+		if strings.EqualFold(quest.GetName(), name) {
+			return quest
+		}
+	}
+
+	return nil
+}
+
+// DeleteQuest deletes the quest. Players' progress on it is left alone and
+// ignored from then on.
+func DeleteQuest(quest *database.Quest) {
+	questsMutex.Lock()
+	delete(_quests, quest.GetId())
+	questsMutex.Unlock()
+
+	utils.HandleError(database.DeleteObject(quest))
+}
+
+// StartQuest adds the quest to the player's quest log. Objectives to collect
+// items count whatever the player is already carrying.
+func StartQuest(player *database.Character, quest *database.Quest) error {
+	questProgressMutex.Lock()
+	defer questProgressMutex.Unlock()
+
+	progress, found := player.GetQuestProgress(quest.GetId())
+
+	if found && progress.Complete {
+		return errors.New("You have already completed that quest")
+	} else if found {
+		return errors.New("You are already on that quest")
+	}
+
+	progress = database.QuestProgress{
+		QuestId: quest.GetId(),
+		Counts:  make([]int, len(quest.GetObjectives())),
+	}
+
+	updateQuest(player, quest, progress, "", "")
+	return nil
+}
+
+// IsQuestComplete returns true if the player has completed the quest
+func IsQuestComplete(player *database.Character, quest *database.Quest) bool {
+	progress, found := player.GetQuestProgress(quest.GetId())
+	return found && progress.Complete
+}
+
+// UpdateQuests advances the quests of the player responsible for the event.
+// It is called with every event that the model generates.
+func UpdateQuests(event Event) {
+	var player *database.Character
+	var objectiveType database.ObjectiveType
+	var targetId bson.ObjectId
+
+	switch e := event.(type) {
+	case EnterEvent:
+		player, objectiveType, targetId = e.Character, database.ObjectiveVisit, e.Room.GetId()
+	case CombatEvent:
+		if !e.Killed {
+			return
+		}
+		player, objectiveType, targetId = e.Attacker, database.ObjectiveKill, e.Defender.GetTemplateId()
+	case GetItemEvent:
+		player, objectiveType, targetId = e.Character, database.ObjectiveCollect, e.Item.GetTemplateId()
+	case TalkEvent:
+		player, objectiveType, targetId = e.Character, database.ObjectiveTalk, e.Npc.GetTemplateId()
+	default:
+		return
+	}
+
+	if !player.IsPlayer() || targetId == "" {
+		return
+	}
+
+	questProgressMutex.Lock()
+	defer questProgressMutex.Unlock()
+
+	for _, progress := range player.GetQuests() {
+		quest := GetQuest(progress.QuestId)
+
+		if quest != nil && !progress.Complete {
+			updateQuest(player, quest, progress, objectiveType, targetId)
+		}
+	}
+}
+
+// updateQuest counts the player's progress towards the quest's objectives,
+// paying out the reward once all of them have been met. Only the first
+// unfinished objective of an ordered quest can be worked on.
+func updateQuest(player *database.Character, quest *database.Quest, progress database.QuestProgress,
+	objectiveType database.ObjectiveType, targetId bson.ObjectId) {
+
+	objectives := quest.GetObjectives()
+
+	for len(progress.Counts) < len(objectives) {
+		progress.Counts = append(progress.Counts, 0)
+	}
+
+	complete := true
+
+	for i, objective := range objectives {
+		count := progress.Counts[i]
+
+		if count < objective.Count {
+			if objective.Type == database.ObjectiveCollect {
+				count = countCarried(player, objective.TargetId)
+			} else if objective.Type == objectiveType && objective.TargetId == targetId {
+				count++
+			}
+
+			if count > objective.Count {
+				count = objective.Count
+			}
+
+			if count != progress.Counts[i] {
+				progress.Counts[i] = count
+				queueEvent(MessageEvent{Character: player, Message: fmt.Sprintf("%s: %s (%v/%v)",
+					quest.GetName(), DescribeObjective(objective), count, objective.Count)})
+			}
+		}
+
+		if count < objective.Count {
+			complete = false
+
+			if quest.IsOrdered() {
+				break
+			}
+		}
+	}
+
+	progress.Complete = complete
+	player.SetQuestProgress(progress)
+
+	if complete {
+		queueEvent(MessageEvent{Character: player, Message: fmt.Sprintf("You have completed the quest: %s", quest.GetName())})
+		payReward(player, quest)
+	}
+}
+
+// countCarried returns the number of items made from the template that the
+// character is carrying
+func countCarried(character *database.Character, templateId bson.ObjectId) int {
+	count := 0
+
+	for _, item := range GetItems(character.GetItemIds()) {
+		if item != nil && item.GetTemplateId() == templateId {
+			count++
+		}
+	}
+
+	return count
+}
+
+func payReward(player *database.Character, quest *database.Quest) {
+	reward := quest.GetReward()
+
+	if reward.Cash > 0 && TransferCash(nil, player, reward.Cash, "quest reward") == nil {
+		queueEvent(MessageEvent{Character: player, Message: fmt.Sprintf("You receive %v monies", reward.Cash)})
+	}
+
+	if reward.Experience > 0 {
+		player.AddExperience(reward.Experience)
+		queueEvent(MessageEvent{Character: player, Message: fmt.Sprintf("You gain %v experience", reward.Experience)})
+	}
+
+	for _, templateId := range reward.Items {
+		template := GetItem(templateId)
+
+		if template != nil {
+			item := CreateItemFromTemplate(template)
+			player.AddItem(item)
+			queueEvent(MessageEvent{Character: player, Message: fmt.Sprintf("You receive %s", item.GetName())})
+		}
+	}
+}
+
+// DescribeObjective returns a short description of the objective, such as
+// "Kill Rat"
+func DescribeObjective(objective database.Objective) string {
+	target := "<Deleted>"
+
+	switch objective.Type {
+	case database.ObjectiveKill, database.ObjectiveTalk:
+		if template := GetCharacter(objective.TargetId); template != nil {
+			target = template.GetName()
+		}
+	case database.ObjectiveCollect:
+		if template := GetItem(objective.TargetId); template != nil {
+			target = template.GetName()
+		}
+	case database.ObjectiveVisit:
+		if room := GetRoom(objective.TargetId); room != nil {
+			target = room.GetTitle()
+		}
+	}
+
+	return fmt.Sprintf("%s %s", utils.FormatName(string(objective.Type)), target)
+}
+
+// vim: nocindent
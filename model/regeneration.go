@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"kmud/database"
+	"strconv"
+	"time"
+)
+
+// Characters recover once every regenerationInterval. The rates are the
+// percentage of their maximum health, mana and movement recovered each time,
+// so a sleeping character takes well over a minute to recover fully and a
+// standing one around eight minutes.
+const (
+	regenerationInterval = 10 * time.Second
+	standingRegeneration = 2
+	restingRegeneration  = 5
+	sleepingRegeneration = 10
+)
+
+var regenerationRates = map[database.Position]int{
+	database.PositionStanding: standingRegeneration,
+	database.PositionResting:  restingRegeneration,
+	database.PositionSleeping: sleepingRegeneration,
+}
+
+// regenerationTimer decides which game timer ticks characters recover on
+type regenerationTimer struct {
+	last time.Time
+}
+
+// Only used from the game timer's goroutine
+var regenerationClock regenerationTimer
+
+// due returns true if a regeneration interval has passed since characters
+// last recovered
+func (self *regenerationTimer) due(now time.Time) bool {
+	if now.Sub(self.last) < regenerationInterval {
+		return false
+	}
+
+	self.last = now
+	return true
+}
+
+// Rest sits the character down so that they recover more quickly
+func Rest(character *database.Character) error {
+	return setPosition(character, database.PositionResting)
+}
+
+// Sleep puts the character to sleep so that they recover as quickly as
+// possible. They won't be able to move until they wake up.
+func Sleep(character *database.Character) error {
+	return setPosition(character, database.PositionSleeping)
+}
+
+// Wake stands the character up from resting or sleeping
+func Wake(character *database.Character) error {
+	return setPosition(character, database.PositionStanding)
+}
+
+func setPosition(character *database.Character, position database.Position) error {
+	if character.GetPosition() == position {
+		return errors.New("You are already " + string(position))
+	}
+
+	if position != database.PositionStanding && InCombat(character) {
+		return errors.New("You can't do that while fighting")
+	}
+
+	character.SetPosition(position)
+	queueEvent(PositionEvent{Character: character, Position: position})
+	return nil
+}
+
+// regenerationRate returns the percentage of their pools that the character
+// recovers each tick, taking their position and room in to account. Nobody
+// recovers while fighting.
+func regenerationRate(character *database.Character) int {
+	if character.IsDead() || InCombat(character) {
+		return 0
+	}
+
+	rate := regenerationRates[character.GetPosition()]
+
+	if room := GetRoom(character.GetRoomId()); room != nil {
+		if percent, err := strconv.Atoi(room.GetProperty(database.RoomPropertyRegeneration)); err == nil && percent >= 0 {
+			rate = rate * percent / 100
+		}
+	}
+
+	return rate
+}
+
+// regenerationAmount returns the given percentage of the maximum, rounded up so
+// that small pools still recover
+func regenerationAmount(maximum int, rate int) int {
+	return (maximum*rate + 99) / 100
+}
+
+// regenerate restores some of the health, mana and movement of every character
+// that is in the world, once every regeneration interval
+func regenerate(now time.Time) {
+	if !regenerationClock.due(now) {
+		return
+	}
+
+	for _, character := range GetCharacters() {
+		if character.IsOnline() {
+			regenerateCharacter(character)
+		}
+	}
+}
+
+func regenerateCharacter(character *database.Character) {
+	rate := regenerationRate(character)
+
+	if rate == 0 {
+		return
+	}
+
+	tickDamage(character, -regenerationAmount(character.GetHealth(), rate))
+	character.SetMana(character.GetMana() + regenerationAmount(character.GetMaxMana(), rate))
+	character.SetMovement(character.GetMovement() + regenerationAmount(character.GetMaxMovement(), rate))
+}
+
+// vim: nocindent
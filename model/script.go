@@ -0,0 +1,665 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	lua "github.com/yuin/gopher-lua"
+	"kmud/database"
+	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Scripts that run for longer than this are stopped
+const scriptTimeout = 100 * time.Millisecond
+
+// Limits on the size of a script's call stack and data stack, in entries
+const (
+	scriptCallStackSize = 64
+	scriptRegistrySize  = 1024
+	scriptRegistryMax   = 64 * 1024
+)
+
+// The longest string the string and table libraries will build for a script,
+// in bytes. The registry limit doesn't cover the heap, so without this a single
+// call could allocate gigabytes before the timeout stops the script.
+const scriptMaxString = 1024 * 1024
+
+// Scripts that allocate more than scriptMaxMemory bytes while they run are
+// stopped. This catches strings built up with the .. operator, which the
+// libraries never see. Allocations are checked every scriptMemoryCheck, so the
+// instruction that crosses the limit still finishes before the script stops.
+const (
+	scriptMaxMemory   = 16 * 1024 * 1024
+	scriptMemoryCheck = time.Millisecond
+)
+
+var scriptsMutex sync.RWMutex
+var _scripts = map[bson.ObjectId]*database.Script{}
+
+func CreateScript(name string) *database.Script {
+	script := database.NewScript(name)
+
+	scriptsMutex.Lock()
+	_scripts[script.GetId()] = script
+	scriptsMutex.Unlock()
+
+	return script
+}
+
+func GetScript(id bson.ObjectId) *database.Script {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+
+	return _scripts[id]
+}
+
+func GetScripts() []*database.Script {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+
+	var scripts []*database.Script
+
+	for _, script := range _scripts {
+		scripts = append(scripts, script)
+	}
+
+	return scripts
+}
+
+// DeleteScript deletes the script and detaches it from everything it was
+// attached to
+func DeleteScript(script *database.Script) {
+	scriptsMutex.Lock()
+	delete(_scripts, script.GetId())
+	scriptsMutex.Unlock()
+
+	utils.HandleError(database.DeleteObject(script))
+
+	var attached []database.Scriptable
+
+	mutex.RLock()
+	for _, room := range _rooms {
+		attached = append(attached, room)
+	}
+	for _, item := range _items {
+		attached = append(attached, item)
+	}
+	for _, char := range _chars {
+		attached = append(attached, char)
+	}
+	mutex.RUnlock()
+
+	for _, object := range attached {
+		if object.GetScriptId() == script.GetId() {
+			object.SetScriptId("")
+		}
+	}
+}
+
+// CheckScript compiles the source without running it, returning any syntax
+// errors
+func CheckScript(source string) error {
+	state := newScriptState()
+	defer state.Close()
+
+	_, err := state.LoadString(source)
+	return err
+}
+
+// newScriptState creates a Lua interpreter with only the libraries that can't
+// reach outside of the game
+func newScriptState() *lua.LState {
+	state := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   scriptCallStackSize,
+		RegistrySize:    scriptRegistrySize,
+		RegistryMaxSize: scriptRegistryMax,
+	})
+
+	libs := []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	}
+
+	for _, lib := range libs {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+
+	limitStrings(state)
+
+	// Nothing that can load code from outside the script or touch the host
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "print", "collectgarbage"} {
+		state.SetGlobal(name, lua.LNil)
+	}
+
+	for name, binding := range scriptBindings {
+		state.SetGlobal(name, state.NewFunction(binding))
+	}
+
+	return state
+}
+
+// limitStrings wraps the library functions that can build large strings from
+// small arguments so that they fail instead of going over scriptMaxString
+func limitStrings(state *lua.LState) {
+	wrap := func(libName string, name string, check func(*lua.LState) int) {
+		lib := state.GetGlobal(libName).(*lua.LTable)
+		original := lib.RawGetString(name).(*lua.LFunction)
+
+		lib.RawSetString(name, state.NewFunction(func(L *lua.LState) int {
+			if size := check(L); size > scriptMaxString {
+				L.RaiseError("%s.%s would make a string longer than %v bytes", libName, name, scriptMaxString)
+			}
+
+			L.Insert(original, 1)
+			L.Call(L.GetTop()-1, lua.MultRet)
+			return L.GetTop()
+		}))
+	}
+
+	wrap(lua.StringLibName, "rep", func(L *lua.LState) int {
+		length := len(L.CheckString(1))
+		count := L.CheckInt(2)
+
+		if length == 0 || count <= 0 {
+			return 0
+		}
+
+		if count > scriptMaxString/length {
+			return scriptMaxString + 1
+		}
+
+		return length * count
+	})
+
+	wrap(lua.StringLibName, "format", func(L *lua.LState) int {
+		format := L.CheckString(1)
+
+		// Like standard Lua, widths and precisions are at most two digits
+		for i := 0; i < len(format); i++ {
+			if format[i] != '%' {
+				continue
+			}
+
+			digits := 0
+			for i++; i < len(format) && strings.IndexByte("-+ #0123456789.", format[i]) != -1; i++ {
+				if format[i] >= '0' && format[i] <= '9' {
+					digits++
+					if digits > 2 {
+						L.RaiseError("invalid format (width or precision too long)")
+					}
+				} else if format[i] == '.' {
+					digits = 0
+				}
+			}
+		}
+
+		return len(format)
+	})
+
+	wrap(lua.StringLibName, "gsub", func(L *lua.LState) int {
+		length := len(L.CheckString(1))
+
+		if repl, ok := L.Get(3).(lua.LString); ok {
+			return length + (length+1)*len(repl)
+		}
+
+		return length
+	})
+
+	wrap(lua.TabLibName, "concat", func(L *lua.LState) int {
+		table := L.CheckTable(1)
+		separator := len(L.OptString(2, ""))
+		first := L.OptInt(3, 1)
+		last := L.OptInt(4, table.Len())
+
+		size := 0
+		for i := first; i <= last && size <= scriptMaxString; i++ {
+			if i > first {
+				size += separator
+			}
+
+			if value, ok := table.RawGetInt(i).(lua.LString); ok {
+				size += len(value)
+			} else if value, ok := table.RawGetInt(i).(lua.LNumber); ok {
+				size += len(value.String())
+			} else {
+				break // Concat will fail on this anyway
+			}
+		}
+
+		return size
+	})
+}
+
+// runScript calls one of the functions defined by the script attached to the
+// object, returning the function's result. Nothing happens and false is
+// returned if there is no script or it doesn't define the function. When a
+// character is given their ID is passed as the first argument.
+func runScript(object database.Scriptable, room *database.Room, character *database.Character,
+	function string, args ...lua.LValue) bool {
+	scriptId := object.GetScriptId()
+
+	if scriptId == "" {
+		return false
+	}
+
+	script := GetScript(scriptId)
+
+	if script == nil {
+		return false
+	}
+
+	if character != nil {
+		if !enterTrigger(character) {
+			return false
+		}
+		defer leaveTrigger(character)
+
+		args = append([]lua.LValue{lua.LString(character.GetId().Hex())}, args...)
+	}
+
+	state := newScriptState()
+	defer state.Close()
+
+	state.SetGlobal("this", lua.LString(object.GetId().Hex()))
+	if room != nil {
+		state.SetGlobal("here", lua.LString(room.GetId().Hex()))
+	}
+
+	found := false
+
+	err := runLimited(state, func() error {
+		if err := state.DoString(script.GetSource()); err != nil {
+			return err
+		}
+
+		fn := state.GetGlobal(function)
+
+		if fn.Type() != lua.LTFunction {
+			return nil
+		}
+
+		found = true
+		return state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...)
+	})
+
+	if err != nil {
+		fmt.Printf("Script %s failed in %s: %v\n", script.GetName(), function, err)
+		return false
+	}
+
+	return found && lua.LVAsBool(state.Get(-1))
+}
+
+// runLimited calls run, which should run Lua code in the state, and stops the
+// code if it goes over the time or memory limits for scripts
+func runLimited(state *lua.LState, run func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	state.SetContext(ctx)
+
+	var exceeded int32
+	done := make(chan bool)
+	defer close(done)
+
+	start := allocatedBytes()
+
+	// Cancelling the context stops the script before its next instruction
+	go func() {
+		ticker := time.NewTicker(scriptMemoryCheck)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if allocatedBytes()-start > scriptMaxMemory {
+					atomic.StoreInt32(&exceeded, 1)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := run()
+
+	if err != nil && atomic.LoadInt32(&exceeded) == 1 {
+		return fmt.Errorf("script used more than %v bytes of memory", scriptMaxMemory)
+	}
+
+	return err
+}
+
+// allocatedBytes returns the total number of bytes the program has allocated
+// on the heap so far
+func allocatedBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// scriptsOnEnter lets the room and the NPCs in it react to a character
+// arriving
+func scriptsOnEnter(room *database.Room, character *database.Character) {
+	runScript(room, room, character, "on_enter")
+
+	for _, npc := range NpcsIn(room) {
+		if npc != character {
+			runScript(npc, room, character, "on_enter")
+		}
+	}
+}
+
+func scriptsOnLeave(room *database.Room, character *database.Character) {
+	runScript(room, room, character, "on_leave")
+}
+
+// RunCommandScripts offers a command the game didn't recognise to the scripts
+// of the character's room and the items they can see. Returns true if a script
+// handled it.
+func RunCommandScripts(character *database.Character, command string) bool {
+	room := GetRoom(character.GetRoomId())
+
+	if room == nil {
+		return false
+	}
+
+	if runScript(room, room, character, "on_command", lua.LString(command)) {
+		return true
+	}
+
+	items := append(GetItems(character.GetItemIds()), ItemsIn(room)...)
+
+	for _, item := range items {
+		if runScript(item, room, character, "on_command", lua.LString(command)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TalkScript lets the NPC's script respond to the player talking to it.
+// Returns true if the script handled the conversation.
+func TalkScript(player *database.Character, npc *database.Character) bool {
+	return runScript(npc, GetRoom(npc.GetRoomId()), player, "on_talk")
+}
+
+// ThinkScript runs the NPC's on_think function. Returns true if the script
+// took over from the NPC's usual behaviour.
+func ThinkScript(npc *database.Character) bool {
+	return runScript(npc, GetRoom(npc.GetRoomId()), nil, "on_think")
+}
+
+func scriptOnGet(item *database.Item, character *database.Character) {
+	runScript(item, GetRoom(character.GetRoomId()), character, "on_get")
+}
+
+// Bindings give scripts access to the model. Characters, items and rooms are
+// passed to and from scripts as ID strings.
+var scriptBindings map[string]lua.LGFunction
+
+func init() {
+	scriptBindings = map[string]lua.LGFunction{
+		"name":         scriptName,
+		"room_of":      scriptRoomOf,
+		"characters":   scriptCharacters,
+		"players":      scriptPlayers,
+		"npcs":         scriptNpcs,
+		"items":        scriptItems,
+		"say":          scriptSay,
+		"message":      scriptMessage,
+		"message_room": scriptMessageRoom,
+		"move":         scriptMove,
+		"teleport":     scriptTeleport,
+		"follow":       scriptFollow,
+		"unfollow":     scriptUnfollow,
+		"create_item":  scriptCreateItem,
+		"has_flag":     scriptHasFlag,
+		"set_flag":     scriptSetFlag,
+		"clear_flag":   scriptClearFlag,
+		"is_player":    scriptIsPlayer,
+		"cash":         scriptCash,
+		"give_cash":    scriptGiveCash,
+		"take_cash":    scriptTakeCash,
+		"random":       scriptRandom,
+	}
+}
+
+func scriptId(state *lua.LState, n int) bson.ObjectId {
+	id := state.CheckString(n)
+
+	if !bson.IsObjectIdHex(id) {
+		state.ArgError(n, "not an ID")
+	}
+
+	return bson.ObjectIdHex(id)
+}
+
+func scriptCharacter(state *lua.LState, n int) *database.Character {
+	character := GetCharacter(scriptId(state, n))
+
+	if character == nil {
+		state.ArgError(n, "character not found")
+	}
+
+	return character
+}
+
+func scriptRoom(state *lua.LState, n int) *database.Room {
+	room := GetRoom(scriptId(state, n))
+
+	if room == nil {
+		state.ArgError(n, "room not found")
+	}
+
+	return room
+}
+
+func scriptIds(state *lua.LState, objects []database.Identifiable) int {
+	table := state.NewTable()
+
+	for _, object := range objects {
+		table.Append(lua.LString(object.GetId().Hex()))
+	}
+
+	state.Push(table)
+	return 1
+}
+
+func scriptCharacterIds(state *lua.LState, characters []*database.Character) int {
+	var objects []database.Identifiable
+	for _, character := range characters {
+		objects = append(objects, character)
+	}
+	return scriptIds(state, objects)
+}
+
+func scriptName(state *lua.LState) int {
+	id := scriptId(state, 1)
+
+	if character := GetCharacter(id); character != nil {
+		state.Push(lua.LString(character.GetName()))
+	} else if item := GetItem(id); item != nil {
+		state.Push(lua.LString(item.GetName()))
+	} else if room := GetRoom(id); room != nil {
+		state.Push(lua.LString(room.GetTitle()))
+	} else {
+		state.Push(lua.LNil)
+	}
+
+	return 1
+}
+
+func scriptRoomOf(state *lua.LState) int {
+	state.Push(lua.LString(scriptCharacter(state, 1).GetRoomId().Hex()))
+	return 1
+}
+
+func scriptCharacters(state *lua.LState) int {
+	return scriptCharacterIds(state, CharactersIn(scriptRoom(state, 1)))
+}
+
+func scriptPlayers(state *lua.LState) int {
+	return scriptCharacterIds(state, PlayersIn(scriptRoom(state, 1), nil))
+}
+
+func scriptNpcs(state *lua.LState) int {
+	return scriptCharacterIds(state, NpcsIn(scriptRoom(state, 1)))
+}
+
+func scriptItems(state *lua.LState) int {
+	var objects []database.Identifiable
+	for _, item := range ItemsIn(scriptRoom(state, 1)) {
+		objects = append(objects, item)
+	}
+	return scriptIds(state, objects)
+}
+
+func scriptSay(state *lua.LState) int {
+	queueEvent(SayEvent{Character: scriptCharacter(state, 1), Message: state.CheckString(2)})
+	return 0
+}
+
+func scriptMessage(state *lua.LState) int {
+	queueEvent(MessageEvent{Character: scriptCharacter(state, 1), Message: state.CheckString(2)})
+	return 0
+}
+
+func scriptMessageRoom(state *lua.LState) int {
+	queueEvent(RoomMessageEvent{Room: scriptRoom(state, 1), Message: state.CheckString(2)})
+	return 0
+}
+
+func scriptMove(state *lua.LState) int {
+	character := scriptCharacter(state, 1)
+	direction := database.StringToDirection(state.CheckString(2))
+
+	if direction == database.DirectionNone {
+		state.ArgError(2, "not a direction")
+	}
+
+	_, err := MoveCharacter(character, direction)
+	state.Push(lua.LBool(err == nil))
+	return 1
+}
+
+func scriptTeleport(state *lua.LState) int {
+	MoveCharacterToRoom(scriptCharacter(state, 1), scriptRoom(state, 2))
+	return 0
+}
+
+func scriptFollow(state *lua.LState) int {
+	err := Follow(scriptCharacter(state, 1), scriptCharacter(state, 2))
+	state.Push(lua.LBool(err == nil))
+	return 1
+}
+
+func scriptUnfollow(state *lua.LState) int {
+	StopFollowing(scriptCharacter(state, 1))
+	return 0
+}
+
+// scriptCreateItem creates an item, from a template if one has the given name,
+// and gives it to a character or leaves it in a room
+func scriptCreateItem(state *lua.LState) int {
+	name := state.CheckString(1)
+	id := scriptId(state, 2)
+
+	character := GetCharacter(id)
+	room := GetRoom(id)
+
+	if character == nil && room == nil {
+		state.ArgError(2, "character or room not found")
+	}
+
+	var item *database.Item
+
+	for _, template := range GetItemTemplates() {
+		if template.GetName() == name {
+			item = CreateItemFromTemplate(template)
+			break
+		}
+	}
+
+	if item == nil {
+		item = CreateItem(name)
+	}
+
+	if character != nil {
+		character.AddItem(item)
+	} else {
+		room.AddItem(item)
+	}
+
+	state.Push(lua.LString(item.GetId().Hex()))
+	return 1
+}
+
+func scriptHasFlag(state *lua.LState) int {
+	state.Push(lua.LBool(scriptCharacter(state, 1).HasFlag(state.CheckString(2))))
+	return 1
+}
+
+func scriptSetFlag(state *lua.LState) int {
+	scriptCharacter(state, 1).SetFlag(state.CheckString(2))
+	return 0
+}
+
+func scriptClearFlag(state *lua.LState) int {
+	scriptCharacter(state, 1).ClearFlag(state.CheckString(2))
+	return 0
+}
+
+func scriptIsPlayer(state *lua.LState) int {
+	state.Push(lua.LBool(scriptCharacter(state, 1).IsPlayer()))
+	return 1
+}
+
+func scriptCash(state *lua.LState) int {
+	state.Push(lua.LNumber(scriptCharacter(state, 1).GetCash()))
+	return 1
+}
+
+func scriptGiveCash(state *lua.LState) int {
+	err := TransferCash(nil, scriptCharacter(state, 1), state.CheckInt(2), "script")
+	state.Push(lua.LBool(err == nil))
+	return 1
+}
+
+func scriptTakeCash(state *lua.LState) int {
+	err := TransferCash(scriptCharacter(state, 1), nil, state.CheckInt(2), "script")
+	state.Push(lua.LBool(err == nil))
+	return 1
+}
+
+func scriptRandom(state *lua.LState) int {
+	low := state.CheckInt(1)
+	high := state.CheckInt(2)
+
+	if high < low {
+		state.ArgError(2, "must not be less than the first argument")
+	}
+
+	state.Push(lua.LNumber(utils.Random(low, high)))
+	return 1
+}
+
+// vim: nocindent
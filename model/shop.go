@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+)
+
+// Shopkeeper returns the first shopkeeper found in the given room, or nil if
+// there isn't one
+func Shopkeeper(room *database.Room) *database.Character {
+	for _, npc := range NpcsIn(room) {
+		if npc.IsShopkeeper() {
+			return npc
+		}
+	}
+
+	return nil
+}
+
+// ShopStock returns the items the shopkeeper has for sale. Anything the
+// shopkeeper is wearing or wielding isn't for sale.
+func ShopStock(shopkeeper *database.Character) []*database.Item {
+	var stock []*database.Item
+
+	for _, item := range GetItems(shopkeeper.GetItemIds()) {
+		if _, equipped := shopkeeper.EquippedSlot(item); !equipped {
+			stock = append(stock, item)
+		}
+	}
+
+	return stock
+}
+
+// Price returns what the shopkeeper charges for one of their items
+func Price(shopkeeper *database.Character, item *database.Item) int {
+	return shopkeeper.GetShop().SellPrice(item.GetValue())
+}
+
+// Offer returns what the shopkeeper would pay for the given item. Fails if
+// the shopkeeper has no interest in buying it.
+func Offer(shopkeeper *database.Character, item *database.Item) (int, error) {
+	offer := shopkeeper.GetShop().BuyPrice(item.GetValue())
+
+	if IsCoins(item) || offer <= 0 {
+		return 0, fmt.Errorf("%s isn't interested in %s", shopkeeper.GetName(), item.GetName())
+	}
+
+	return offer, nil
+}
+
+// Buy sells one of the shopkeeper's items to the player, returning the price
+// paid
+func Buy(player *database.Character, shopkeeper *database.Character, item *database.Item) (int, error) {
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	if !shopkeeper.HasItem(item) {
+		return 0, errors.New("That is no longer for sale")
+	}
+
+	price := Price(shopkeeper, item)
+
+	if TransferCash(player, nil, price, "bought "+item.GetName()) != nil {
+		return 0, fmt.Errorf("You can't afford %s", item.GetName())
+	}
+
+	removeItem(shopkeeper, item)
+	player.AddItem(item)
+
+	return price, nil
+}
+
+// Sell sells one of the player's items to the shopkeeper, returning the
+// amount paid for it
+func Sell(player *database.Character, shopkeeper *database.Character, item *database.Item) (int, error) {
+	offer, err := Offer(shopkeeper, item)
+
+	if err != nil {
+		return 0, err
+	}
+
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	if !removeItem(player, item) {
+		return 0, errors.New("You aren't carrying that")
+	}
+
+	shopkeeper.AddItem(item)
+	TransferCash(nil, player, offer, "sold "+item.GetName())
+
+	return offer, nil
+}
+
+// Restock tops the shopkeeper's stock back up to the levels given in their
+// shop configuration
+func Restock(shopkeeper *database.Character) {
+	shop := shopkeeper.GetShop()
+
+	if shop == nil {
+		return
+	}
+
+	stock := ShopStock(shopkeeper)
+
+	for _, entry := range shop.Stock {
+		template := GetItem(entry.TemplateId)
+
+		if template == nil || !template.IsTemplate() {
+			continue
+		}
+
+		count := 0
+		for _, item := range stock {
+			if item.GetTemplateId() == entry.TemplateId {
+				count++
+			}
+		}
+
+		for ; count < entry.Count; count++ {
+			shopkeeper.AddItem(CreateItemFromTemplate(template))
+		}
+	}
+}
+
+// vim: nocindent
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	enhancedDamageMax = 4  // Extra damage done by every hit at full proficiency
+	dodgeMax          = 15 // Percent taken off an opponent's chance to hit at full proficiency
+)
+
+var cooldownsMutex sync.Mutex
+var cooldowns = map[*database.Character]map[string]time.Time{} // When each skill can next be used
+
+// SetClass gives the character the stats of the class and teaches them its
+// skills. Skills the character already knows keep their proficiency.
+func SetClass(character *database.Character, class *database.Class) {
+	character.SetClass(class.Name)
+	character.SetHealth(class.Health)
+	character.SetHitPoints(class.Health)
+	character.SetStrength(class.Strength)
+	character.SetDexterity(class.Dexterity)
+	character.SetMaxMovement(class.Movement)
+	character.SetMovement(class.Movement)
+	character.SetMaxMana(class.Mana)
+	character.SetMana(class.Mana)
+
+	for _, skill := range class.Skills {
+		if character.GetSkill(skill) == 0 {
+			character.SetSkill(skill, database.StartingProficiency)
+		}
+	}
+}
+
+// KnownSkills returns the skills that the character knows, sorted by name
+func KnownSkills(character *database.Character) []database.Skill {
+	var names []string
+	for name := range character.GetSkills() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var skills []database.Skill
+	for _, name := range names {
+		if skill := database.FindSkill(name); skill != nil {
+			skills = append(skills, *skill)
+		}
+	}
+
+	return skills
+}
+
+// Cooldown returns how long the character has to wait before they can use
+// the skill again
+func Cooldown(character *database.Character, skill string) time.Duration {
+	cooldownsMutex.Lock()
+	defer cooldownsMutex.Unlock()
+
+	remaining := cooldowns[character][skill].Sub(time.Now())
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+func startCooldown(character *database.Character, skill *database.Skill) {
+	cooldownsMutex.Lock()
+	defer cooldownsMutex.Unlock()
+
+	if cooldowns[character] == nil {
+		cooldowns[character] = map[string]time.Time{}
+	}
+
+	cooldowns[character][skill.Name] = time.Now().Add(skill.Cooldown)
+}
+
+// UseSkill has the character use an active skill against the target. If no
+// target is given the character's current opponent is used. Using a skill on
+// someone the character isn't fighting starts a fight with them.
+func UseSkill(character *database.Character, name string, target *database.Character) error {
+	skill := database.FindSkill(name)
+
+	if skill == nil || character.GetSkill(skill.Name) == 0 {
+		return errors.New("You don't know how to do that")
+	}
+
+	if skill.Type != database.SkillActive {
+		return fmt.Errorf("You use %s without thinking about it", skill.Name)
+	}
+
+	if target == nil {
+		fightsMutex.RLock()
+		target = fights[character]
+		fightsMutex.RUnlock()
+
+		if target == nil {
+			return fmt.Errorf("Who do you want to %s?", skill.Name)
+		}
+	}
+
+	if target == character {
+		return errors.New("You can't do that to yourself")
+	}
+
+	if skill.Opener && InCombat(target) {
+		return errors.New("They are too alert for that")
+	}
+
+	if remaining := Cooldown(character, skill.Name); remaining > 0 {
+		return fmt.Errorf("You can't %s again for another %v seconds", skill.Name, int(remaining.Seconds()+0.5))
+	}
+
+	if character.GetMovement() < skill.Cost {
+		return errors.New("You are too tired")
+	}
+
+	if err := StartFight(character, target); err != nil {
+		return err
+	}
+
+	if !character.UseMovement(skill.Cost) {
+		return errors.New("You are too tired")
+	}
+
+	startCooldown(character, skill)
+
+	if roll(1, 100) <= character.GetSkill(skill.Name) {
+		strike(character, target, skill.Name, rollDice(skill.Damage)+statBonus(Strength(character))-ArmorClass(target))
+	} else {
+		queueEvent(CombatEvent{Attacker: character, Defender: target, Outcome: CombatMiss, Skill: skill.Name})
+	}
+
+	practice(character, skill.Name)
+
+	return nil
+}
+
+// strike does damage to the target with a skill or spell, killing them if it
+// takes all of their hit points
+func strike(attacker *database.Character, target *database.Character, name string, damage int) {
+	event := CombatEvent{Attacker: attacker, Defender: target, Outcome: CombatHit, Skill: name}
+
+	if damage < minDamage {
+		damage = minDamage
+	}
+
+	// Damage is applied while holding the fights mutex so that the combat loop
+	// can't kill the target at the same time
+	fightsMutex.Lock()
+
+	if target.IsDead() {
+		fightsMutex.Unlock()
+		return
+	}
+
+	event.Damage = damage
+	target.Hit(damage)
+	event.Killed = target.IsDead()
+
+	if event.Killed {
+		endFightsWith(target, StopReasonDeath, StopReasonDeath)
+	}
+
+	fightsMutex.Unlock()
+
+	queueEvent(event)
+
+	if event.Killed {
+		Kill(target, attacker)
+	}
+}
+
+// practice gives the character a chance to get better at a skill they just
+// used. The better they already are the less likely they are to improve.
+func practice(character *database.Character, skill string) {
+	proficiency := character.GetSkill(skill)
+
+	if proficiency == 0 || proficiency >= database.MaxProficiency {
+		return
+	}
+
+	if roll(1, 100) > proficiency {
+		character.SetSkill(skill, proficiency+1)
+		queueEvent(MessageEvent{Character: character, Message: fmt.Sprintf("You have become better at %s! (%v%%)", skill, proficiency+1)})
+	}
+}
+
+// enhancedDamage returns the extra damage the character does with each hit
+func enhancedDamage(character *database.Character) int {
+	return character.GetSkill("enhanced damage") * enhancedDamageMax / database.MaxProficiency
+}
+
+// dodgeChance returns how much the character's dodging takes off an
+// attacker's chance to hit them
+func dodgeChance(character *database.Character) int {
+	return character.GetSkill("dodge") * dodgeMax / database.MaxProficiency
+}
+
+// vim: nocindent
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"errors"
+	"kmud/database"
+)
+
+// KnownSpells returns the spells the character's class is able to cast
+func KnownSpells(character *database.Character) []database.Spell {
+	class := database.FindClass(character.GetClass())
+
+	if class == nil {
+		return nil
+	}
+
+	var spells []database.Spell
+
+	for _, name := range class.Spells {
+		if spell := database.FindSpell(name); spell != nil {
+			spells = append(spells, *spell)
+		}
+	}
+
+	return spells
+}
+
+func knowsSpell(character *database.Character, spell *database.Spell) bool {
+	for _, known := range KnownSpells(character) {
+		if known.Name == spell.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cast has the caster cast a spell on the target. Offensive spells are cast on
+// the caster's current opponent if no target is given and start a fight with
+// the target, while other spells are cast on the caster.
+func Cast(caster *database.Character, name string, target *database.Character) error {
+	spell := database.FindSpell(name)
+
+	if spell == nil || !knowsSpell(caster, spell) {
+		return errors.New("You don't know that spell")
+	}
+
+	switch spell.Target {
+	case database.SpellSelf:
+		if target != nil && target != caster {
+			return errors.New("You can only cast that on yourself")
+		}
+		target = caster
+	case database.SpellDefensive:
+		if target == nil {
+			target = caster
+		}
+	case database.SpellOffensive:
+		if target == nil {
+			fightsMutex.RLock()
+			target = fights[caster]
+			fightsMutex.RUnlock()
+
+			if target == nil {
+				return errors.New("Who do you want to cast it on?")
+			}
+		}
+
+		if target == caster {
+			return errors.New("You can't cast that on yourself")
+		}
+	}
+
+	if target.GetRoomId() != caster.GetRoomId() {
+		return errors.New("They aren't here")
+	}
+
+	if caster.GetMana() < spell.Mana {
+		return errors.New("You don't have enough mana")
+	}
+
+	if spell.Target == database.SpellOffensive {
+		if err := StartFight(caster, target); err != nil {
+			return err
+		}
+	}
+
+	if !caster.UseMana(spell.Mana) {
+		return errors.New("You don't have enough mana")
+	}
+
+	queueEvent(SpellEvent{Caster: caster, Target: target, Spell: spell.Name, Room: GetRoom(caster.GetRoomId())})
+
+	if spell.Heal != "" {
+		target.Heal(rollDice(spell.Heal))
+	}
+
+	if spell.Status != "" {
+		ApplyStatus(target, spell.Status)
+	}
+
+	if spell.Damage != "" {
+		strike(caster, target, spell.Name, rollDice(spell.Damage))
+	}
+
+	return nil
+}
+
+// vim: nocindent
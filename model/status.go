@@ -0,0 +1,163 @@
+package model
+
+import (
+	"kmud/database"
+	"sync"
+	"time"
+)
+
+// statusMutex stops the timer and spells from changing a character's status
+// effects at the same time
+var statusMutex sync.Mutex
+
+// ApplyStatus puts the status effect on the character, following its stacking
+// rule if they already have it. Returns false if nothing changed.
+func ApplyStatus(character *database.Character, name string) bool {
+	status := database.FindStatus(name)
+
+	if status == nil {
+		return false
+	}
+
+	statusMutex.Lock()
+	defer statusMutex.Unlock()
+
+	now := time.Now()
+	statuses := character.GetStatuses()
+
+	for i, active := range statuses {
+		if active.Name != status.Name {
+			continue
+		}
+
+		switch status.Stacking {
+		case database.StackNone:
+			return false
+		case database.StackAdd:
+			if active.Stacks < status.MaxStacks {
+				statuses[i].Stacks++
+			}
+		}
+
+		statuses[i].Expires = now.Add(status.Duration)
+		character.SetStatuses(statuses)
+		queueEvent(StatusEvent{Character: character, Status: status})
+		return true
+	}
+
+	active := database.ActiveStatus{Name: status.Name, Stacks: 1, Expires: now.Add(status.Duration)}
+
+	if status.TickInterval > 0 {
+		active.NextTick = now.Add(status.TickInterval)
+	}
+
+	character.SetStatuses(append(statuses, active))
+	queueEvent(StatusEvent{Character: character, Status: status})
+	return true
+}
+
+// statusModifiers adds up the stat modifiers of the character's status effects
+func statusModifiers(character *database.Character) (strength int, dexterity int, armor int) {
+	for _, active := range character.GetStatuses() {
+		if status := database.FindStatus(active.Name); status != nil {
+			strength += status.Strength * active.Stacks
+			dexterity += status.Dexterity * active.Stacks
+			armor += status.Armor * active.Stacks
+		}
+	}
+
+	return
+}
+
+// Strength returns the character's strength once their status effects have
+// been taken in to account
+func Strength(character *database.Character) int {
+	strength, _, _ := statusModifiers(character)
+	return character.GetStrength() + strength
+}
+
+// Dexterity returns the character's dexterity once their status effects have
+// been taken in to account
+func Dexterity(character *database.Character) int {
+	_, dexterity, _ := statusModifiers(character)
+	return character.GetDexterity() + dexterity
+}
+
+// tickStatuses applies the periodic damage and healing of everyone's status
+// effects, and removes the ones that have worn off
+func tickStatuses(now time.Time) {
+	var deaths []*database.Character
+
+	statusMutex.Lock()
+
+	for _, character := range GetCharacters() {
+		statuses := character.GetStatuses()
+
+		if len(statuses) == 0 {
+			continue
+		}
+
+		var remaining []database.ActiveStatus
+
+		for _, active := range statuses {
+			status := database.FindStatus(active.Name)
+
+			if status == nil {
+				continue
+			}
+
+			if status.TickInterval > 0 && !now.Before(active.NextTick) {
+				damage := status.TickDamage * active.Stacks
+				active.NextTick = now.Add(status.TickInterval)
+
+				if tickDamage(character, damage) {
+					deaths = append(deaths, character)
+				}
+
+				queueEvent(StatusEvent{Character: character, Status: status, Damage: damage})
+			}
+
+			if now.Before(active.Expires) {
+				remaining = append(remaining, active)
+			} else {
+				queueEvent(StatusEvent{Character: character, Status: status, Ended: true})
+			}
+		}
+
+		character.SetStatuses(remaining)
+	}
+
+	statusMutex.Unlock()
+
+	for _, character := range deaths {
+		Kill(character, nil)
+	}
+}
+
+// tickDamage hurts or heals the character by the given amount, returning true
+// if it killed them. It holds the fights mutex so that the combat loop can't
+// kill them at the same time.
+func tickDamage(character *database.Character, damage int) bool {
+	fightsMutex.Lock()
+	defer fightsMutex.Unlock()
+
+	if character.IsDead() {
+		return false
+	}
+
+	if damage < 0 {
+		character.Heal(-damage)
+		return false
+	}
+
+	character.Hit(damage)
+
+	if character.IsDead() {
+		endFightsWith(character, StopReasonDeath, StopReasonDeath)
+		return true
+	}
+
+	return false
+}
+
+// vim: nocindent
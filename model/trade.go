@@ -0,0 +1,334 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"kmud/database"
+	"strings"
+	"sync"
+)
+
+// Trade is an exchange of items and cash between two players. Each side adds
+// to their offer and then confirms it. Changing either offer clears both
+// confirmations, and once both sides have confirmed the exchange happens.
+type Trade struct {
+	mutex     sync.Mutex
+	parties   [2]*database.Character
+	items     [2][]*database.Item
+	cash      [2]int
+	confirmed [2]bool
+	finished  bool
+}
+
+var tradesMutex sync.Mutex
+var trades = map[*database.Character]*Trade{}
+
+// StartTrade opens a trade between the two characters
+func StartTrade(initiator *database.Character, other *database.Character) (*Trade, error) {
+	if initiator == other {
+		return nil, errors.New("You can't trade with yourself")
+	}
+
+	if !other.IsPlayer() || !other.IsOnline() {
+		return nil, fmt.Errorf("%s doesn't want to trade", other.GetName())
+	}
+
+	if initiator.GetRoomId() != other.GetRoomId() {
+		return nil, errors.New("They aren't here")
+	}
+
+	if InCombat(initiator) || InCombat(other) {
+		return nil, errors.New("You can't trade during a fight")
+	}
+
+	tradesMutex.Lock()
+	defer tradesMutex.Unlock()
+
+	if trades[initiator] != nil {
+		return nil, errors.New("You are already trading")
+	}
+
+	if trades[other] != nil {
+		return nil, fmt.Errorf("%s is already trading with someone else", other.GetName())
+	}
+
+	trade := &Trade{parties: [2]*database.Character{initiator, other}}
+	trades[initiator] = trade
+	trades[other] = trade
+
+	queueEvent(TradeEvent{Character: other,
+		Message: fmt.Sprintf("%s wants to trade with you", initiator.GetName())})
+
+	return trade, nil
+}
+
+// GetTrade returns the trade the character is involved in, or nil
+func GetTrade(character *database.Character) *Trade {
+	tradesMutex.Lock()
+	defer tradesMutex.Unlock()
+
+	return trades[character]
+}
+
+// CancelTrade cancels any trade the character is involved in, letting both
+// sides know why
+func CancelTrade(character *database.Character, reason string) {
+	trade := GetTrade(character)
+
+	if trade != nil {
+		trade.mutex.Lock()
+		trade.finish()
+		trade.mutex.Unlock()
+
+		for _, party := range trade.parties {
+			queueEvent(TradeEvent{Character: party, Message: "Trade cancelled: " + reason})
+		}
+	}
+}
+
+// finish removes the trade from the list of active trades. Must be called with
+// the trade's mutex held.
+func (self *Trade) finish() {
+	self.finished = true
+
+	tradesMutex.Lock()
+	for _, party := range self.parties {
+		if trades[party] == self {
+			delete(trades, party)
+		}
+	}
+	tradesMutex.Unlock()
+}
+
+// side returns the index of the character in the trade. Must be called with
+// the trade's mutex held.
+func (self *Trade) side(character *database.Character) (int, error) {
+	if self.finished {
+		return 0, errors.New("That trade is over")
+	}
+
+	for i, party := range self.parties {
+		if party == character {
+			return i, nil
+		}
+	}
+
+	return 0, errors.New("You aren't part of that trade")
+}
+
+// changed clears both confirmations and lets the other side know what
+// happened. Must be called with the trade's mutex held.
+func (self *Trade) changed(side int, message string) {
+	self.confirmed = [2]bool{false, false}
+	queueEvent(TradeEvent{Character: self.parties[1-side], Message: message})
+}
+
+// Partner returns the other character in the trade
+func (self *Trade) Partner(character *database.Character) *database.Character {
+	if self.parties[0] == character {
+		return self.parties[1]
+	}
+	return self.parties[0]
+}
+
+func (self *Trade) OfferItem(character *database.Character, item *database.Item) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	if err != nil {
+		return err
+	}
+
+	if !character.HasItem(item) {
+		return errors.New("You aren't carrying that")
+	}
+
+	for _, offered := range self.items[side] {
+		if offered == item {
+			return fmt.Errorf("You are already offering %s", item.GetName())
+		}
+	}
+
+	self.items[side] = append(self.items[side], item)
+	self.changed(side, fmt.Sprintf("%s offers %s", character.GetName(), item.GetName()))
+
+	return nil
+}
+
+func (self *Trade) WithdrawItem(character *database.Character, item *database.Item) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	if err != nil {
+		return err
+	}
+
+	for i, offered := range self.items[side] {
+		if offered == item {
+			self.items[side] = append(self.items[side][:i:i], self.items[side][i+1:]...)
+			self.changed(side, fmt.Sprintf("%s withdraws %s", character.GetName(), item.GetName()))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("You aren't offering %s", item.GetName())
+}
+
+// OfferCash sets the amount of cash the character is offering
+func (self *Trade) OfferCash(character *database.Character, amount int) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	if err != nil {
+		return err
+	}
+
+	if amount < 0 {
+		return errors.New("The amount can't be negative")
+	}
+
+	if amount > character.GetCash() {
+		return errors.New("You don't have that much cash")
+	}
+
+	self.cash[side] = amount
+	self.changed(side, fmt.Sprintf("%s offers %v coins", character.GetName(), amount))
+
+	return nil
+}
+
+// Offer returns the items and cash the character is currently offering
+func (self *Trade) Offer(character *database.Character) ([]*database.Item, int) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	if err != nil {
+		return nil, 0
+	}
+
+	return append([]*database.Item(nil), self.items[side]...), self.cash[side]
+}
+
+// IsConfirmed returns true if the character has confirmed the trade as it stands
+func (self *Trade) IsConfirmed(character *database.Character) bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	return err == nil && self.confirmed[side]
+}
+
+// Confirm accepts the trade as it currently stands on behalf of the
+// character. Once both sides have confirmed the exchange is carried out and
+// true is returned.
+func (self *Trade) Confirm(character *database.Character) (bool, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	side, err := self.side(character)
+	if err != nil {
+		return false, err
+	}
+
+	self.confirmed[side] = true
+
+	if !self.confirmed[1-side] {
+		queueEvent(TradeEvent{Character: self.parties[1-side],
+			Message: fmt.Sprintf("%s accepts the trade", character.GetName())})
+		return false, nil
+	}
+
+	err = self.exchange()
+	self.finish()
+
+	for _, party := range self.parties {
+		if err == nil {
+			queueEvent(TradeEvent{Character: party, Message: "Trade complete"})
+		} else {
+			queueEvent(TradeEvent{Character: party, Message: "Trade failed: " + err.Error()})
+		}
+	}
+
+	return err == nil, err
+}
+
+// exchange swaps both offers. Either everything changes hands or nothing does.
+// Must be called with the trade's mutex held.
+func (self *Trade) exchange() error {
+	claimMutex.Lock()
+	defer claimMutex.Unlock()
+
+	for side, party := range self.parties {
+		for _, item := range self.items[side] {
+			if !party.HasItem(item) {
+				return fmt.Errorf("%s no longer has %s", party.GetName(), item.GetName())
+			}
+		}
+	}
+
+	a, b := self.parties[0], self.parties[1]
+
+	if !a.TakeCash(self.cash[0]) {
+		return fmt.Errorf("%s doesn't have enough cash", a.GetName())
+	}
+
+	if !b.TakeCash(self.cash[1]) {
+		a.AddCash(self.cash[0])
+		return fmt.Errorf("%s doesn't have enough cash", b.GetName())
+	}
+
+	b.AddCash(self.cash[0])
+	a.AddCash(self.cash[1])
+
+	for side, party := range self.parties {
+		if self.cash[side] > 0 {
+			recordTransaction(party, self.parties[1-side], self.cash[side], "trade")
+		}
+
+		for _, item := range self.items[side] {
+			removeItem(party, item)
+			self.parties[1-side].AddItem(item)
+		}
+	}
+
+	return nil
+}
+
+// Describe summarises the trade from the point of view of the given character
+func (self *Trade) Describe(character *database.Character) string {
+	describe := func(who string, items []*database.Item, cash int, confirmed bool) string {
+		var offer []string
+		for _, item := range items {
+			offer = append(offer, item.GetName())
+		}
+
+		if cash > 0 {
+			offer = append(offer, fmt.Sprintf("%v coins", cash))
+		}
+
+		if len(offer) == 0 {
+			offer = append(offer, "nothing")
+		}
+
+		status := ""
+		if confirmed {
+			status = " (accepted)"
+		}
+
+		return fmt.Sprintf("%s offer%s: %s", who, status, strings.Join(offer, ", "))
+	}
+
+	partner := self.Partner(character)
+
+	items, cash := self.Offer(character)
+	partnerItems, partnerCash := self.Offer(partner)
+
+	return describe("Your", items, cash, self.IsConfirmed(character)) + "\r\n" +
+		describe(partner.GetName()+"'s", partnerItems, partnerCash, self.IsConfirmed(partner))
+}
+
+// vim: nocindent
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"kmud/database"
+	"labix.org/v2/mgo/bson"
+	"strings"
+	"sync"
+)
+
+// Triggers can move characters into rooms with triggers of their own, so the
+// number of triggers running on behalf of each character is limited to stop
+// two rooms from bouncing a character back and forth forever
+const maxTriggerDepth = 5
+
+var triggerMutex sync.Mutex
+var triggerDepth = map[*database.Character]int{}
+
+// enterTrigger records that a trigger or script is about to run on behalf of
+// the character. Returns false if too many are already running.
+func enterTrigger(character *database.Character) bool {
+	triggerMutex.Lock()
+	defer triggerMutex.Unlock()
+
+	if triggerDepth[character] >= maxTriggerDepth {
+		return false
+	}
+
+	triggerDepth[character]++
+	return true
+}
+
+func leaveTrigger(character *database.Character) {
+	triggerMutex.Lock()
+	defer triggerMutex.Unlock()
+
+	triggerDepth[character]--
+	if triggerDepth[character] == 0 {
+		delete(triggerDepth, character)
+	}
+}
+
+// RunTriggers runs the room's triggers of the given type on behalf of the
+// character
+func RunTriggers(room *database.Room, character *database.Character, triggerType database.TriggerType) {
+	for _, trigger := range room.GetTriggers() {
+		if trigger.Type == triggerType {
+			RunTrigger(room, character, trigger)
+		}
+	}
+}
+
+// RunCommandTriggers runs the triggers in the character's room that respond
+// to the given command. Returns false if the room doesn't know the command.
+func RunCommandTriggers(character *database.Character, command string) bool {
+	room := GetRoom(character.GetRoomId())
+
+	if room == nil {
+		return false
+	}
+
+	found := false
+
+	for _, trigger := range room.GetTriggers() {
+		if trigger.Type == database.TriggerCommand && strings.EqualFold(trigger.Verb, command) {
+			found = true
+			RunTrigger(room, character, trigger)
+		}
+	}
+
+	return found
+}
+
+// RunTrigger carries out the trigger's actions, provided the character meets
+// its conditions. The character is nil for timer triggers.
+func RunTrigger(room *database.Room, character *database.Character, trigger database.Trigger) {
+	if character != nil {
+		if !ConditionsMet(character, trigger.Conditions) {
+			return
+		}
+
+		if !enterTrigger(character) {
+			return
+		}
+		defer leaveTrigger(character)
+	}
+
+	for _, action := range trigger.Actions {
+		if character == nil && action.Type.NeedsCharacter() {
+			continue
+		}
+
+		runTriggerAction(room, character, action)
+	}
+}
+
+func runTriggerAction(room *database.Room, character *database.Character, action database.TriggerAction) {
+	switch action.Type {
+	case database.ActionMessage:
+		queueEvent(MessageEvent{Character: character, Message: action.Value})
+
+	case database.ActionMessageRoom:
+		queueEvent(RoomMessageEvent{Room: room, Message: action.Value})
+
+	case database.ActionTeleport:
+		if bson.IsObjectIdHex(action.Value) {
+			destination := GetRoom(bson.ObjectIdHex(action.Value))
+			if destination != nil {
+				MoveCharacterToRoom(character, destination)
+			}
+		}
+
+	case database.ActionGiveItem:
+		var item *database.Item
+
+		for _, template := range GetItemTemplates() {
+			if strings.EqualFold(template.GetName(), action.Value) {
+				item = CreateItemFromTemplate(template)
+				break
+			}
+		}
+
+		if item == nil {
+			item = CreateItem(action.Value)
+		}
+
+		character.AddItem(item)
+
+	case database.ActionTakeItem:
+		item := findItem(character, action.Value)
+		if item != nil && takeItem(character, item) {
+			DeleteItem(item)
+		}
+
+	case database.ActionOpenDoor, database.ActionCloseDoor:
+		dir := database.StringToDirection(action.Value)
+		exit, found := room.GetExit(dir)
+
+		if found && exit.HasDoor() {
+			doorMutex.Lock()
+			if action.Type == database.ActionOpenDoor {
+				exit.Door = database.DoorOpen
+			} else {
+				exit.Door = database.DoorClosed
+			}
+			SetExit(room, dir, exit)
+			doorMutex.Unlock()
+		}
+
+	case database.ActionSpawn:
+		for _, template := range GetAllNpcTemplates() {
+			if strings.EqualFold(template.GetName(), action.Value) {
+				CreateNpcFromTemplate(template, room)
+				break
+			}
+		}
+	}
+}
+
+// vim: nocindent
@@ -160,12 +160,31 @@ func newPlayer(conn *wrappedConnection, user *database.User) *database.Character
 		} else if err := utils.ValidateName(name); err != nil {
 			user.WriteLine(err.Error())
 		} else {
+			choice, _ := classMenu().Exec(conn, user.GetColorMode())
+			index, err := strconv.Atoi(choice)
+
+			if err != nil || index < 1 || index > len(database.Classes) {
+				continue
+			}
+
 			room := model.GetRooms()[0] // TODO: Better way to pick an initial character location
-			return model.CreatePlayer(name, user, room)
+			player := model.CreatePlayer(name, user, room)
+			model.SetClass(player, &database.Classes[index-1])
+			return player
 		}
 	}
 }
 
+func classMenu() *utils.Menu {
+	menu := utils.NewMenu("Class")
+
+	for i, class := range database.Classes {
+		menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("%s - %s", class.Name, class.Description))
+	}
+
+	return menu
+}
+
 func mainMenu() *utils.Menu {
 	menu := utils.NewMenu("MUD")
 
@@ -181,7 +200,9 @@ func userMenu(user *database.User) *utils.Menu {
 
 	menu := utils.NewMenu(user.GetName())
 	menu.AddAction("l", "Logout")
-	menu.AddAction("a", "Admin")
+	if canAdmin(user) {
+		menu.AddAction("a", "Admin")
+	}
 	menu.AddAction("n", "New character")
 	if len(chars) > 0 {
 		menu.AddAction("d", "Delete character")
@@ -214,6 +235,23 @@ func deleteMenu(user *database.User) *utils.Menu {
 	return menu
 }
 
+// canAdmin returns true if the user is allowed in the admin menu. Until
+// someone has been made an admin everyone is, so that the first one can be
+// chosen.
+func canAdmin(user *database.User) bool {
+	if user.HasRole(database.RoleAdmin) {
+		return true
+	}
+
+	for _, other := range model.GetUsers() {
+		if other.HasRole(database.RoleAdmin) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func adminMenu() *utils.Menu {
 	menu := utils.NewMenu("Admin")
 	menu.AddAction("u", "Users")
@@ -250,6 +288,7 @@ func userSpecificMenu(user *database.User) *utils.Menu {
 
 	menu := utils.NewMenu("User: " + user.GetName() + " " + suffix)
 	menu.AddAction("d", "Delete")
+	menu.AddAction("r", fmt.Sprintf("Role - %s", user.GetRole()))
 
 	if user.Online() {
 		menu.AddAction("w", "Watch")
@@ -313,6 +352,13 @@ func handleConnection(conn *wrappedConnection) {
 
 			conn.telnet.DoWindowSize()
 			conn.telnet.DoTerminalType()
+			conn.telnet.WillGMCP()
+
+			conn.telnet.Negotiate(func(verb telnet.TelnetCode, option telnet.TelnetCode) {
+				if option == telnet.GMCP {
+					user.SetGMCP(verb == telnet.DO)
+				}
+			})
 
 			conn.telnet.Listen(func(code telnet.TelnetCode, data []byte) {
 				switch code {
@@ -365,6 +411,16 @@ func handleConnection(conn *wrappedConnection) {
 										} else if choice == "d" {
 											model.DeleteUserId(userId)
 											break
+										} else if choice == "r" {
+											roleMenu := utils.NewMenu("Role")
+											for i, role := range database.Roles {
+												roleMenu.AddAction(strconv.Itoa(i+1), string(role))
+											}
+
+											choice, _ = roleMenu.Exec(conn, user.GetColorMode())
+											if index, err := strconv.Atoi(choice); err == nil && index > 0 && index <= len(database.Roles) {
+												model.GetUser(userId).SetRole(database.Roles[index-1])
+											}
 										} else if choice == "w" {
 											userToWatch := model.GetUser(userId)
 
@@ -1,10 +1,14 @@
 package session
 
 import (
+	"errors"
+	"fmt"
 	"kmud/database"
 	"kmud/model"
 	"kmud/utils"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type actionHandler struct {
@@ -33,13 +37,64 @@ func (ah *actionHandler) handleAction(action string, args []string) {
 		}
 	}
 
+	command := strings.Join(append([]string{action}, args...), " ")
+
+	if _, found := ah.session.room.GetNamedExit(command); found {
+		newRoom, err := model.TakeNamedExit(ah.session.player, command)
+		if err == nil {
+			ah.session.room = newRoom
+			ah.session.printRoom()
+		} else {
+			ah.session.printError(err.Error())
+		}
+
+		return
+	}
+
+	if model.RunCommandTriggers(ah.session.player, command) {
+		return
+	}
+
 	found := utils.FindAndCallMethod(ah, action, args)
 
-	if !found {
+	if !found && !ah.useSkill(action, args) && !ah.useChannel(action, args) && !model.RunCommandScripts(ah.session.player, command) {
 		ah.session.printError("You can't do that")
 	}
 }
 
+// useSkill uses the active skill named by the action, if the player knows
+// one. Returns false if they don't.
+func (ah *actionHandler) useSkill(action string, args []string) bool {
+	skill := database.FindSkill(action)
+
+	if skill == nil || skill.Type != database.SkillActive || ah.session.player.GetSkill(skill.Name) == 0 {
+		return false
+	}
+
+	var target *database.Character
+
+	if len(args) > 0 {
+		charList := model.CharactersIn(ah.session.room)
+		index := utils.BestMatch(strings.Join(args, " "), database.CharacterNames(charList))
+
+		if index == -1 {
+			ah.session.printError("Not found")
+			return true
+		} else if index == -2 {
+			ah.session.printError("Which one do you mean?")
+			return true
+		}
+
+		target = charList[index]
+	}
+
+	if err := model.UseSkill(ah.session.player, skill.Name, target); err != nil {
+		ah.session.printError(err.Error())
+	}
+
+	return true
+}
+
 func (ah *actionHandler) L(args []string) {
 	ah.Look(args)
 }
@@ -59,7 +114,7 @@ func (ah *actionHandler) Look(args []string) {
 			} else if index != -1 {
 				ah.session.printLine("Looking at: %s", charList[index].GetName())
 			} else {
-				itemList := model.ItemsIn(ah.session.room)
+				itemList := append(model.ItemsIn(ah.session.room), model.GetItems(ah.session.player.GetItemIds())...)
 				index = utils.BestMatch(args[0], database.ItemNames(itemList))
 
 				if index == -1 {
@@ -67,13 +122,14 @@ func (ah *actionHandler) Look(args []string) {
 				} else if index == -2 {
 					ah.session.printError("Which one do you mean?")
 				} else {
-					ah.session.printLine("Looking at: %s", itemList[index].GetName())
+					ah.session.printItem(itemList[index])
 				}
 			}
 		} else {
-			if ah.session.room.HasExit(arg) {
-				loc := ah.session.room.NextLocation(arg)
-				roomToSee := model.GetRoomByLocation(loc, ah.session.currentZone())
+			if exit, _ := ah.session.room.GetExit(arg); !exit.IsOpen() {
+				ah.session.printLine("The door is closed")
+			} else if ah.session.room.HasExit(arg) {
+				roomToSee := model.ExitDestination(ah.session.room, arg)
 				if roomToSee != nil {
 					area := model.GetArea(roomToSee.GetAreaId())
 					ah.session.printLine(roomToSee.ToString(model.PlayersIn(roomToSee, nil),
@@ -93,6 +149,11 @@ func (ah *actionHandler) A(args []string) {
 }
 
 func (ah *actionHandler) Attack(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: attack <name>")
+		return
+	}
+
 	charList := model.CharactersIn(ah.session.room)
 	index := utils.BestMatch(args[0], database.CharacterNames(charList))
 
@@ -105,8 +166,37 @@ func (ah *actionHandler) Attack(args []string) {
 		if defender.GetId() == ah.session.player.GetId() {
 			ah.session.printError("You can't attack yourself")
 		} else {
-			model.StartFight(ah.session.player, defender)
+			err := model.StartFight(ah.session.player, defender)
+
+			if err != nil {
+				ah.session.printError(err.Error())
+			}
+		}
+	}
+}
+
+func (ah *actionHandler) Flee(args []string) {
+	direction := database.DirectionNone
+
+	if len(args) == 1 {
+		direction = database.StringToDirection(args[0])
+
+		if direction == database.DirectionNone {
+			ah.session.printError("Usage: flee [direction]")
+			return
 		}
+	} else if len(args) > 1 {
+		ah.session.printError("Usage: flee [direction]")
+		return
+	}
+
+	newRoom, err := model.Flee(ah.session.player, direction)
+
+	if err == nil {
+		ah.session.room = newRoom
+		ah.session.printRoom()
+	} else {
+		ah.session.printError(err.Error())
 	}
 }
 
@@ -130,13 +220,81 @@ func (ah *actionHandler) Talk(args []string) {
 		ah.session.printError("Which one do you mean?")
 	} else {
 		npc := npcList[index]
-		ah.session.printLine(npc.PrettyConversation())
+
+		if model.Talk(ah.session.player, npc) {
+			return
+		}
+
+		if len(npc.GetDialogue()) == 0 {
+			ah.session.printLine(npc.PrettyConversation())
+		} else {
+			runDialogue(ah.session, npc)
+		}
+	}
+}
+
+func (ah *actionHandler) Ask(args []string) {
+	askUsage := func() {
+		ah.session.printError("Usage: ask <NPC name> about <topic>")
+	}
+
+	if len(args) < 2 {
+		askUsage()
+		return
+	}
+
+	topic := args[1:]
+	if topic[0] == "about" {
+		topic = topic[1:]
+	}
+
+	if len(topic) == 0 {
+		askUsage()
+		return
+	}
+
+	npcList := model.NpcsIn(ah.session.room)
+	index := utils.BestMatch(args[0], database.CharacterNames(npcList))
+
+	if index == -1 {
+		ah.session.printError("Not found")
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else {
+		npc := npcList[index]
+		response := model.AskAbout(ah.session.player, npc, strings.Join(topic, " "))
+
+		if response == "" {
+			ah.session.printLine("%s has nothing to say about that", npc.GetName())
+		} else {
+			ah.session.printLine(npc.PrettySay(response))
+		}
 	}
 }
 
 func (ah *actionHandler) Drop(args []string) {
 	dropUsage := func() {
 		ah.session.printError("Usage: drop <item name>")
+		ah.session.printError("       drop <amount> coins")
+	}
+
+	if len(args) == 2 && isCoinsWord(args[1]) {
+		amount, err := strconv.Atoi(args[0])
+
+		if err != nil {
+			dropUsage()
+			return
+		}
+
+		err = model.DropCash(ah.session.player, ah.session.room, amount)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("Dropped %v coins", amount)
+		}
+
+		return
 	}
 
 	if len(args) != 1 {
@@ -153,9 +311,12 @@ func (ah *actionHandler) Drop(args []string) {
 		ah.session.printError("Which one do you mean?")
 	} else {
 		item := characterItems[index]
-		ah.session.player.RemoveItem(item)
-		ah.session.room.AddItem(item)
-		ah.session.printLine("Dropped %s", item.GetName())
+
+		if err := model.DropItem(ah.session.player, item, ah.session.room); err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("Dropped %s", item.GetName())
+		}
 	}
 }
 
@@ -177,7 +338,12 @@ func (ah *actionHandler) G(args []string) {
 
 func (ah *actionHandler) Pickup(args []string) {
 	takeUsage := func() {
-		ah.session.printError("Usage: take <item name>")
+		ah.session.printError("Usage: take <item name> [from <container>]")
+	}
+
+	if len(args) == 3 && args[1] == "from" {
+		ah.takeFrom(args[0], args[2])
+		return
 	}
 
 	if len(args) != 1 {
@@ -194,34 +360,1062 @@ func (ah *actionHandler) Pickup(args []string) {
 		ah.session.printError("Item %s not found", args[0])
 	} else {
 		item := itemsInRoom[index]
-		ah.session.player.AddItem(item)
-		ah.session.room.RemoveItem(item)
-		ah.session.printLine("Picked up %s", item.GetName())
+		cash := item.GetCash()
+		err := model.PickupItem(ah.session.player, item, ah.session.room)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else if model.IsCoins(item) {
+			ah.session.printLine("Picked up %v coins", cash)
+		} else {
+			ah.session.printLine("Picked up %s", item.GetName())
+		}
 	}
 }
 
-func (ah *actionHandler) I(args []string) {
-	ah.Inventory(args)
+// isCoinsWord returns true if the word refers to money
+func isCoinsWord(word string) bool {
+	switch strings.ToLower(word) {
+	case "coin", "coins", "cash", "monies":
+		return true
+	}
+	return false
 }
 
-func (ah *actionHandler) Inv(args []string) {
-	ah.Inventory(args)
+func (ah *actionHandler) Give(args []string) {
+	giveUsage := func() {
+		ah.session.printError("Usage: give <amount> <character name>")
+	}
+
+	if len(args) == 3 && isCoinsWord(args[1]) {
+		args = []string{args[0], args[2]}
+	}
+
+	if len(args) != 2 {
+		giveUsage()
+		return
+	}
+
+	amount, err := strconv.Atoi(args[0])
+
+	if err != nil {
+		giveUsage()
+		return
+	}
+
+	charList := model.CharactersIn(ah.session.room)
+	index := utils.BestMatch(args[1], database.CharacterNames(charList))
+
+	if index == -1 {
+		ah.session.printError("Not found")
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else {
+		receiver := charList[index]
+		err := model.GiveCash(ah.session.player, receiver, amount)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("You give %v coins to %s", amount, receiver.GetName())
+		}
+	}
 }
 
-func (ah *actionHandler) Inventory(args []string) {
-	itemIds := ah.session.player.GetItemIds()
+func (ah *actionHandler) Trade(args []string) {
+	tradeUsage := func() {
+		ah.session.printError("Usage: trade <character name> | offer <item>|<amount> coins | withdraw <item>|coins | show | accept | cancel")
+	}
 
-	if len(itemIds) == 0 {
-		ah.session.printLine("You aren't carrying anything")
+	if len(args) == 0 {
+		tradeUsage()
+		return
+	}
+
+	player := ah.session.player
+	trade := model.GetTrade(player)
+
+	subcommand := strings.ToLower(args[0])
+
+	switch subcommand {
+	case "offer", "withdraw", "show", "accept", "cancel":
+		if trade == nil {
+			ah.session.printError("You aren't trading with anyone")
+			return
+		}
+	}
+
+	switch subcommand {
+	case "offer":
+		if len(args) == 3 && isCoinsWord(args[2]) {
+			amount, err := strconv.Atoi(args[1])
+			if err != nil {
+				tradeUsage()
+				return
+			}
+
+			err = trade.OfferCash(player, amount)
+			if err != nil {
+				ah.session.printError(err.Error())
+			} else {
+				ah.session.printLine("You offer %v coins", amount)
+			}
+		} else if len(args) > 1 {
+			item := ah.findCarriedItem(strings.Join(args[1:], " "))
+			if item == nil {
+				return
+			}
+
+			err := trade.OfferItem(player, item)
+			if err != nil {
+				ah.session.printError(err.Error())
+			} else {
+				ah.session.printLine("You offer %s", item.GetName())
+			}
+		} else {
+			tradeUsage()
+		}
+	case "withdraw":
+		if len(args) == 2 && isCoinsWord(args[1]) {
+			trade.OfferCash(player, 0)
+			ah.session.printLine("You withdraw your coins")
+		} else if len(args) > 1 {
+			items, _ := trade.Offer(player)
+			index := utils.BestMatch(strings.Join(args[1:], " "), database.ItemNames(items))
+
+			if index == -1 {
+				ah.session.printError("You aren't offering that")
+			} else if index == -2 {
+				ah.session.printError("Which one do you mean?")
+			} else {
+				trade.WithdrawItem(player, items[index])
+				ah.session.printLine("You withdraw %s", items[index].GetName())
+			}
+		} else {
+			tradeUsage()
+		}
+	case "show":
+		ah.session.printLine("%s", trade.Describe(player))
+	case "accept":
+		_, err := trade.Confirm(player)
+		if err == nil && model.GetTrade(player) != nil {
+			ah.session.printLine("You accept the trade. Waiting for %s", trade.Partner(player).GetName())
+		}
+	case "cancel":
+		model.CancelTrade(player, player.GetName()+" cancelled the trade")
+	default:
+		if len(args) != 1 {
+			tradeUsage()
+			return
+		}
+
+		players := model.PlayersIn(ah.session.room, player)
+		index := utils.BestMatch(args[0], database.CharacterNames(players))
+
+		if index == -1 {
+			ah.session.printError("Not found")
+		} else if index == -2 {
+			ah.session.printError("Which one do you mean?")
+		} else {
+			_, err := model.StartTrade(player, players[index])
+			if err != nil {
+				ah.session.printError(err.Error())
+			} else {
+				ah.session.printLine("You start trading with %s", players[index].GetName())
+			}
+		}
+	}
+}
+
+// findShopkeeper returns the shopkeeper in the player's room, printing an
+// error and returning nil if there isn't one
+func (ah *actionHandler) findShopkeeper() *database.Character {
+	shopkeeper := model.Shopkeeper(ah.session.room)
+
+	if shopkeeper == nil {
+		ah.session.printError("There is no shopkeeper here")
+	}
+
+	return shopkeeper
+}
+
+func (ah *actionHandler) List(args []string) {
+	shopkeeper := ah.findShopkeeper()
+
+	if shopkeeper == nil {
+		return
+	}
+
+	stock := model.ShopStock(shopkeeper)
+
+	if len(stock) == 0 {
+		ah.session.printLine("%s has nothing for sale", shopkeeper.GetName())
+		return
+	}
+
+	ah.session.printLine("%s has for sale:", shopkeeper.GetName())
+
+	for _, item := range stock {
+		ah.session.printLine("  %-30s %6v coins", item.GetName(), model.Price(shopkeeper, item))
+	}
+}
+
+func (ah *actionHandler) Buy(args []string) {
+	if len(args) == 0 {
+		ah.session.printError("Usage: buy <item name>")
+		return
+	}
+
+	shopkeeper := ah.findShopkeeper()
+
+	if shopkeeper == nil {
+		return
+	}
+
+	stock := model.ShopStock(shopkeeper)
+	index := utils.BestMatch(strings.Join(args, " "), database.ItemNames(stock))
+
+	if index == -1 {
+		ah.session.printError("%s doesn't sell that", shopkeeper.GetName())
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
 	} else {
-		var itemNames []string
-		for _, item := range model.GetItems(itemIds) {
-			itemNames = append(itemNames, item.GetName())
+		item := stock[index]
+		price, err := model.Buy(ah.session.player, shopkeeper, item)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("You buy %s for %v coins", item.GetName(), price)
 		}
-		ah.session.printLine("You are carrying: %s", strings.Join(itemNames, ", "))
 	}
+}
 
-	ah.session.printLine("Cash: %v", ah.session.player.GetCash())
+func (ah *actionHandler) Sell(args []string) {
+	if len(args) == 0 {
+		ah.session.printError("Usage: sell <item name>")
+		return
+	}
+
+	shopkeeper := ah.findShopkeeper()
+
+	if shopkeeper == nil {
+		return
+	}
+
+	item := ah.findCarriedItem(strings.Join(args, " "))
+
+	if item == nil {
+		return
+	}
+
+	amount, err := model.Sell(ah.session.player, shopkeeper, item)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	} else {
+		ah.session.printLine("You sell %s for %v coins", item.GetName(), amount)
+	}
+}
+
+func (ah *actionHandler) Value(args []string) {
+	if len(args) == 0 {
+		ah.session.printError("Usage: value <item name>")
+		return
+	}
+
+	shopkeeper := ah.findShopkeeper()
+
+	if shopkeeper == nil {
+		return
+	}
+
+	item := ah.findCarriedItem(strings.Join(args, " "))
+
+	if item == nil {
+		return
+	}
+
+	offer, err := model.Offer(shopkeeper, item)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	} else {
+		ah.session.printLine("%s would pay %v coins for %s", shopkeeper.GetName(), offer, item.GetName())
+	}
+}
+
+// findContainer finds the item in the room or the player's inventory that
+// best matches the given name, printing an error and returning nil if there
+// isn't one
+func (ah *actionHandler) findContainer(name string) *database.Item {
+	items := append(model.ItemsIn(ah.session.room), model.GetItems(ah.session.player.GetItemIds())...)
+	index := utils.BestMatch(name, database.ItemNames(items))
+
+	if index == -1 {
+		ah.session.printError("Container %s not found", name)
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else {
+		return items[index]
+	}
+
+	return nil
+}
+
+func (ah *actionHandler) takeFrom(itemName string, containerName string) {
+	container := ah.findContainer(containerName)
+
+	if container == nil {
+		return
+	}
+
+	if container.IsLocked() {
+		ah.session.printError("%s is locked", container.GetName())
+		return
+	}
+
+	contents := model.GetItems(container.GetItemIds())
+	index := utils.BestMatch(itemName, database.ItemNames(contents))
+
+	if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else if index == -1 {
+		ah.session.printError("Item %s not found in %s", itemName, container.GetName())
+	} else {
+		item := contents[index]
+		cash := item.GetCash()
+		err := model.TakeItemFrom(ah.session.player, item, container)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else if model.IsCoins(item) {
+			ah.session.printLine("Took %v coins from %s", cash, container.GetName())
+		} else {
+			ah.session.printLine("Took %s from %s", item.GetName(), container.GetName())
+		}
+	}
+}
+
+func (ah *actionHandler) Put(args []string) {
+	if len(args) != 3 || (args[1] != "in" && args[1] != "into") {
+		ah.session.printError("Usage: put <item name> in <container>")
+		return
+	}
+
+	item := ah.findCarriedItem(args[0])
+
+	if item == nil {
+		return
+	}
+
+	container := ah.findContainer(args[2])
+
+	if container == nil {
+		return
+	}
+
+	err := model.PutItem(ah.session.player, item, container)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	} else {
+		ah.session.printLine("Put %s in %s", item.GetName(), container.GetName())
+	}
+}
+
+// doorAction runs one of the door functions against the direction given in
+// args, returning false if args don't name a direction
+func (ah *actionHandler) doorAction(args []string, action func(*database.Character, database.Direction) error) bool {
+	if len(args) != 1 {
+		return false
+	}
+
+	direction := database.StringToDirection(args[0])
+
+	if direction == database.DirectionNone {
+		return false
+	}
+
+	err := action(ah.session.player, direction)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	}
+
+	return true
+}
+
+func (ah *actionHandler) Open(args []string) {
+	if !ah.doorAction(args, model.OpenDoor) {
+		ah.session.printError("Usage: open <direction>")
+	}
+}
+
+func (ah *actionHandler) Close(args []string) {
+	if !ah.doorAction(args, model.CloseDoor) {
+		ah.session.printError("Usage: close <direction>")
+	}
+}
+
+func (ah *actionHandler) Pick(args []string) {
+	if !ah.doorAction(args, model.PickDoor) {
+		ah.session.printError("Usage: pick <direction>")
+	}
+}
+
+func (ah *actionHandler) Lock(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: lock <container>|<direction>")
+		return
+	}
+
+	if ah.doorAction(args, model.LockDoor) {
+		return
+	}
+
+	container := ah.findContainer(args[0])
+
+	if container != nil {
+		err := model.SetItemLocked(ah.session.player, container, true)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("You lock %s", container.GetName())
+		}
+	}
+}
+
+func (ah *actionHandler) Unlock(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: unlock <container>|<direction>")
+		return
+	}
+
+	if ah.doorAction(args, model.UnlockDoor) {
+		return
+	}
+
+	container := ah.findContainer(args[0])
+
+	if container != nil {
+		err := model.SetItemLocked(ah.session.player, container, false)
+
+		if err != nil {
+			ah.session.printError(err.Error())
+		} else {
+			ah.session.printLine("You unlock %s", container.GetName())
+		}
+	}
+}
+
+func (ah *actionHandler) I(args []string) {
+	ah.Inventory(args)
+}
+
+func (ah *actionHandler) Inv(args []string) {
+	ah.Inventory(args)
+}
+
+func (ah *actionHandler) Inventory(args []string) {
+	itemIds := ah.session.player.GetItemIds()
+
+	if len(itemIds) == 0 {
+		ah.session.printLine("You aren't carrying anything")
+	} else {
+		var itemNames []string
+		for _, item := range model.GetItems(itemIds) {
+			if slot, equipped := ah.session.player.EquippedSlot(item); equipped {
+				itemNames = append(itemNames, fmt.Sprintf("%s (%s)", item.GetName(), slot))
+			} else {
+				itemNames = append(itemNames, item.GetName())
+			}
+		}
+		ah.session.printLine("You are carrying: %s", strings.Join(itemNames, ", "))
+	}
+
+	ah.session.printLine("Cash: %v", ah.session.player.GetCash())
+}
+
+func (ah *actionHandler) Score(args []string) {
+	player := ah.session.player
+
+	class := player.GetClass()
+	if class == "" {
+		class = "None"
+	}
+
+	ah.session.printLine(utils.Colorize(utils.ColorYellow, player.GetName()))
+	ah.session.printLine("  Class:      %s", class)
+	ah.session.printLine("  Position:   %s", player.GetPosition())
+	ah.session.printLine("  Health:     %v/%v", player.GetHitPoints(), player.GetHealth())
+	ah.session.printLine("  Mana:       %v/%v", player.GetMana(), player.GetMaxMana())
+	ah.session.printLine("  Movement:   %v/%v", player.GetMovement(), player.GetMaxMovement())
+	ah.session.printLine("  Strength:   %v", model.Strength(player))
+	ah.session.printLine("  Dexterity:  %v", model.Dexterity(player))
+	ah.session.printLine("  Experience: %v", player.GetExperience())
+	ah.session.printLine("  Cash:       %v", player.GetCash())
+
+	statuses := player.GetStatuses()
+
+	if len(statuses) > 0 {
+		ah.session.printLine("  Affected by:")
+	}
+
+	for _, active := range statuses {
+		name := active.Name
+		if active.Stacks > 1 {
+			name = fmt.Sprintf("%s x%v", name, active.Stacks)
+		}
+
+		remaining := int(active.Expires.Sub(time.Now()).Seconds() + 0.5)
+		ah.session.printLine("    %-20s %v seconds", name, remaining)
+	}
+}
+
+func (ah *actionHandler) Skills(args []string) {
+	skills := model.KnownSkills(ah.session.player)
+
+	if len(skills) == 0 {
+		ah.session.printLine("You don't know any skills")
+		return
+	}
+
+	for _, skill := range skills {
+		line := fmt.Sprintf("  %-16s %3v%%  %-8s", skill.Name, ah.session.player.GetSkill(skill.Name), skill.Type)
+
+		if skill.Type == database.SkillActive {
+			line += fmt.Sprintf("  %v movement", skill.Cost)
+
+			if remaining := model.Cooldown(ah.session.player, skill.Name); remaining > 0 {
+				line += fmt.Sprintf(", ready in %v seconds", int(remaining.Seconds()+0.5))
+			}
+		}
+
+		ah.session.printLine("%s", line)
+	}
+}
+
+func (ah *actionHandler) Rest(args []string) {
+	if err := model.Rest(ah.session.player); err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Sleep(args []string) {
+	if err := model.Sleep(ah.session.player); err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Wake(args []string) {
+	if err := model.Wake(ah.session.player); err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Stand(args []string) {
+	ah.Wake(args)
+}
+
+func (ah *actionHandler) Group(args []string) {
+	groupUsage := func() {
+		ah.session.printError("Usage: group invite <player> | accept | leave | kick <player> | list | loot <rule> | say <message>")
+	}
+
+	if len(args) == 0 {
+		groupUsage()
+		return
+	}
+
+	player := ah.session.player
+	var err error
+
+	switch strings.ToLower(args[0]) {
+	case "invite", "kick":
+		if len(args) != 2 {
+			groupUsage()
+			return
+		}
+
+		other := model.GetCharacterByName(args[1])
+		if other == nil || !other.IsPlayer() {
+			ah.session.printError("Player '%s' not found", args[1])
+			return
+		}
+
+		if strings.ToLower(args[0]) == "invite" {
+			err = model.InviteToParty(player, other)
+			if err == nil {
+				ah.session.printLine("You invite %s to join your party", other.GetName())
+			}
+		} else {
+			err = model.KickFromParty(player, other)
+		}
+	case "accept":
+		err = model.AcceptPartyInvite(player)
+	case "leave":
+		err = model.LeaveParty(player)
+	case "list":
+		ah.partyList()
+	case "loot":
+		rule := model.FindLootRule(strings.Join(args[1:], " "))
+		if rule == "" {
+			ah.session.printError("Loot rules: %s", strings.Join(lootRuleNames(), ", "))
+			return
+		}
+		err = model.SetLootRule(player, rule)
+	case "say":
+		if len(args) < 2 {
+			groupUsage()
+			return
+		}
+		err = model.PartySay(player, strings.Join(args[1:], " "))
+	default:
+		groupUsage()
+	}
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Gtell(args []string) {
+	if len(args) == 0 {
+		ah.session.printError("Usage: gtell <message>")
+		return
+	}
+
+	ah.Group(append([]string{"say"}, args...))
+}
+
+func (ah *actionHandler) partyList() {
+	members := model.PartyMembers(ah.session.player)
+
+	if members == nil {
+		ah.session.printLine("You aren't in a party")
+		return
+	}
+
+	ah.session.printLine(utils.Colorize(utils.ColorYellow, fmt.Sprintf("Party of %s (loot: %s)",
+		members[0].GetName(), model.PartyLootRule(ah.session.player))))
+
+	for _, member := range members {
+		following := ""
+		if member != members[0] && model.Following(member) == members[0] {
+			following = " (following)"
+		}
+
+		room := "elsewhere"
+		if member.GetRoomId() == ah.session.player.GetRoomId() {
+			room = "here"
+		}
+
+		ah.session.printLine("  %-16s %v/%v hp  %s%s", member.GetName(), member.GetHitPoints(), member.GetHealth(), room, following)
+	}
+}
+
+func lootRuleNames() []string {
+	var names []string
+	for _, rule := range model.LootRules {
+		names = append(names, string(rule))
+	}
+	return names
+}
+
+// Follow starts following another character in the room. Without a name the
+// player follows their party leader.
+func (ah *actionHandler) Follow(args []string) {
+	player := ah.session.player
+	var err error
+
+	if len(args) == 0 {
+		if model.PartyLeader(player) != nil {
+			err = model.FollowLeader(player)
+		} else if leader := model.Following(player); leader != nil {
+			ah.session.printLine("You are following %s", leader.GetName())
+		} else {
+			ah.session.printError("Usage: follow <character>")
+		}
+	} else {
+		charList := model.CharactersIn(ah.session.room)
+		index := utils.BestMatch(strings.Join(args, " "), database.CharacterNames(charList))
+
+		if index == -1 {
+			err = errors.New("Not found")
+		} else if index == -2 {
+			err = errors.New("Which one do you mean?")
+		} else if charList[index] == player {
+			err = model.StopFollowing(player)
+		} else {
+			err = model.Follow(player, charList[index])
+		}
+	}
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Unfollow(args []string) {
+	if err := model.StopFollowing(ah.session.player); err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+// useChannel talks on the channel named by the action, e.g. "ooc hello".
+// Returns false if there is no such channel.
+func (ah *actionHandler) useChannel(action string, args []string) bool {
+	channel := database.FindChannel(action)
+
+	if channel == nil {
+		return false
+	}
+
+	if len(args) == 0 {
+		ah.session.printError("Nothing to say")
+	} else if err := model.ChannelSay(ah.session.player, channel, strings.Join(args, " ")); err != nil {
+		ah.session.printError(err.Error())
+	}
+
+	return true
+}
+
+func (ah *actionHandler) Channels(args []string) {
+	ah.Channel([]string{"list"})
+}
+
+func (ah *actionHandler) Channel(args []string) {
+	channelUsage := func() {
+		ah.session.printError("Usage: channel list | join <channel> | leave <channel> | history <channel> | " +
+			"mute <channel> <player> [minutes] | unmute <channel> <player> | ban <channel> <player> | unban <channel> <player>")
+	}
+
+	if len(args) == 0 {
+		channelUsage()
+		return
+	}
+
+	player := ah.session.player
+	subcommand := strings.ToLower(args[0])
+
+	if subcommand == "list" {
+		for i := range database.Channels {
+			channel := &database.Channels[i]
+
+			if !model.ChannelAllowed(player, channel) {
+				continue
+			}
+
+			status := "off"
+			if player.GetChannelMembership(channel).Banned {
+				status = "banned"
+			} else if model.OnChannel(player, channel) {
+				status = "on"
+			}
+
+			ah.session.printLine("  %s %s", utils.Colorize(channel.Color, fmt.Sprintf("%-10s", channel.Name)), status)
+		}
+		return
+	}
+
+	if len(args) < 2 {
+		channelUsage()
+		return
+	}
+
+	channel := database.FindChannel(args[1])
+	if channel == nil {
+		ah.session.printError("There is no channel called '%s'", args[1])
+		return
+	}
+
+	var target *database.Character
+
+	switch subcommand {
+	case "mute", "unmute", "ban", "unban":
+		if len(args) < 3 {
+			channelUsage()
+			return
+		}
+
+		target = model.GetCharacterByName(args[2])
+		if target == nil {
+			ah.session.printError("Player '%s' not found", args[2])
+			return
+		}
+	}
+
+	var err error
+
+	switch subcommand {
+	case "join":
+		if err = model.JoinChannel(player, channel); err == nil {
+			ah.session.printLine("You join %s", channel.Name)
+		}
+	case "leave":
+		if err = model.LeaveChannel(player, channel); err == nil {
+			ah.session.printLine("You leave %s", channel.Name)
+		}
+	case "history":
+		var history []model.ChannelEvent
+		if history, err = model.ChannelHistory(player, channel); err == nil {
+			for _, event := range history {
+				ah.session.printLine("%s %s", event.Time.Format("15:04"), event.ToString(player))
+			}
+		}
+	case "mute":
+		minutes := 10
+		if len(args) > 3 {
+			if minutes, err = strconv.Atoi(args[3]); err != nil || minutes <= 0 {
+				channelUsage()
+				return
+			}
+		}
+
+		if err = model.MuteOnChannel(player, target, channel, time.Duration(minutes)*time.Minute); err == nil {
+			ah.session.printLine("%s has been muted on %s for %v minutes", target.GetName(), channel.Name, minutes)
+		}
+	case "unmute":
+		if err = model.MuteOnChannel(player, target, channel, 0); err == nil {
+			ah.session.printLine("%s can talk on %s again", target.GetName(), channel.Name)
+		}
+	case "ban", "unban":
+		if err = model.BanFromChannel(player, target, channel, subcommand == "ban"); err == nil {
+			ah.session.printLine("%s has been %sned from %s", target.GetName(), subcommand, channel.Name)
+		}
+	default:
+		channelUsage()
+	}
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Spells(args []string) {
+	spells := model.KnownSpells(ah.session.player)
+
+	if len(spells) == 0 {
+		ah.session.printLine("You don't know any spells")
+		return
+	}
+
+	for _, spell := range spells {
+		ah.session.printLine("  %-16s %3v mana  %s", spell.Name, spell.Mana, spell.Target)
+	}
+}
+
+// Cast takes the name of a spell, optionally followed by the name of who to
+// cast it on. Since spell names can have more than one word the longest
+// leading set of words that names a known spell is used.
+func (ah *actionHandler) Cast(args []string) {
+	if len(args) == 0 {
+		ah.session.printError("Usage: cast <spell> [target]")
+		return
+	}
+
+	var names []string
+	for _, spell := range model.KnownSpells(ah.session.player) {
+		names = append(names, spell.Name)
+	}
+
+	spell := ""
+	for i := len(args); i > 0; i-- {
+		if index := utils.BestMatch(strings.Join(args[:i], " "), names); index >= 0 {
+			spell = names[index]
+			args = args[i:]
+			break
+		}
+	}
+
+	if spell == "" {
+		ah.session.printError("You don't know that spell")
+		return
+	}
+
+	var target *database.Character
+
+	if len(args) > 0 {
+		charList := model.CharactersIn(ah.session.room)
+		index := utils.BestMatch(strings.Join(args, " "), database.CharacterNames(charList))
+
+		if index == -1 {
+			ah.session.printError("Not found")
+			return
+		} else if index == -2 {
+			ah.session.printError("Which one do you mean?")
+			return
+		}
+
+		target = charList[index]
+	}
+
+	if err := model.Cast(ah.session.player, spell, target); err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) Quests(args []string) {
+	var completed []string
+	active := 0
+
+	for _, progress := range ah.session.player.GetQuests() {
+		quest := model.GetQuest(progress.QuestId)
+
+		if quest == nil {
+			continue
+		} else if progress.Complete {
+			completed = append(completed, quest.GetName())
+			continue
+		}
+
+		active++
+		ah.session.printLine(utils.Colorize(utils.ColorYellow, quest.GetName()))
+
+		if description := quest.GetDescription(); description != "" {
+			ah.session.printLine("  %s", description)
+		}
+
+		for i, objective := range quest.GetObjectives() {
+			count := 0
+			if i < len(progress.Counts) {
+				count = progress.Counts[i]
+			}
+
+			ah.session.printLine("  %-30s %v/%v", model.DescribeObjective(objective), count, objective.Count)
+		}
+	}
+
+	if active == 0 {
+		ah.session.printLine("You aren't on any quests")
+	}
+
+	if len(completed) > 0 {
+		ah.session.printLine("Completed: %s", strings.Join(completed, ", "))
+	}
+}
+
+// findCarriedItem finds the item in the player's inventory that best matches
+// the given name, printing an error and returning nil if there isn't one
+func (ah *actionHandler) findCarriedItem(name string) *database.Item {
+	characterItems := model.GetItems(ah.session.player.GetItemIds())
+	index := utils.BestMatch(name, database.ItemNames(characterItems))
+
+	if index == -1 {
+		ah.session.printError("You aren't carrying that")
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else {
+		return characterItems[index]
+	}
+
+	return nil
+}
+
+func (ah *actionHandler) Wear(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: wear <item name>")
+		return
+	}
+
+	item := ah.findCarriedItem(args[0])
+
+	if item == nil {
+		return
+	}
+
+	if item.GetKind() == database.ItemKindWeapon {
+		ah.session.printError("You should wield that")
+		return
+	}
+
+	slot, err := model.Equip(ah.session.player, item)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	} else if slot == database.SlotHeld {
+		ah.session.printLine("You hold %s", item.GetName())
+	} else {
+		ah.session.printLine("You wear %s on your %s", item.GetName(), slot)
+	}
+}
+
+func (ah *actionHandler) Wield(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: wield <weapon name>")
+		return
+	}
+
+	item := ah.findCarriedItem(args[0])
+
+	if item == nil {
+		return
+	}
+
+	if item.GetKind() != database.ItemKindWeapon {
+		ah.session.printError("That isn't a weapon")
+		return
+	}
+
+	_, err := model.Equip(ah.session.player, item)
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	} else {
+		ah.session.printLine("You wield %s", item.GetName())
+	}
+}
+
+func (ah *actionHandler) Remove(args []string) {
+	if len(args) != 1 {
+		ah.session.printError("Usage: remove <item name>")
+		return
+	}
+
+	var equipped []*database.Item
+	for _, slot := range database.EquipmentSlots {
+		item := model.EquippedItem(ah.session.player, slot)
+		if item != nil {
+			equipped = append(equipped, item)
+		}
+	}
+
+	index := utils.BestMatch(args[0], database.ItemNames(equipped))
+
+	if index == -1 {
+		ah.session.printError("You aren't using that")
+	} else if index == -2 {
+		ah.session.printError("Which one do you mean?")
+	} else {
+		item := equipped[index]
+		model.Unequip(ah.session.player, item)
+		ah.session.printLine("You remove %s", item.GetName())
+	}
+}
+
+func (ah *actionHandler) Eq(args []string) {
+	ah.Equipment(args)
+}
+
+func (ah *actionHandler) Equipment(args []string) {
+	for _, slot := range database.EquipmentSlots {
+		name := "nothing"
+		item := model.EquippedItem(ah.session.player, slot)
+		if item != nil {
+			name = item.GetName()
+		}
+
+		ah.session.printLine("%-8s %s", strings.Title(string(slot))+":", name)
+	}
+
+	damage := model.WeaponDamage(ah.session.player)
+	if damage == "" {
+		damage = "bare hands"
+	}
+
+	ah.session.printLine("Armor class: %v, Damage: %s", model.ArmorClass(ah.session.player), damage)
 }
 
 func (ah *actionHandler) Help(args []string) {
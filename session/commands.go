@@ -48,6 +48,9 @@ func specificNpcMenu(npcId bson.ObjectId) *utils.Menu {
 	}
 
 	menu.AddAction("o", fmt.Sprintf("Roaming - %s", roamingState))
+	menu.AddAction("l", "Dialogue and topics")
+	menu.AddAction("h", "Shop")
+	menu.AddAction("x", fmt.Sprintf("Script - %s", scriptName(npc.GetScriptId())))
 	return menu
 }
 
@@ -72,6 +75,86 @@ func specificSpawnMenu(templateId bson.ObjectId) *utils.Menu {
 
 	menu.AddAction("r", "Rename")
 	menu.AddAction("d", "Delete")
+	menu.AddAction("b", fmt.Sprintf("Behavior - %s", template.GetBehavior()))
+	menu.AddAction("f", fmt.Sprintf("Flee below - %v%% health", template.GetFleeHealth()))
+	menu.AddAction("p", fmt.Sprintf("Patrol route - %v rooms", len(template.GetPatrolRoute())))
+	menu.AddAction("l", "Dialogue and topics")
+	menu.AddAction("o", fmt.Sprintf("Loot table - %v items", len(template.GetLootTable())))
+	menu.AddAction("h", "Shop")
+	menu.AddAction("x", fmt.Sprintf("Script - %s", scriptName(template.GetScriptId())))
+
+	return menu
+}
+
+func behaviorMenu() *utils.Menu {
+	menu := utils.NewMenu("Behavior")
+
+	for i, behavior := range database.Behaviors {
+		menu.AddAction(strconv.Itoa(i+1), string(behavior))
+	}
+
+	return menu
+}
+
+func patrolRouteMenu(route []bson.ObjectId) *utils.Menu {
+	menu := utils.NewMenu("Patrol Route")
+
+	for i, roomId := range route {
+		name := "<Deleted room>"
+		room := model.GetRoom(roomId)
+		if room != nil {
+			name = fmt.Sprintf("%s %v", room.GetTitle(), room.GetLocation())
+		}
+
+		menu.AddActionData(i+1, name, roomId)
+	}
+
+	menu.AddAction("a", "Add current room")
+	menu.AddAction("c", "Clear")
+
+	return menu
+}
+
+func spawnersMenu(title string, spawners []*database.Spawner) *utils.Menu {
+	menu := utils.NewMenu(title)
+
+	menu.AddAction("n", "New")
+
+	for i, spawner := range spawners {
+		index := i + 1
+
+		name := "<No template>"
+		template := model.GetCharacter(spawner.GetTemplateId())
+		if template != nil {
+			name = template.GetName()
+		}
+
+		text := fmt.Sprintf("%s (max %v, every %vs)", name, spawner.GetCount(), spawner.GetInterval())
+		menu.AddActionData(index, text, spawner.GetId())
+	}
+
+	return menu
+}
+
+func specificSpawnerMenu(spawner *database.Spawner) *utils.Menu {
+	name := "<No template>"
+	template := model.GetCharacter(spawner.GetTemplateId())
+	if template != nil {
+		name = template.GetName()
+	}
+
+	menu := utils.NewMenu("Spawner")
+
+	roamingState := "Off"
+	if spawner.GetRoaming() {
+		roamingState = "On"
+	}
+
+	menu.AddAction("t", fmt.Sprintf("Template - %s", name))
+	menu.AddAction("c", fmt.Sprintf("Count - %v", spawner.GetCount()))
+	menu.AddAction("i", fmt.Sprintf("Interval - %v seconds", spawner.GetInterval()))
+	menu.AddAction("o", fmt.Sprintf("Roaming - %s", roamingState))
+	menu.AddAction("d", "Delete")
 
 	return menu
 }
@@ -126,6 +209,97 @@ func (ch *commandHandler) quickRoom(command string) {
 	ch.session.room.SetExitEnabled(dir.Opposite(), true)
 }
 
+func doorMenu(exit database.Exit) *utils.Menu {
+	onOrOff := func(on bool) string {
+		if on {
+			return "On"
+		}
+		return "Off"
+	}
+
+	menu := utils.NewMenu("Door")
+
+	menu.AddAction("d", fmt.Sprintf("Door - %s", onOrOff(exit.HasDoor())))
+
+	if exit.HasDoor() {
+		menu.AddAction("s", fmt.Sprintf("State - %s", exit.Door))
+
+		key := exit.Key
+		if key == "" {
+			key = "<no lock>"
+		}
+
+		menu.AddAction("k", fmt.Sprintf("Key code - %s", key))
+		menu.AddAction("p", fmt.Sprintf("Pick difficulty - %v%%", exit.Difficulty))
+	}
+
+	menu.AddAction("h", fmt.Sprintf("Hidden - %s", onOrOff(exit.Hidden)))
+
+	return menu
+}
+
+// Door edits the door on one of the current room's exits. Changes to the door
+// are mirrored on the other side of the exit.
+func (ch *commandHandler) Door(args []string) {
+	if len(args) != 1 {
+		ch.session.printError("Usage: /door <direction>")
+		return
+	}
+
+	direction := database.StringToDirection(args[0])
+
+	if direction == database.DirectionNone || !ch.session.room.HasExit(direction) {
+		ch.session.printError("There is no exit in that direction")
+		return
+	}
+
+	for {
+		exit, _ := ch.session.room.GetExit(direction)
+		choice, _ := ch.session.execMenu(doorMenu(exit))
+
+		switch choice {
+		case "":
+			return
+		case "d":
+			if exit.HasDoor() {
+				exit.Door = database.DoorNone
+				exit.Key = ""
+				exit.Difficulty = 0
+			} else {
+				exit.Door = database.DoorClosed
+				exit.Difficulty = database.DefaultPickDifficulty
+			}
+		case "s":
+			switch exit.Door {
+			case database.DoorOpen:
+				exit.Door = database.DoorClosed
+			case database.DoorClosed:
+				if exit.HasLock() {
+					exit.Door = database.DoorLocked
+				} else {
+					exit.Door = database.DoorOpen
+				}
+			case database.DoorLocked:
+				exit.Door = database.DoorOpen
+			}
+		case "k":
+			exit.Key = ch.session.getUserInput(CleanUserInput, "Key code (blank to remove the lock): ")
+			if exit.Key == "" && exit.IsLocked() {
+				exit.Door = database.DoorClosed
+			}
+		case "p":
+			difficulty, ok := getNumber(ch, "Percent chance of failing to pick the lock: ")
+			if ok && difficulty <= 100 {
+				exit.Difficulty = difficulty
+			}
+		case "h":
+			exit.Hidden = !exit.Hidden
+		}
+
+		model.SetExit(ch.session.room, direction, exit)
+	}
+}
+
 func (ch *commandHandler) Loc(args []string) {
 	ch.Location(args)
 }
@@ -141,6 +315,8 @@ func (ch *commandHandler) Room(args []string) {
 	menu.AddAction("d", "Description")
 	menu.AddAction("e", "Exits")
 	menu.AddAction("a", "Area")
+	menu.AddAction("s", "Spawners")
+	menu.AddAction("x", "Script")
 
 	for {
 		choice, _ := ch.session.execMenu(menu)
@@ -187,6 +363,8 @@ func (ch *commandHandler) Room(args []string) {
 					}
 				}
 			}
+		case "x":
+			attachScript(ch, ch.session.room)
 		case "a":
 			menu := utils.NewMenu("Change Area")
 			menu.AddAction("n", "None")
@@ -207,6 +385,15 @@ func (ch *commandHandler) Room(args []string) {
 			default:
 				ch.session.room.SetAreaId(areaId)
 			}
+		case "s":
+			room := ch.session.room
+			editSpawners(ch, "Room Spawners",
+				func() []*database.Spawner {
+					return model.RoomSpawners(room)
+				},
+				func(template *database.Character) {
+					model.CreateRoomSpawner(template, room)
+				})
 		}
 	}
 }
@@ -282,6 +469,11 @@ func (ch *commandHandler) Map(args []string) {
 func (ch *commandHandler) Zone(args []string) {
 	if len(args) == 0 {
 		ch.session.printLine("Current zone: " + utils.Colorize(utils.ColorBlue, ch.session.currentZone().GetName()))
+
+		recallRoom := model.GetRoom(ch.session.currentZone().GetRecallRoomId())
+		if recallRoom != nil {
+			ch.session.printLine("Recall room: %s %v", recallRoom.GetTitle(), recallRoom.GetLocation())
+		}
 	} else if len(args) == 1 {
 		if args[0] == "list" {
 			ch.session.printLineColor(utils.ColorBlue, "Zones")
@@ -289,8 +481,11 @@ func (ch *commandHandler) Zone(args []string) {
 			for _, zone := range model.GetZones() {
 				ch.session.printLine(zone.GetName())
 			}
+		} else if args[0] == "recall" {
+			ch.session.currentZone().SetRecallRoomId(ch.session.room.GetId())
+			ch.session.printLine("Recall room set")
 		} else {
-			ch.session.printError("Usage: /zone [list|rename <name>|new <name>]")
+			ch.session.printError("Usage: /zone [list|recall|rename <name>|new <name>]")
 		}
 	} else if len(args) == 2 {
 		if args[0] == "rename" {
@@ -326,8 +521,12 @@ func (ch *commandHandler) B(args []string) {
 	ch.Broadcast(args)
 }
 
+// Broadcast sends a message to everyone in the game. Only admins can use it,
+// everyone else has the chat channels.
 func (ch *commandHandler) Broadcast(args []string) {
-	if len(args) == 0 {
+	if !ch.session.user.HasRole(database.RoleAdmin) {
+		ch.session.printError("Only admins can broadcast, try the ooc channel instead")
+	} else if len(args) == 0 {
 		ch.session.printError("Nothing to say")
 	} else {
 		model.BroadcastMessage(ch.session.player, strings.Join(args, " "))
@@ -373,12 +572,116 @@ func (ch *commandHandler) Whisper(args []string) {
 	}
 
 	if !targetChar.IsOnline() {
-		ch.session.printError("Player '%s' is not online", targetChar.GetName())
+		ch.session.printError("Player '%s' is not online, use 'mail send %s' instead", targetChar.GetName(), targetChar.GetName())
 		return
 	}
 
 	message := strings.Join(args[1:], " ")
-	model.Tell(ch.session.player, targetChar, message)
+	if err := model.Tell(ch.session.player, targetChar, message); err != nil {
+		ch.session.printError(err.Error())
+	}
+}
+
+// findPlayer looks up a player by name, printing an error if there isn't one
+func (ch *commandHandler) findPlayer(name string) *database.Character {
+	player := model.GetCharacterByName(name)
+
+	if player == nil || !player.IsPlayer() {
+		ch.session.printError("Player '%s' not found", name)
+		return nil
+	}
+
+	return player
+}
+
+// listPlayers prints the names of the characters with the given IDs
+func (ch *commandHandler) listPlayers(title string, ids []bson.ObjectId) {
+	var names []string
+
+	for _, id := range ids {
+		if char := model.GetCharacter(id); char != nil {
+			names = append(names, char.GetName())
+		}
+	}
+
+	if len(names) == 0 {
+		names = []string{"Nobody"}
+	}
+
+	ch.session.printLine("%s: %s", title, strings.Join(names, ", "))
+}
+
+// Ignore toggles whether the player sees tells, says, emotes and channel
+// messages from someone. Without a name the players being ignored are listed.
+func (ch *commandHandler) Ignore(args []string) {
+	player := ch.session.player
+
+	if len(args) == 0 {
+		ch.listPlayers("Ignoring", player.GetIgnoring())
+		return
+	}
+
+	other := ch.findPlayer(args[0])
+
+	if other == nil {
+		return
+	} else if other == player {
+		ch.session.printError("You can't ignore yourself")
+	} else if player.IsIgnoring(other) {
+		player.SetIgnoring(other, false)
+		ch.session.printLine("You are no longer ignoring %s", other.GetName())
+	} else {
+		player.SetIgnoring(other, true)
+		ch.session.printLine("You are now ignoring %s", other.GetName())
+	}
+}
+
+// Friend toggles whether someone is on the player's friends list, which
+// decides who can send them tells when they only accept tells from friends
+func (ch *commandHandler) Friend(args []string) {
+	player := ch.session.player
+
+	if len(args) == 0 {
+		ch.listPlayers("Friends", player.GetFriends())
+		return
+	}
+
+	other := ch.findPlayer(args[0])
+
+	if other == nil {
+		return
+	} else if other == player {
+		ch.session.printError("You can't add yourself as a friend")
+	} else if player.IsFriend(other) {
+		player.SetFriend(other, false)
+		ch.session.printLine("%s is no longer your friend", other.GetName())
+	} else {
+		player.SetFriend(other, true)
+		ch.session.printLine("%s is now your friend", other.GetName())
+	}
+}
+
+// Tells sets who the player accepts tells from
+func (ch *commandHandler) Tells(args []string) {
+	var names []string
+	for _, preference := range database.TellPreferences {
+		names = append(names, string(preference))
+	}
+
+	if len(args) == 0 {
+		ch.session.printLine("You accept tells from %s", ch.session.player.GetTellPreference())
+		return
+	}
+
+	index := utils.BestMatch(args[0], names)
+
+	if index < 0 {
+		ch.session.printError("Usage: /tells <%s>", strings.Join(names, "|"))
+		return
+	}
+
+	ch.session.player.SetTellPreference(database.TellPreferences[index])
+	ch.session.printLine("You now accept tells from %s", database.TellPreferences[index])
 }
 
 func (ch *commandHandler) Tel(args []string) {
@@ -564,6 +867,94 @@ func getNpcName(ch *commandHandler) string {
 	return name
 }
 
+// chooseNpcTemplate asks the user to pick one of the NPC templates. Returns
+// nil if they didn't choose one.
+func chooseNpcTemplate(ch *commandHandler) *database.Character {
+	menu := utils.NewMenu("Templates")
+
+	for i, template := range model.GetAllNpcTemplates() {
+		index := i + 1
+		menu.AddActionData(index, template.GetName(), template.GetId())
+	}
+
+	choice, templateId := ch.session.execMenu(menu)
+
+	if choice == "" {
+		return nil
+	}
+
+	return model.GetCharacter(templateId)
+}
+
+func getNumber(ch *commandHandler, prompt string) (int, bool) {
+	for {
+		input := ch.session.getUserInput(CleanUserInput, prompt)
+
+		if input == "" {
+			return 0, false
+		}
+
+		number, err := strconv.Atoi(input)
+
+		if err == nil && number >= 0 {
+			return number, true
+		}
+
+		ch.session.printError("Please enter a positive number")
+	}
+}
+
+// editSpawners runs the menus used to edit a set of spawners, either those
+// attached to a room or those attached to an area
+func editSpawners(ch *commandHandler, title string, getSpawners func() []*database.Spawner, create func(*database.Character)) {
+	for {
+		choice, spawnerId := ch.session.execMenu(spawnersMenu(title, getSpawners()))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			template := chooseNpcTemplate(ch)
+			if template != nil {
+				create(template)
+			}
+			continue
+		}
+
+		for {
+			spawner := model.GetSpawner(spawnerId)
+			if spawner == nil {
+				break
+			}
+
+			choice, _ := ch.session.execMenu(specificSpawnerMenu(spawner))
+
+			if choice == "" {
+				break
+			} else if choice == "t" {
+				template := chooseNpcTemplate(ch)
+				if template != nil {
+					spawner.SetTemplateId(template.GetId())
+				}
+			} else if choice == "c" {
+				count, ok := getNumber(ch, "Maximum population: ")
+				if ok {
+					spawner.SetCount(count)
+				}
+			} else if choice == "i" {
+				interval, ok := getNumber(ch, "Seconds between respawns: ")
+				if ok {
+					spawner.SetInterval(interval)
+				}
+			} else if choice == "o" {
+				spawner.SetRoaming(!spawner.GetRoaming())
+			} else if choice == "d" {
+				model.DeleteSpawner(spawner)
+				break
+			}
+		}
+	}
+}
+
 func (ch *commandHandler) Npc(args []string) {
 	for {
 		choice, npcId := ch.session.execMenu(npcMenu(nil))
@@ -602,6 +993,12 @@ func (ch *commandHandler) Npc(args []string) {
 					}
 				} else if choice == "o" {
 					npc.SetRoaming(!npc.GetRoaming())
+				} else if choice == "l" {
+					editConversation(ch, npc)
+				} else if choice == "h" {
+					editShop(ch, npc)
+				} else if choice == "x" {
+					attachScript(ch, npc)
 				} else if choice == "" {
 					break
 				}
@@ -640,6 +1037,43 @@ func (ch *commandHandler) Spawn(args []string) {
 				} else if choice == "d" {
 					model.DeleteCharacterId(templateId)
 					break
+				} else if choice == "b" {
+					choice, _ := ch.session.execMenu(behaviorMenu())
+					index, err := strconv.Atoi(choice)
+					if err == nil && index > 0 && index <= len(database.Behaviors) {
+						template := model.GetCharacter(templateId)
+						template.SetBehavior(database.Behaviors[index-1])
+					}
+				} else if choice == "f" {
+					percent, ok := getNumber(ch, "Flee below health percentage (0 to never flee): ")
+					if ok && percent <= 100 {
+						template := model.GetCharacter(templateId)
+						template.SetFleeHealth(percent)
+					}
+				} else if choice == "l" {
+					editConversation(ch, model.GetCharacter(templateId))
+				} else if choice == "o" {
+					editLoot(ch, model.GetCharacter(templateId))
+				} else if choice == "h" {
+					editShop(ch, model.GetCharacter(templateId))
+				} else if choice == "x" {
+					attachScript(ch, model.GetCharacter(templateId))
+				} else if choice == "p" {
+					template := model.GetCharacter(templateId)
+
+					for {
+						route := template.GetPatrolRoute()
+						choice, _ := ch.session.execMenu(patrolRouteMenu(route))
+
+						if choice == "" {
+							break
+						} else if choice == "a" {
+							newRoute := append([]bson.ObjectId(nil), route...)
+							template.SetPatrolRoute(append(newRoute, ch.session.room.GetId()))
+						} else if choice == "c" {
+							template.SetPatrolRoute(nil)
+						}
+					}
 				}
 			}
 		}
@@ -661,6 +1095,342 @@ func (ch *commandHandler) Create(args []string) {
 	ch.session.printLine("Item created")
 }
 
+func itemMenu(item *database.Item) *utils.Menu {
+	menu := utils.NewMenu(item.GetName())
+
+	menu.AddAction("n", fmt.Sprintf("Name - %s", item.GetName()))
+	menu.AddAction("d", fmt.Sprintf("Description - %s", item.GetDescription()))
+	menu.AddAction("t", fmt.Sprintf("Type - %s", item.GetKind()))
+
+	for i, property := range item.GetKind().Properties() {
+		menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("%s - %s", strings.Title(property), item.GetProperty(property)))
+	}
+
+	if item.IsContainer() {
+		lockedState := "No"
+		if item.IsLocked() {
+			lockedState = "Yes"
+		}
+
+		menu.AddAction("l", fmt.Sprintf("Locked - %s", lockedState))
+	}
+
+	menu.AddAction("x", fmt.Sprintf("Script - %s", scriptName(item.GetScriptId())))
+
+	return menu
+}
+
+func itemKindMenu() *utils.Menu {
+	menu := utils.NewMenu("Item Type")
+
+	for i, kind := range database.ItemKinds {
+		menu.AddAction(strconv.Itoa(i+1), string(kind))
+	}
+
+	return menu
+}
+
+// Item edits an item in the room or in the player's inventory
+func (ch *commandHandler) Item(args []string) {
+	if len(args) != 1 {
+		ch.session.printError("Usage: /item <item name>")
+		return
+	}
+
+	items := append(model.ItemsIn(ch.session.room), model.GetItems(ch.session.player.GetItemIds())...)
+	index := utils.BestMatch(args[0], database.ItemNames(items))
+
+	if index == -1 {
+		ch.session.printError("Item not found")
+		return
+	} else if index == -2 {
+		ch.session.printError("Which one do you mean?")
+		return
+	}
+
+	editItem(ch, items[index])
+}
+
+// editItem runs the item editor menu for an item or item template
+func editItem(ch *commandHandler, item *database.Item) {
+	for {
+		choice, _ := ch.session.execMenu(itemMenu(item))
+
+		switch choice {
+		case "":
+			return
+		case "n":
+			name := ch.session.getUserInput(CleanUserInput, "New name: ")
+			if name != "" {
+				item.SetName(name)
+			}
+		case "d":
+			description := ch.session.getUserInput(RawUserInput, "New description: ")
+			if description != "" {
+				item.SetDescription(description)
+			}
+		case "l":
+			if item.IsContainer() {
+				item.SetLocked(!item.IsLocked())
+			}
+		case "x":
+			attachScript(ch, item)
+		case "t":
+			choice, _ := ch.session.execMenu(itemKindMenu())
+			index, err := strconv.Atoi(choice)
+			if err == nil && index > 0 && index <= len(database.ItemKinds) {
+				item.SetKind(database.ItemKinds[index-1])
+			}
+		default:
+			properties := item.GetKind().Properties()
+			index, err := strconv.Atoi(choice)
+			if err != nil || index < 1 || index > len(properties) {
+				break
+			}
+
+			property := properties[index-1]
+
+			if property == database.PropertySlot {
+				menu := utils.NewMenu("Slot")
+				for i, slot := range database.ArmorSlots {
+					menu.AddAction(strconv.Itoa(i+1), string(slot))
+				}
+
+				choice, _ := ch.session.execMenu(menu)
+				index, err := strconv.Atoi(choice)
+				if err == nil && index > 0 && index <= len(database.ArmorSlots) {
+					item.SetProperty(property, string(database.ArmorSlots[index-1]))
+				}
+			} else if property == database.PropertyKey {
+				code := ch.session.getUserInput(CleanUserInput, "Key code: ")
+				if code != "" {
+					item.SetProperty(property, code)
+				}
+			} else if property == database.PropertyDamage {
+				dice := ch.session.getUserInput(CleanUserInput, "Damage dice (e.g. 1d8+1): ")
+				if dice == "" {
+					break
+				}
+
+				if _, _, _, err := utils.ParseDice(dice); err != nil {
+					ch.session.printError(err.Error())
+				} else {
+					item.SetProperty(property, dice)
+				}
+			} else {
+				value, ok := getNumber(ch, fmt.Sprintf("%s: ", strings.Title(property)))
+				if ok {
+					item.SetIntProperty(property, value)
+				}
+			}
+		}
+	}
+}
+
+func itemTemplatesMenu() *utils.Menu {
+	menu := utils.NewMenu("Item Templates")
+
+	menu.AddAction("n", "New")
+
+	for i, template := range model.GetItemTemplates() {
+		index := i + 1
+		menu.AddActionData(index, template.GetName(), template.GetId())
+	}
+
+	return menu
+}
+
+func specificItemTemplateMenu(template *database.Item) *utils.Menu {
+	menu := utils.NewMenu(template.GetName())
+
+	menu.AddAction("e", "Edit")
+	menu.AddAction("u", fmt.Sprintf("Update copies (%v)", len(model.ItemInstances(template))))
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+func (ch *commandHandler) IT(args []string) {
+	ch.ItemTemplates(args)
+}
+
+// ItemTemplates lets builders manage the item templates that /load, loot
+// tables and shops create items from
+func (ch *commandHandler) ItemTemplates(args []string) {
+	for {
+		choice, templateId := ch.session.execMenu(itemTemplatesMenu())
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			name := ch.session.getUserInput(CleanUserInput, "Name: ")
+			if name != "" {
+				editItem(ch, model.CreateItemTemplate(name))
+			}
+			continue
+		}
+
+		for {
+			template := model.GetItem(templateId)
+			if template == nil {
+				break
+			}
+
+			choice, _ := ch.session.execMenu(specificItemTemplateMenu(template))
+
+			if choice == "" {
+				break
+			} else if choice == "e" {
+				editItem(ch, template)
+			} else if choice == "u" {
+				ch.session.printLine("Updated %v items", model.UpdateInstances(template))
+			} else if choice == "d" {
+				model.DeleteItem(template)
+			}
+		}
+	}
+}
+
+// Load creates a copy of an item template in the current room
+func (ch *commandHandler) Load(args []string) {
+	if len(args) != 1 {
+		ch.session.printError("Usage: /load <item template name>")
+		return
+	}
+
+	templates := model.GetItemTemplates()
+	index := utils.BestMatch(args[0], database.ItemNames(templates))
+
+	if index == -1 {
+		ch.session.printError("Template not found")
+	} else if index == -2 {
+		ch.session.printError("Which one do you mean?")
+	} else {
+		item := model.CreateItemFromTemplate(templates[index])
+		ch.session.room.AddItem(item)
+		ch.session.printLine("Loaded %s", item.GetName())
+	}
+}
+
+func lootMenu(loot []database.LootEntry) *utils.Menu {
+	menu := utils.NewMenu("Loot")
+
+	menu.AddAction("n", "New")
+
+	for i, entry := range loot {
+		name := "<Deleted template>"
+		template := model.GetItem(entry.TemplateId)
+		if template != nil {
+			name = template.GetName()
+		}
+
+		menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s (%v%%)", name, entry.Chance))
+	}
+
+	return menu
+}
+
+// editLoot edits the loot table of an NPC template
+func editLoot(ch *commandHandler, npcTemplate *database.Character) {
+	for {
+		loot := append([]database.LootEntry(nil), npcTemplate.GetLootTable()...)
+		choice, _ := ch.session.execMenu(lootMenu(loot))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			choice, templateId := ch.session.execMenu(itemTemplatesMenu())
+			if choice == "" || choice == "n" {
+				continue
+			}
+
+			chance, ok := getNumber(ch, "Percent chance: ")
+			if ok && chance > 0 {
+				npcTemplate.SetLootTable(append(loot, database.LootEntry{TemplateId: templateId, Chance: chance}))
+			}
+		} else if index, err := strconv.Atoi(choice); err == nil && index > 0 && index <= len(loot) {
+			npcTemplate.SetLootTable(append(loot[:index-1], loot[index:]...))
+		}
+	}
+}
+
+func shopMenu(shop *database.Shop) *utils.Menu {
+	menu := utils.NewMenu("Shop")
+
+	if shop == nil {
+		menu.AddAction("o", "Open shop")
+		return menu
+	}
+
+	menu.AddAction("c", "Close shop")
+	menu.AddAction("r", fmt.Sprintf("Restock every - %v seconds", shop.Restock))
+	menu.AddAction("m", fmt.Sprintf("Markup - %v%%", shop.Markup))
+	menu.AddAction("b", fmt.Sprintf("Buyback - %v%%", shop.Buyback))
+	menu.AddAction("n", "New stock")
+
+	for i, entry := range shop.Stock {
+		name := "<Deleted template>"
+		template := model.GetItem(entry.TemplateId)
+		if template != nil {
+			name = template.GetName()
+		}
+
+		menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s (%v)", name, entry.Count))
+	}
+
+	return menu
+}
+
+// editShop edits the shop configuration of an NPC or NPC template
+func editShop(ch *commandHandler, npc *database.Character) {
+	for {
+		shop := npc.GetShop()
+		choice, _ := ch.session.execMenu(shopMenu(shop))
+
+		if choice == "" {
+			return
+		} else if choice == "o" && shop == nil {
+			npc.SetShop(database.NewShop())
+		} else if shop == nil {
+			continue
+		} else if choice == "c" {
+			npc.SetShop(nil)
+		} else if choice == "r" {
+			seconds, ok := getNumber(ch, "Seconds between restocks: ")
+			if ok && seconds > 0 {
+				shop.Restock = seconds
+				npc.SetShop(shop)
+			}
+		} else if choice == "m" {
+			percent, ok := getNumber(ch, "Percentage of value charged to buyers: ")
+			if ok {
+				shop.Markup = percent
+				npc.SetShop(shop)
+			}
+		} else if choice == "b" {
+			percent, ok := getNumber(ch, "Percentage of value paid to sellers (0 to buy nothing): ")
+			if ok {
+				shop.Buyback = percent
+				npc.SetShop(shop)
+			}
+		} else if choice == "n" {
+			choice, templateId := ch.session.execMenu(itemTemplatesMenu())
+			if choice == "" || choice == "n" {
+				continue
+			}
+
+			count, ok := getNumber(ch, "Number to keep in stock: ")
+			if ok && count > 0 {
+				shop.Stock = append(shop.Stock, database.StockEntry{TemplateId: templateId, Count: count})
+				npc.SetShop(shop)
+			}
+		} else if index, err := strconv.Atoi(choice); err == nil && index > 0 && index <= len(shop.Stock) {
+			shop.Stock = append(shop.Stock[:index-1], shop.Stock[index:]...)
+			npc.SetShop(shop)
+		}
+	}
+}
+
 func (ch *commandHandler) DestroyItem(args []string) {
 	destroyUsage := func() {
 		ch.session.printError("Usage: /destroyitem <item name>")
@@ -687,20 +1457,82 @@ func (ch *commandHandler) DestroyItem(args []string) {
 }
 
 func (ch *commandHandler) RoomID(args []string) {
-	ch.session.printLine("Room ID: %v", ch.session.room.GetId())
+	ch.session.printLine("Room ID: %s", ch.session.room.GetId().Hex())
+}
+
+// Link connects an exit in the current room to any other room, in any zone.
+// Compass exits get a matching exit back unless the target room already has
+// one, anything else becomes a named exit such as "climb ladder".
+func (ch *commandHandler) Link(args []string) {
+	if len(args) < 2 || !bson.IsObjectIdHex(args[0]) {
+		ch.session.printError("Usage: /link <room id> <direction>|<exit name>")
+		return
+	}
+
+	target := model.GetRoom(bson.ObjectIdHex(args[0]))
+
+	if target == nil {
+		ch.session.printError("Room not found")
+		return
+	}
+
+	room := ch.session.room
+	name := strings.Join(args[1:], " ")
+	direction := database.StringToDirection(name)
+
+	if direction == database.DirectionNone {
+		model.LinkNamedExit(room, name, target)
+		ch.session.printLine("Linked \"%s\" to %s", strings.ToLower(name), target.GetTitle())
+		return
+	}
+
+	model.LinkExit(room, direction, target)
+
+	if !target.HasExit(direction.Opposite()) {
+		model.LinkExit(target, direction.Opposite(), room)
+	}
+
+	ch.session.printLine("Linked %s to %s", strings.ToLower(database.DirectionToString(direction)), target.GetTitle())
+}
+
+// Unlink removes a linked or named exit from the current room
+func (ch *commandHandler) Unlink(args []string) {
+	if len(args) == 0 {
+		ch.session.printError("Usage: /unlink <direction>|<exit name>")
+		return
+	}
+
+	room := ch.session.room
+	name := strings.Join(args, " ")
+	direction := database.StringToDirection(name)
+
+	if direction != database.DirectionNone {
+		if exit, _ := room.GetExit(direction); exit.IsLinked() {
+			room.SetExitEnabled(direction, false)
+			ch.session.printLine("Exit removed")
+		} else {
+			ch.session.printError("That exit isn't linked")
+		}
+	} else if _, found := room.GetNamedExit(name); found {
+		room.RemoveNamedExit(name)
+		ch.session.printLine("Exit removed")
+	} else {
+		ch.session.printError("There is no exit called \"%s\"", name)
+	}
 }
 
 func (ch *commandHandler) Cash(args []string) {
 	cashUsage := func() {
 		ch.session.printError("Usage: /cash give <amount>")
+		ch.session.printError("       /cash log [character name]")
 	}
 
-	if len(args) != 2 {
+	if len(args) == 0 {
 		cashUsage()
 		return
 	}
 
-	if args[0] == "give" {
+	if args[0] == "give" && len(args) == 2 {
 		amount, err := strconv.Atoi(args[1])
 
 		if err != nil {
@@ -708,8 +1540,34 @@ func (ch *commandHandler) Cash(args []string) {
 			return
 		}
 
-		ch.session.player.AddCash(amount)
-		ch.session.printLine("Received: %v monies", amount)
+		err = model.TransferCash(nil, ch.session.player, amount, "admin")
+
+		if err != nil {
+			ch.session.printError(err.Error())
+		} else {
+			ch.session.printLine("Received: %v monies", amount)
+		}
+	} else if args[0] == "log" && len(args) <= 2 {
+		var character *database.Character
+
+		if len(args) == 2 {
+			character = model.GetCharacterByName(args[1])
+
+			if character == nil {
+				ch.session.printError("Character not found")
+				return
+			}
+		}
+
+		transactions := model.GetTransactions(character)
+
+		if len(transactions) == 0 {
+			ch.session.printLine("No transactions")
+		}
+
+		for _, transaction := range transactions {
+			ch.session.printLine(transaction.String())
+		}
 	} else {
 		cashUsage()
 		return
@@ -839,12 +1697,21 @@ func (ch *commandHandler) Area(args []string) {
 				areaMenu := utils.NewMenu(area.GetName())
 				areaMenu.AddAction("r", "Rename")
 				areaMenu.AddAction("d", "Delete")
+				areaMenu.AddAction("s", "Spawners")
 
 				choice, _ = ch.session.execMenu(areaMenu)
 
 				switch choice {
 				case "":
 					break
+				case "s":
+					editSpawners(ch, area.GetName()+" Spawners",
+						func() []*database.Spawner {
+							return model.AreaSpawners(area)
+						},
+						func(template *database.Character) {
+							model.CreateAreaSpawner(template, area)
+						})
 				case "r":
 					newName := ch.session.getUserInput(RawUserInput, "New name: ")
 
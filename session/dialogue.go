@@ -0,0 +1,395 @@
+package session
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/model"
+	"kmud/utils"
+	"strconv"
+)
+
+// runDialogue walks the player through the NPC's dialogue tree, starting at
+// the first node, until they run out of choices or walk away
+func runDialogue(session *Session, npc *database.Character) {
+	dialogue := npc.GetDialogue()
+	node := 1
+
+	for node > 0 && node <= len(dialogue) {
+		current := dialogue[node-1]
+		session.printLine(npc.PrettySay(current.Text))
+
+		choices := model.AvailableChoices(session.player, current)
+
+		if len(choices) == 0 {
+			break
+		}
+
+		menu := utils.NewMenu(npc.GetName())
+		for i, choice := range choices {
+			menu.AddAction(strconv.Itoa(i+1), choice.Text)
+		}
+
+		choice, _ := session.execMenu(menu)
+		index, err := strconv.Atoi(choice)
+
+		if err != nil {
+			break
+		}
+
+		selected := choices[index-1]
+
+		for _, message := range model.ApplyEffects(session.player, npc, selected.Effects) {
+			session.printLine(message)
+		}
+
+		node = selected.Next
+	}
+}
+
+func topicsMenu(topics []database.Topic) *utils.Menu {
+	menu := utils.NewMenu("Topics")
+
+	menu.AddAction("n", "New")
+
+	for i, topic := range topics {
+		menu.AddAction(strconv.Itoa(i+1), topic.Keyword)
+	}
+
+	return menu
+}
+
+func specificTopicMenu(topic database.Topic) *utils.Menu {
+	menu := utils.NewMenu(topic.Keyword)
+
+	menu.AddAction("k", fmt.Sprintf("Keyword - %s", topic.Keyword))
+	menu.AddAction("r", fmt.Sprintf("Response - %s", topic.Response))
+	menu.AddAction("c", fmt.Sprintf("Conditions - %v", len(topic.Conditions)))
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+func dialogueMenu(dialogue []database.DialogueNode) *utils.Menu {
+	menu := utils.NewMenu("Dialogue")
+
+	menu.AddAction("n", "New node")
+
+	for i, node := range dialogue {
+		menu.AddAction(strconv.Itoa(i+1), node.Text)
+	}
+
+	return menu
+}
+
+func specificNodeMenu(number int, node database.DialogueNode) *utils.Menu {
+	menu := utils.NewMenu(fmt.Sprintf("Node %v", number))
+
+	menu.AddAction("t", fmt.Sprintf("Text - %s", node.Text))
+	menu.AddAction("n", "New choice")
+
+	for i, choice := range node.Choices {
+		next := "end"
+		if choice.Next > 0 {
+			next = fmt.Sprintf("node %v", choice.Next)
+		}
+
+		menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("%s (%s)", choice.Text, next))
+	}
+
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+func specificChoiceMenu(choice database.DialogueChoice) *utils.Menu {
+	menu := utils.NewMenu(choice.Text)
+
+	next := "End dialogue"
+	if choice.Next > 0 {
+		next = fmt.Sprintf("%v", choice.Next)
+	}
+
+	menu.AddAction("t", fmt.Sprintf("Text - %s", choice.Text))
+	menu.AddAction("n", fmt.Sprintf("Next node - %s", next))
+	menu.AddAction("c", fmt.Sprintf("Conditions - %v", len(choice.Conditions)))
+	menu.AddAction("e", fmt.Sprintf("Effects - %v", len(choice.Effects)))
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+// menuIndex converts a numbered menu choice back in to a slice index. Returns
+// -1 if the choice wasn't a number in range.
+func menuIndex(choice string, length int) int {
+	index, err := strconv.Atoi(choice)
+
+	if err != nil || index < 1 || index > length {
+		return -1
+	}
+
+	return index - 1
+}
+
+// editConversation lets builders edit the topics and dialogue of an NPC or NPC
+// template
+func editConversation(ch *commandHandler, npc *database.Character) {
+	for {
+		menu := utils.NewMenu("Conversation")
+		menu.AddAction("t", fmt.Sprintf("Topics - %v", len(npc.GetTopics())))
+		menu.AddAction("l", fmt.Sprintf("Dialogue - %v nodes", len(npc.GetDialogue())))
+
+		choice, _ := ch.session.execMenu(menu)
+
+		switch choice {
+		case "":
+			return
+		case "t":
+			editTopics(ch, npc)
+		case "l":
+			editDialogue(ch, npc)
+		}
+	}
+}
+
+func editTopics(ch *commandHandler, npc *database.Character) {
+	for {
+		topics := append([]database.Topic(nil), npc.GetTopics()...)
+		choice, _ := ch.session.execMenu(topicsMenu(topics))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			keyword := ch.session.getUserInput(CleanUserInput, "Keyword: ")
+			if keyword != "" {
+				npc.SetTopics(append(topics, database.Topic{Keyword: keyword}))
+			}
+			continue
+		}
+
+		index := menuIndex(choice, len(topics))
+
+		for index != -1 {
+			topic := topics[index]
+			choice, _ := ch.session.execMenu(specificTopicMenu(topic))
+
+			if choice == "" {
+				break
+			} else if choice == "k" {
+				keyword := ch.session.getUserInput(CleanUserInput, "Keyword: ")
+				if keyword != "" {
+					topic.Keyword = keyword
+				}
+			} else if choice == "r" {
+				response := ch.session.getUserInput(RawUserInput, "Response: ")
+				if response != "" {
+					topic.Response = response
+				}
+			} else if choice == "c" {
+				topic.Conditions = editConditions(ch, topic.Conditions)
+			} else if choice == "d" {
+				topics = append(topics[:index:index], topics[index+1:]...)
+				npc.SetTopics(topics)
+				break
+			}
+
+			topics = append([]database.Topic(nil), topics...)
+			topics[index] = topic
+			npc.SetTopics(topics)
+		}
+	}
+}
+
+func editDialogue(ch *commandHandler, npc *database.Character) {
+	for {
+		dialogue := append([]database.DialogueNode(nil), npc.GetDialogue()...)
+		choice, _ := ch.session.execMenu(dialogueMenu(dialogue))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			text := ch.session.getUserInput(RawUserInput, "Text: ")
+			if text != "" {
+				npc.SetDialogue(append(dialogue, database.DialogueNode{Text: text}))
+			}
+			continue
+		}
+
+		index := menuIndex(choice, len(dialogue))
+
+		for index != -1 {
+			node := dialogue[index]
+			choice, _ := ch.session.execMenu(specificNodeMenu(index+1, node))
+
+			if choice == "" {
+				break
+			} else if choice == "t" {
+				text := ch.session.getUserInput(RawUserInput, "Text: ")
+				if text != "" {
+					node.Text = text
+				}
+			} else if choice == "n" {
+				text := ch.session.getUserInput(RawUserInput, "Choice text: ")
+				if text != "" {
+					node.Choices = append(append([]database.DialogueChoice(nil), node.Choices...),
+						database.DialogueChoice{Text: text})
+				}
+			} else if choice == "d" {
+				npc.SetDialogue(deleteNode(dialogue, index))
+				break
+			} else if choiceIndex := menuIndex(choice, len(node.Choices)); choiceIndex != -1 {
+				node.Choices = editChoice(ch, node.Choices, choiceIndex)
+			}
+
+			dialogue = append([]database.DialogueNode(nil), dialogue...)
+			dialogue[index] = node
+			npc.SetDialogue(dialogue)
+		}
+	}
+}
+
+// deleteNode removes a node from the dialogue, fixing up the choices that
+// pointed at the nodes after it. Choices that led to the deleted node end the
+// dialogue instead.
+func deleteNode(dialogue []database.DialogueNode, index int) []database.DialogueNode {
+	deleted := index + 1
+	dialogue = append(dialogue[:index:index], dialogue[index+1:]...)
+
+	for i, node := range dialogue {
+		choices := append([]database.DialogueChoice(nil), node.Choices...)
+
+		for j, choice := range choices {
+			if choice.Next == deleted {
+				choices[j].Next = 0
+			} else if choice.Next > deleted {
+				choices[j].Next--
+			}
+		}
+
+		dialogue[i].Choices = choices
+	}
+
+	return dialogue
+}
+
+// editChoice edits the choice at the given index, returning the updated list
+// of choices
+func editChoice(ch *commandHandler, choices []database.DialogueChoice, index int) []database.DialogueChoice {
+	choices = append([]database.DialogueChoice(nil), choices...)
+
+	for {
+		choice := choices[index]
+		selection, _ := ch.session.execMenu(specificChoiceMenu(choice))
+
+		switch selection {
+		case "":
+			return choices
+		case "t":
+			text := ch.session.getUserInput(RawUserInput, "Choice text: ")
+			if text != "" {
+				choice.Text = text
+			}
+		case "n":
+			next, ok := getNumber(ch, "Next node (0 to end the dialogue): ")
+			if ok {
+				choice.Next = next
+			}
+		case "c":
+			choice.Conditions = editConditions(ch, choice.Conditions)
+		case "e":
+			choice.Effects = editEffects(ch, choice.Effects)
+		case "d":
+			return append(choices[:index:index], choices[index+1:]...)
+		}
+
+		choices[index] = choice
+	}
+}
+
+// editConditions lets the user add and remove conditions, returning the new list
+func editConditions(ch *commandHandler, conditions []database.Condition) []database.Condition {
+	conditions = append([]database.Condition(nil), conditions...)
+
+	for {
+		menu := utils.NewMenu("Conditions")
+		menu.AddAction("n", "New")
+		for i, condition := range conditions {
+			menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s", condition))
+		}
+
+		choice, _ := ch.session.execMenu(menu)
+
+		if choice == "" {
+			return conditions
+		} else if choice == "n" {
+			typeMenu := utils.NewMenu("Condition")
+			for i, conditionType := range database.ConditionTypes {
+				typeMenu.AddAction(strconv.Itoa(i+1), string(conditionType))
+			}
+
+			choice, _ := ch.session.execMenu(typeMenu)
+			typeIndex := menuIndex(choice, len(database.ConditionTypes))
+
+			if typeIndex != -1 {
+				condition := database.Condition{Type: database.ConditionTypes[typeIndex]}
+
+				if readEffectValue(ch, condition.Type.UsesAmount(), &condition.Value, &condition.Amount) {
+					conditions = append(conditions, condition)
+				}
+			}
+		} else if index := menuIndex(choice, len(conditions)); index != -1 {
+			conditions = append(conditions[:index], conditions[index+1:]...)
+		}
+	}
+}
+
+// editEffects lets the user add and remove effects, returning the new list
+func editEffects(ch *commandHandler, effects []database.Effect) []database.Effect {
+	effects = append([]database.Effect(nil), effects...)
+
+	for {
+		menu := utils.NewMenu("Effects")
+		menu.AddAction("n", "New")
+		for i, effect := range effects {
+			menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s", effect))
+		}
+
+		choice, _ := ch.session.execMenu(menu)
+
+		if choice == "" {
+			return effects
+		} else if choice == "n" {
+			typeMenu := utils.NewMenu("Effect")
+			for i, effectType := range database.EffectTypes {
+				typeMenu.AddAction(strconv.Itoa(i+1), string(effectType))
+			}
+
+			choice, _ := ch.session.execMenu(typeMenu)
+			typeIndex := menuIndex(choice, len(database.EffectTypes))
+
+			if typeIndex != -1 {
+				effect := database.Effect{Type: database.EffectTypes[typeIndex]}
+
+				if readEffectValue(ch, effect.Type.UsesAmount(), &effect.Value, &effect.Amount) {
+					effects = append(effects, effect)
+				}
+			}
+		} else if index := menuIndex(choice, len(effects)); index != -1 {
+			effects = append(effects[:index], effects[index+1:]...)
+		}
+	}
+}
+
+// readEffectValue prompts for the amount or name used by a condition or
+// effect. Returns false if the user didn't enter anything.
+func readEffectValue(ch *commandHandler, usesAmount bool, value *string, amount *int) bool {
+	if usesAmount {
+		number, ok := getNumber(ch, "Amount: ")
+		*amount = number
+		return ok
+	}
+
+	*value = ch.session.getUserInput(RawUserInput, "Item, flag or quest name: ")
+	return *value != ""
+}
+
+// vim: nocindent
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/model"
+	"kmud/utils"
+	"strconv"
+	"strings"
+)
+
+// Mail lets players send messages to each other whether or not the recipient
+// is online
+func (ah *actionHandler) Mail(args []string) {
+	mailUsage := func() {
+		ah.session.printError("Usage: mail [list] | send <player> | read <#> | reply <#> | delete <#> | collect <#>")
+	}
+
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" {
+		ah.listMail()
+		return
+	}
+
+	if len(args) != 2 {
+		mailUsage()
+		return
+	}
+
+	player := ah.session.player
+	subcommand := strings.ToLower(args[0])
+
+	if subcommand == "send" {
+		to := model.GetCharacterByName(args[1])
+		if to == nil || !to.IsPlayer() {
+			ah.session.printError("Player '%s' not found", args[1])
+			return
+		}
+
+		ah.composeMail(to, "")
+		return
+	}
+
+	mail := ah.findMail(args[1])
+	if mail == nil {
+		return
+	}
+
+	var err error
+
+	switch subcommand {
+	case "read":
+		ah.readMail(mail)
+	case "reply":
+		from := model.GetCharacter(mail.GetFromId())
+		if from == nil {
+			ah.session.printError("%s no longer exists", mail.GetFromName())
+			return
+		}
+
+		subject := mail.GetSubject()
+		if !strings.HasPrefix(subject, "Re: ") {
+			subject = "Re: " + subject
+		}
+
+		ah.composeMail(from, subject)
+	case "delete":
+		if err = model.DeleteMail(player, mail); err == nil {
+			ah.session.printLine("Mail deleted")
+		}
+	case "collect":
+		if err = model.CollectAttachments(player, mail); err == nil {
+			ah.session.printLine("You collect the attachments")
+		}
+	default:
+		mailUsage()
+	}
+
+	if err != nil {
+		ah.session.printError(err.Error())
+	}
+}
+
+func (ah *actionHandler) listMail() {
+	mailbox := model.Mailbox(ah.session.player)
+
+	if len(mailbox) == 0 {
+		ah.session.printLine("Your mailbox is empty")
+		return
+	}
+
+	for i, mail := range mailbox {
+		unread := " "
+		if !mail.IsRead() {
+			unread = "*"
+		}
+
+		attachments := ""
+		if mail.HasAttachments() {
+			attachments = " (attachments)"
+		}
+
+		ah.session.printLine("%s%3v. %-16s %s%s", unread, i+1, mail.GetFromName(), mail.GetSubject(), attachments)
+	}
+}
+
+// findMail looks up mail by its number in the player's mailbox, printing an
+// error if there isn't any
+func (ah *actionHandler) findMail(number string) *database.Mail {
+	mailbox := model.Mailbox(ah.session.player)
+	index, err := strconv.Atoi(number)
+
+	if err != nil || index < 1 || index > len(mailbox) {
+		ah.session.printError("There is no mail numbered '%s'", number)
+		return nil
+	}
+
+	return mailbox[index-1]
+}
+
+func (ah *actionHandler) readMail(mail *database.Mail) {
+	ah.session.printLine(utils.Colorize(utils.ColorYellow, mail.GetSubject()))
+	ah.session.printLine("From: %s, %s", mail.GetFromName(), mail.GetSent().Format("2 Jan 2006 15:04"))
+	ah.session.printLine("")
+
+	for _, line := range strings.Split(mail.GetBody(), "\n") {
+		ah.session.printLine("%s", line)
+	}
+
+	var attachments []string
+
+	if cash := mail.GetCash(); cash > 0 {
+		attachments = append(attachments, fmt.Sprintf("%v coins", cash))
+	}
+
+	for _, item := range model.GetItems(mail.GetItemIds()) {
+		if item != nil {
+			attachments = append(attachments, item.GetName())
+		}
+	}
+
+	if len(attachments) > 0 {
+		ah.session.printLine("")
+		ah.session.printLine("Attached: %s", strings.Join(attachments, ", "))
+	}
+
+	mail.SetRead(true)
+}
+
+// composeMail asks for the subject, body and attachments of a new piece of
+// mail and sends it. The subject is only asked for if one isn't given.
+func (ah *actionHandler) composeMail(to *database.Character, subject string) {
+	if subject == "" {
+		subject = ah.session.getUserInput(RawUserInput, "Subject: ")
+		if subject == "" {
+			return
+		}
+	}
+
+	ah.session.printLine("Enter the message, ending with a line containing only '.'")
+
+	var lines []string
+	for {
+		line := ah.session.getUserInput(RawUserInput, "> ")
+		if line == "." {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	cash := 0
+	if input := ah.session.getUserInput(CleanUserInput, "Attach coins (blank for none): "); input != "" {
+		var err error
+		if cash, err = strconv.Atoi(input); err != nil || cash < 0 {
+			ah.session.printError("Invalid amount, mail not sent")
+			return
+		}
+	}
+
+	var items []*database.Item
+	for {
+		name := ah.session.getUserInput(CleanUserInput, "Attach item (blank when done): ")
+		if name == "" {
+			break
+		}
+
+		if item := ah.findCarriedItem(name); item != nil {
+			items = append(items, item)
+		}
+	}
+
+	if _, err := model.SendMail(ah.session.player, to, subject, strings.Join(lines, "\n"), cash, items); err != nil {
+		ah.session.printError(err.Error())
+	} else {
+		ah.session.printLine("Mail sent to %s", to.GetName())
+	}
+}
+
+// vim: nocindent
@@ -70,14 +70,28 @@ func (self *mapBuilder) addRoom(room *database.Room, x int, y int, z int) {
 			return
 		}
 
-		if room.HasExit(dir) {
+		if exit, found := room.GetExit(dir); found {
 			self.data[z][y][x].addExit(dir)
+
+			if exit.IsLinked() {
+				self.data[z][y][x].color = utils.ColorYellow
+			}
+		}
+	}
+
+	hasPortal := len(room.GetNamedExits()) > 0
+	for _, dir := range room.GetExits() {
+		if exit, _ := room.GetExit(dir); exit.IsLinked() {
+			hasPortal = true
 		}
 	}
 
 	if self.userRoom.GetId() == room.GetId() {
 		self.data[z][y][x].char = 'O'
 		self.data[z][y][x].color = utils.ColorRed
+	} else if hasPortal {
+		self.data[z][y][x].char = '@'
+		self.data[z][y][x].color = utils.ColorYellow
 	} else {
 		self.data[z][y][x].color = utils.ColorMagenta
 		if room.HasExit(database.DirectionUp) && room.HasExit(database.DirectionDown) {
@@ -0,0 +1,219 @@
+package session
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/model"
+	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"strconv"
+)
+
+func questsMenu() *utils.Menu {
+	menu := utils.NewMenu("Quests")
+
+	menu.AddAction("n", "New")
+
+	for i, quest := range model.GetQuests() {
+		menu.AddActionData(i+1, quest.GetName(), quest.GetId())
+	}
+
+	return menu
+}
+
+func specificQuestMenu(quest *database.Quest) *utils.Menu {
+	menu := utils.NewMenu(quest.GetName())
+
+	order := "Any order"
+	if quest.IsOrdered() {
+		order = "In order"
+	}
+
+	reward := quest.GetReward()
+
+	menu.AddAction("r", "Rename")
+	menu.AddAction("e", fmt.Sprintf("Description - %s", quest.GetDescription()))
+	menu.AddAction("o", fmt.Sprintf("Objectives - %v", len(quest.GetObjectives())))
+	menu.AddAction("i", fmt.Sprintf("Order - %s", order))
+	menu.AddAction("c", fmt.Sprintf("Cash reward - %v", reward.Cash))
+	menu.AddAction("x", fmt.Sprintf("Experience reward - %v", reward.Experience))
+	menu.AddAction("t", fmt.Sprintf("Item rewards - %v", len(reward.Items)))
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+// Quest lets builders define quests. Players are given quests through the
+// "start quest" effect of a dialogue choice.
+func (ch *commandHandler) Quest(args []string) {
+	for {
+		choice, questId := ch.session.execMenu(questsMenu())
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			name := ch.session.getUserInput(CleanUserInput, "Name: ")
+			if name != "" {
+				model.CreateQuest(name)
+			}
+			continue
+		}
+
+		for {
+			quest := model.GetQuest(questId)
+			if quest == nil {
+				break
+			}
+
+			choice, _ := ch.session.execMenu(specificQuestMenu(quest))
+			reward := quest.GetReward()
+
+			switch choice {
+			case "":
+				quest = nil
+			case "r":
+				name := ch.session.getUserInput(CleanUserInput, "Name: ")
+				if name != "" {
+					quest.SetName(name)
+				}
+			case "e":
+				description := ch.session.getUserInput(RawUserInput, "Description: ")
+				if description != "" {
+					quest.SetDescription(description)
+				}
+			case "o":
+				quest.SetObjectives(editObjectives(ch, quest.GetObjectives()))
+			case "i":
+				quest.SetOrdered(!quest.IsOrdered())
+			case "c":
+				if cash, ok := getNumber(ch, "Cash: "); ok {
+					reward.Cash = cash
+					quest.SetReward(reward)
+				}
+			case "x":
+				if experience, ok := getNumber(ch, "Experience: "); ok {
+					reward.Experience = experience
+					quest.SetReward(reward)
+				}
+			case "t":
+				reward.Items = editRewardItems(ch, reward.Items)
+				quest.SetReward(reward)
+			case "d":
+				model.DeleteQuest(quest)
+				quest = nil
+			}
+
+			if quest == nil {
+				break
+			}
+		}
+	}
+}
+
+// editObjectives lets the builder add and remove the objectives of a quest,
+// returning the new list
+func editObjectives(ch *commandHandler, objectives []database.Objective) []database.Objective {
+	for {
+		menu := utils.NewMenu("Objectives")
+		menu.AddAction("n", "New")
+		for i, objective := range objectives {
+			menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s (%v)", model.DescribeObjective(objective), objective.Count))
+		}
+
+		choice, _ := ch.session.execMenu(menu)
+
+		if choice == "" {
+			return objectives
+		} else if choice == "n" {
+			typeMenu := utils.NewMenu("Objective")
+			for i, objectiveType := range database.ObjectiveTypes {
+				typeMenu.AddAction(strconv.Itoa(i+1), string(objectiveType))
+			}
+
+			choice, _ := ch.session.execMenu(typeMenu)
+			typeIndex := menuIndex(choice, len(database.ObjectiveTypes))
+
+			if typeIndex != -1 {
+				objective := database.Objective{Type: database.ObjectiveTypes[typeIndex], Count: 1}
+
+				if readObjectiveTarget(ch, &objective) {
+					objectives = append(objectives, objective)
+				}
+			}
+		} else if index := menuIndex(choice, len(objectives)); index != -1 {
+			objectives = append(objectives[:index:index], objectives[index+1:]...)
+		}
+	}
+}
+
+// readObjectiveTarget asks for the NPC template, item template or room that
+// the objective is about, along with how many times it needs to be done.
+// Returns false if the builder gave up.
+func readObjectiveTarget(ch *commandHandler, objective *database.Objective) bool {
+	switch objective.Type {
+	case database.ObjectiveKill, database.ObjectiveTalk:
+		choice, templateId := ch.session.execMenu(spawnMenu())
+		if choice == "" || choice == "n" {
+			return false
+		}
+		objective.TargetId = templateId
+	case database.ObjectiveCollect:
+		choice, templateId := ch.session.execMenu(itemTemplatesMenu())
+		if choice == "" || choice == "n" {
+			return false
+		}
+		objective.TargetId = templateId
+	case database.ObjectiveVisit:
+		input := ch.session.getUserInput(CleanUserInput, "Room ID (blank for this room): ")
+
+		if input == "" {
+			objective.TargetId = ch.session.room.GetId()
+		} else if bson.IsObjectIdHex(input) && model.GetRoom(bson.ObjectIdHex(input)) != nil {
+			objective.TargetId = bson.ObjectIdHex(input)
+		} else {
+			ch.session.printError("Room not found")
+			return false
+		}
+	}
+
+	if objective.Type != database.ObjectiveVisit {
+		count, ok := getNumber(ch, "How many: ")
+		if !ok || count == 0 {
+			return false
+		}
+		objective.Count = count
+	}
+
+	return true
+}
+
+// editRewardItems lets the builder pick the item templates that are given
+// out when a quest is completed
+func editRewardItems(ch *commandHandler, items []bson.ObjectId) []bson.ObjectId {
+	for {
+		menu := utils.NewMenu("Item rewards")
+		menu.AddAction("n", "New")
+		for i, templateId := range items {
+			name := "<Deleted template>"
+			if template := model.GetItem(templateId); template != nil {
+				name = template.GetName()
+			}
+			menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s", name))
+		}
+
+		choice, _ := ch.session.execMenu(menu)
+
+		if choice == "" {
+			return items
+		} else if choice == "n" {
+			choice, templateId := ch.session.execMenu(itemTemplatesMenu())
+			if choice != "" && choice != "n" {
+				items = append(items, templateId)
+			}
+		} else if index := menuIndex(choice, len(items)); index != -1 {
+			items = append(items[:index:index], items[index+1:]...)
+		}
+	}
+}
+
+// vim: nocindent
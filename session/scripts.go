@@ -0,0 +1,157 @@
+package session
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/model"
+	"kmud/utils"
+	"labix.org/v2/mgo/bson"
+	"strings"
+)
+
+func scriptsMenu(title string) *utils.Menu {
+	menu := utils.NewMenu(title)
+
+	menu.AddAction("n", "New")
+
+	for i, script := range model.GetScripts() {
+		menu.AddActionData(i+1, script.GetName(), script.GetId())
+	}
+
+	return menu
+}
+
+func specificScriptMenu(script *database.Script) *utils.Menu {
+	menu := utils.NewMenu(script.GetName())
+
+	menu.AddAction("r", "Rename")
+	menu.AddAction("v", "View")
+	menu.AddAction("e", "Edit")
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+// scriptName returns the name of the script with the given ID, for showing
+// in menus
+func scriptName(id bson.ObjectId) string {
+	if id == "" {
+		return "None"
+	}
+
+	script := model.GetScript(id)
+	if script == nil {
+		return "<Deleted script>"
+	}
+
+	return script.GetName()
+}
+
+func (ch *commandHandler) printScript(script *database.Script) {
+	source := script.GetSource()
+
+	if source == "" {
+		ch.session.printLine("<empty>")
+		return
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+		ch.session.printLine("%3v  %s", i+1, line)
+	}
+}
+
+// editSource replaces the script's source with lines typed by the builder
+func editSource(ch *commandHandler, script *database.Script) {
+	ch.session.printLine("Enter the script one line at a time, finishing with an empty line")
+
+	var lines []string
+
+	for {
+		line := ch.session.getUserInput(RawUserInput, "> ")
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		ch.session.printLine("Script unchanged")
+		return
+	}
+
+	source := strings.Join(lines, "\n")
+	script.SetSource(source)
+
+	if err := model.CheckScript(source); err != nil {
+		ch.session.printError("Saved, but the script has an error: %s", err)
+	} else {
+		ch.session.printLine("Script saved")
+	}
+}
+
+// Script lets builders write the Lua scripts that can be attached to rooms,
+// items and NPC templates
+func (ch *commandHandler) Script(args []string) {
+	for {
+		choice, scriptId := ch.session.execMenu(scriptsMenu("Scripts"))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			name := ch.session.getUserInput(CleanUserInput, "Name: ")
+			if name != "" {
+				editSource(ch, model.CreateScript(name))
+			}
+			continue
+		}
+
+		for {
+			script := model.GetScript(scriptId)
+			if script == nil {
+				break
+			}
+
+			choice, _ := ch.session.execMenu(specificScriptMenu(script))
+
+			if choice == "" {
+				break
+			} else if choice == "r" {
+				name := ch.session.getUserInput(CleanUserInput, "Name: ")
+				if name != "" {
+					script.SetName(name)
+				}
+			} else if choice == "v" {
+				ch.printScript(script)
+			} else if choice == "e" {
+				editSource(ch, script)
+			} else if choice == "d" {
+				model.DeleteScript(script)
+			}
+		}
+	}
+}
+
+// attachScript lets the builder pick the script for a room, item or NPC
+func attachScript(ch *commandHandler, object database.Scriptable) {
+	menu := scriptsMenu(fmt.Sprintf("Script - %s", scriptName(object.GetScriptId())))
+	menu.AddAction("x", "None")
+
+	choice, scriptId := ch.session.execMenu(menu)
+
+	switch choice {
+	case "":
+	case "n":
+		name := ch.session.getUserInput(CleanUserInput, "Name: ")
+		if name != "" {
+			script := model.CreateScript(name)
+			editSource(ch, script)
+			object.SetScriptId(script.GetId())
+		}
+	case "x":
+		object.SetScriptId("")
+	default:
+		object.SetScriptId(scriptId)
+	}
+}
+
+// vim: nocindent
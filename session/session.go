@@ -1,10 +1,12 @@
 package session
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"kmud/database"
 	"kmud/model"
+	"kmud/telnet"
 	"kmud/utils"
 	"labix.org/v2/mgo/bson"
 	"strconv"
@@ -39,6 +41,9 @@ type Session struct {
 
 	replyId bson.ObjectId
 
+	lastGMCP   string // The last GMCP data sent, so that it's only sent again when it changes
+	lastPrompt string
+
 	// logger *log.Logger
 }
 
@@ -49,7 +54,7 @@ func NewSession(conn io.ReadWriter, user *database.User, player *database.Charac
 	session.player = player
 	session.room = model.GetRoom(player.GetRoomId())
 
-	session.prompt = "%h/%H> "
+	session.prompt = "%h/%H %m/%M %v/%V%e%g> "
 
 	session.userInputChannel = make(chan string)
 	session.inputModeChannel = make(chan userInputMode)
@@ -85,6 +90,10 @@ func (session *Session) Exec() {
 	session.printLineColor(utils.ColorWhite, "Welcome, "+session.player.GetName())
 	session.printRoom()
 
+	if unread := model.UnreadMail(session.player); unread > 0 {
+		session.printLineColor(utils.ColorYellow, "You have %v unread mail, type 'mail' to read it", unread)
+	}
+
 	// Main routine in charge of actually reading input from the connection object,
 	// also has built in throttling to limit how fast we are allowed to process
 	// commands from the user.
@@ -151,6 +160,43 @@ func (session *Session) printRoom() {
 		model.GetItems(session.room.GetItemIds()), area))
 }
 
+func (session *Session) printItem(item *database.Item) {
+	session.printLine("Looking at: %s (%s)", item.GetName(), item.GetKind())
+
+	description := item.GetDescription()
+	if description == "" {
+		description = "You see nothing special about it"
+	}
+	session.printLine(description)
+
+	for _, property := range item.GetKind().Properties() {
+		value := item.GetProperty(property)
+		if value != "" && property != database.PropertyKey {
+			session.printLine("  %s: %s", strings.Title(property), value)
+		}
+	}
+
+	if model.IsCoins(item) {
+		session.printLine("There are %v coins", item.GetCash())
+	}
+
+	if item.IsContainer() {
+		if item.IsLocked() {
+			session.printLine("It is locked")
+		} else if len(item.GetItemIds()) == 0 {
+			session.printLine("It is empty")
+		} else {
+			var names []string
+			for _, content := range model.GetItems(item.GetItemIds()) {
+				if content != nil {
+					names = append(names, content.GetName())
+				}
+			}
+			session.printLine("It contains: %s", strings.Join(names, ", "))
+		}
+	}
+}
+
 func (session *Session) clearLine() {
 	utils.ClearLine(session.conn)
 }
@@ -200,27 +246,25 @@ func (session *Session) getUserInputP(inputMode userInputMode, prompter utils.Pr
 			if event.Type() == model.TellEventType {
 				tellEvent := event.(model.TellEvent)
 				session.replyId = tellEvent.From.GetId()
-			} else if event.Type() == model.CombatEventType {
-				combatEvent := event.(model.CombatEvent)
-
-				if combatEvent.Defender == session.player {
-					session.player.Hit(combatEvent.Damage)
-					if session.player.GetHitPoints() <= 0 {
-						session.asyncMessage(">> You're dead <<")
-						model.StopFight(combatEvent.Defender)
-						model.StopFight(combatEvent.Attacker)
-					}
+			} else if event.Type() == model.EnterEventType {
+				enterEvent := event.(model.EnterEvent)
+
+				// The player was moved by something other than their own
+				// action (death, for example), so catch up with them
+				if enterEvent.Character == session.player && session.player.GetRoomId() != session.room.GetId() {
+					session.room = model.GetRoom(session.player.GetRoomId())
+					session.clearLine()
+					session.printRoom()
+					session.user.Write(prompter.GetPrompt())
 				}
 			} else if event.Type() == model.TimerEventType {
-				if !model.InCombat(session.player) {
-					oldHps := session.player.GetHitPoints()
-					session.player.Heal(5)
-					newHps := session.player.GetHitPoints()
-
-					if oldHps != newHps {
-						session.clearLine()
-						session.user.Write(prompter.GetPrompt())
-					}
+				session.sendGMCP()
+
+				// Redraw the prompt if regeneration has changed it
+				if prompt := prompter.GetPrompt(); prompt != session.lastPrompt {
+					session.lastPrompt = prompt
+					session.clearLine()
+					session.user.Write(prompt)
 				}
 			}
 
@@ -240,14 +284,104 @@ func (session *Session) getUserInput(inputMode userInputMode, prompt string) str
 	return session.getUserInputP(inputMode, utils.SimplePrompter(prompt))
 }
 
+// GetPrompt fills in the tokens of the player's prompt: %h/%H for hit points,
+// %m/%M for mana, %v/%V for movement, %e for their status effects and %g for
+// the health of the rest of their party. %e and %g are empty when there is
+// nothing to show, otherwise they include a leading space.
 func (session *Session) GetPrompt() string {
+	player := session.player
+
+	effects := ""
+	if names := statusNames(player); len(names) > 0 {
+		effects = " [" + strings.Join(names, ", ") + "]"
+	}
+
 	prompt := session.prompt
-	prompt = strings.Replace(prompt, "%h", strconv.Itoa(session.player.GetHitPoints()), -1)
-	prompt = strings.Replace(prompt, "%H", strconv.Itoa(session.player.GetHealth()), -1)
+	prompt = strings.Replace(prompt, "%h", strconv.Itoa(player.GetHitPoints()), -1)
+	prompt = strings.Replace(prompt, "%H", strconv.Itoa(player.GetHealth()), -1)
+	prompt = strings.Replace(prompt, "%m", strconv.Itoa(player.GetMana()), -1)
+	prompt = strings.Replace(prompt, "%M", strconv.Itoa(player.GetMaxMana()), -1)
+	prompt = strings.Replace(prompt, "%v", strconv.Itoa(player.GetMovement()), -1)
+	prompt = strings.Replace(prompt, "%V", strconv.Itoa(player.GetMaxMovement()), -1)
+	prompt = strings.Replace(prompt, "%e", effects, -1)
+	prompt = strings.Replace(prompt, "%g", partyStatus(player), -1)
 
 	return utils.Colorize(utils.ColorWhite, prompt)
 }
 
+// statusNames lists the character's status effects, along with how many
+// stacks there are of those that have more than one
+func statusNames(character *database.Character) []string {
+	var names []string
+
+	for _, active := range character.GetStatuses() {
+		if active.Stacks > 1 {
+			names = append(names, fmt.Sprintf("%s x%v", active.Name, active.Stacks))
+		} else {
+			names = append(names, active.Name)
+		}
+	}
+
+	return names
+}
+
+// partyStatus shows how healthy the other members of the character's party
+// are, e.g. " [Bob 80%, Alice 45%]"
+func partyStatus(character *database.Character) string {
+	var members []string
+
+	for _, member := range model.PartyMembers(character) {
+		if member != character {
+			members = append(members, fmt.Sprintf("%s %v%%", member.GetName(), member.GetHitPoints()*100/member.GetHealth()))
+		}
+	}
+
+	if len(members) == 0 {
+		return ""
+	}
+
+	return " [" + strings.Join(members, ", ") + "]"
+}
+
+type gmcpStatus struct {
+	Name      string `json:"name"`
+	Stacks    int    `json:"stacks"`
+	Remaining int    `json:"remaining"` // Seconds
+}
+
+// sendGMCP sends the player's vitals and status effects to clients that
+// support GMCP, if they have changed since they were last sent
+func (session *Session) sendGMCP() {
+	if !session.user.GMCP() {
+		return
+	}
+
+	player := session.player
+
+	vitals, _ := json.Marshal(map[string]int{
+		"hp":      player.GetHitPoints(),
+		"maxhp":   player.GetHealth(),
+		"mana":    player.GetMana(),
+		"maxmana": player.GetMaxMana(),
+		"mv":      player.GetMovement(),
+		"maxmv":   player.GetMaxMovement(),
+	})
+
+	statuses := []gmcpStatus{}
+	for _, active := range player.GetStatuses() {
+		remaining := int(active.Expires.Sub(time.Now()).Seconds() + 0.5)
+		statuses = append(statuses, gmcpStatus{Name: active.Name, Stacks: active.Stacks, Remaining: remaining})
+	}
+	effects, _ := json.Marshal(statuses)
+
+	data := append(telnet.BuildGMCP("Char.Vitals", vitals), telnet.BuildGMCP("Char.Effects", effects)...)
+
+	if string(data) != session.lastGMCP {
+		session.lastGMCP = string(data)
+		session.conn.Write(data)
+	}
+}
+
 func (session *Session) currentZone() *database.Zone {
 	return model.GetZone(session.room.GetZoneId())
 }
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"fmt"
+	"kmud/database"
+	"kmud/utils"
+	"strconv"
+)
+
+func triggersMenu(triggers []database.Trigger) *utils.Menu {
+	menu := utils.NewMenu("Triggers")
+
+	menu.AddAction("n", "New")
+
+	for i, trigger := range triggers {
+		menu.AddAction(strconv.Itoa(i+1), trigger.String())
+	}
+
+	return menu
+}
+
+func specificTriggerMenu(trigger database.Trigger) *utils.Menu {
+	menu := utils.NewMenu(trigger.String())
+
+	switch trigger.Type {
+	case database.TriggerCommand:
+		menu.AddAction("v", fmt.Sprintf("Verb - %s", trigger.Verb))
+	case database.TriggerTimer:
+		menu.AddAction("i", fmt.Sprintf("Interval - %v seconds", trigger.Interval))
+	}
+
+	menu.AddAction("c", fmt.Sprintf("Conditions - %v", len(trigger.Conditions)))
+	menu.AddAction("a", fmt.Sprintf("Actions - %v", len(trigger.Actions)))
+	menu.AddAction("d", "Delete")
+
+	return menu
+}
+
+// readTriggerSetting asks for the command or interval that fires the trigger,
+// if its type needs one. Returns false if the builder gave up.
+func readTriggerSetting(ch *commandHandler, trigger *database.Trigger) bool {
+	switch trigger.Type {
+	case database.TriggerCommand:
+		verb := ch.session.getUserInput(CleanUserInput, "Command: ")
+		if verb == "" {
+			return false
+		}
+		trigger.Verb = verb
+	case database.TriggerTimer:
+		seconds, ok := getNumber(ch, "Seconds between runs: ")
+		if !ok || seconds == 0 {
+			return false
+		}
+		trigger.Interval = seconds
+	}
+
+	return true
+}
+
+// Triggers edits the triggers attached to the current room
+func (ch *commandHandler) Triggers(args []string) {
+	room := ch.session.room
+
+	for {
+		triggers := append([]database.Trigger(nil), room.GetTriggers()...)
+		choice, _ := ch.session.execMenu(triggersMenu(triggers))
+
+		if choice == "" {
+			return
+		} else if choice == "n" {
+			typeMenu := utils.NewMenu("Trigger")
+			for i, triggerType := range database.TriggerTypes {
+				typeMenu.AddAction(strconv.Itoa(i+1), string(triggerType))
+			}
+
+			choice, _ := ch.session.execMenu(typeMenu)
+			typeIndex := menuIndex(choice, len(database.TriggerTypes))
+
+			if typeIndex != -1 {
+				trigger := database.Trigger{Type: database.TriggerTypes[typeIndex]}
+
+				if readTriggerSetting(ch, &trigger) {
+					room.SetTriggers(append(triggers, trigger))
+				}
+			}
+			continue
+		}
+
+		index := menuIndex(choice, len(triggers))
+
+		for index != -1 {
+			trigger := triggers[index]
+			choice, _ := ch.session.execMenu(specificTriggerMenu(trigger))
+
+			if choice == "" {
+				break
+			} else if choice == "v" || choice == "i" {
+				readTriggerSetting(ch, &trigger)
+			} else if choice == "c" {
+				trigger.Conditions = editConditions(ch, trigger.Conditions)
+			} else if choice == "a" {
+				trigger.Actions = editTriggerActions(ch, trigger.Actions)
+			} else if choice == "d" {
+				triggers = append(triggers[:index:index], triggers[index+1:]...)
+				room.SetTriggers(triggers)
+				break
+			}
+
+			triggers = append([]database.Trigger(nil), triggers...)
+			triggers[index] = trigger
+			room.SetTriggers(triggers)
+		}
+	}
+}
+
+func editTriggerActions(ch *commandHandler, actions []database.TriggerAction) []database.TriggerAction {
+	actions = append([]database.TriggerAction(nil), actions...)
+
+	for {
+		menu := utils.NewMenu("Actions")
+		menu.AddAction("n", "New")
+		for i, action := range actions {
+			menu.AddAction(strconv.Itoa(i+1), fmt.Sprintf("Remove %s", action))
+		}
+
+		choice, _ := ch.session.execMenu(menu)
+
+		if choice == "" {
+			return actions
+		} else if choice == "n" {
+			typeMenu := utils.NewMenu("Action")
+			for i, actionType := range database.TriggerActionTypes {
+				typeMenu.AddAction(strconv.Itoa(i+1), string(actionType))
+			}
+
+			choice, _ := ch.session.execMenu(typeMenu)
+			typeIndex := menuIndex(choice, len(database.TriggerActionTypes))
+
+			if typeIndex != -1 {
+				action := database.TriggerAction{Type: database.TriggerActionTypes[typeIndex]}
+				action.Value = ch.session.getUserInput(RawUserInput, action.Type.Prompt())
+
+				if action.Value != "" {
+					actions = append(actions, action)
+				}
+			}
+		} else if index := menuIndex(choice, len(actions)); index != -1 {
+			actions = append(actions[:index], actions[index+1:]...)
+		}
+	}
+}
+
+// vim: nocindent
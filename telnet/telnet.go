@@ -54,6 +54,12 @@ func (t *Telnet) Listen(listenFunc func(TelnetCode, []byte)) {
 	t.processor.listenFunc = listenFunc
 }
 
+// Negotiate registers a function that is called whenever the client agrees or
+// refuses to use an option, with the verb (WILL, WONT, DO or DONT) they sent
+func (t *Telnet) Negotiate(negotiateFunc func(TelnetCode, TelnetCode)) {
+	t.processor.negotiateFunc = negotiateFunc
+}
+
 // Idea/name for this function shamelessly stolen from bufio
 func (t *Telnet) fill() {
 	buf := make([]byte, 1024)
@@ -98,6 +104,12 @@ func (t *Telnet) DoWindowSize() {
 	t.SendCommand(DO, WS)
 }
 
+// WillGMCP offers to send out of band data to the client using the Generic Mud
+// Communication Protocol. Clients that support it reply with DO GMCP.
+func (t *Telnet) WillGMCP() {
+	t.SendCommand(WILL, GMCP)
+}
+
 func (t *Telnet) DoTerminalType() {
 	// This is really supposed to be two commands, one to ask if they'll send a
 	// terminal type, and another to indicate that they should send it if
@@ -124,6 +136,22 @@ func BuildCommand(codes ...TelnetCode) []byte {
 	return command
 }
 
+// BuildGMCP wraps a GMCP message, such as "Char.Vitals" and its JSON data, in a
+// subnegotiation
+func BuildGMCP(module string, data []byte) []byte {
+	message := append([]byte(module+" "), data...)
+
+	command := BuildCommand(SB, GMCP)
+	for _, b := range message {
+		command = append(command, b)
+		if b == codeToByte[IAC] {
+			command = append(command, b)
+		}
+	}
+
+	return append(command, BuildCommand(SE)...)
+}
+
 const (
 	NUL  TelnetCode = iota // NULL, no operation
 	ECHO TelnetCode = iota // Echo
@@ -236,13 +264,15 @@ const (
 // The processor can then be read from with all of the telnet codes removed, leaving
 // the pure user input stream.
 type telnetProcessor struct {
-	state     processorState
-	currentSB TelnetCode
+	state       processorState
+	currentSB   TelnetCode
+	currentVerb TelnetCode
 
 	capturedBytes []byte
 	subdata       map[TelnetCode][]byte
 	cleanData     string
 	listenFunc    func(TelnetCode, []byte)
+	negotiateFunc func(TelnetCode, TelnetCode)
 
 	debug bool
 }
@@ -331,9 +361,14 @@ func (self *telnetProcessor) addByte(b byte) {
 	case stateInIAC:
 		if code == WILL || code == WONT || code == DO || code == DONT {
 			// Stay in this state
+			self.currentVerb = code
 		} else if code == SB {
 			self.state = stateInSB
 		} else {
+			if self.currentVerb != NUL && self.negotiateFunc != nil {
+				self.negotiateFunc(self.currentVerb, code)
+			}
+			self.currentVerb = NUL
 			self.state = stateBase
 		}
 		self.capture(b)
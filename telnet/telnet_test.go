@@ -183,4 +183,36 @@ func Test_Processor(t *testing.T) {
 	}
 }
 
+func Test_Negotiation(t *testing.T) {
+	var fc fakeConn
+	telnet := NewTelnet(&fc)
+
+	var verb, option TelnetCode
+	telnet.Negotiate(func(v TelnetCode, o TelnetCode) {
+		verb, option = v, o
+	})
+
+	data := append(BuildCommand(DO, GMCP), []byte("test")...)
+	telnet.Write(data)
+
+	readBuffer := make([]byte, 1024)
+	n, _ := telnet.Read(readBuffer)
+
+	if compareData(readBuffer[:n], []byte("test")) == false {
+		t.Errorf("Process(%s) == '%s', want 'test'", data, readBuffer[:n])
+	}
+
+	if verb != DO || option != GMCP {
+		t.Errorf("Negotiation was %s %s, want DO GMCP", CodeToString(verb), CodeToString(option))
+	}
+
+	message := BuildGMCP("Char.Vitals", []byte{'{', '\xff', '}'})
+	wanted := append(BuildCommand(SB, GMCP), []byte("Char.Vitals {\xff\xff}")...)
+	wanted = append(wanted, BuildCommand(SE)...)
+
+	if compareData(message, wanted) == false {
+		t.Errorf("BuildGMCP() == %v, want %v", message, wanted)
+	}
+}
+
 // vim: nocindent
@@ -11,6 +11,7 @@ import (
 	"math/rand"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -387,6 +388,56 @@ func Random(low, high int) int {
 	return result
 }
 
+var diceRegexp = regexp.MustCompile(`^(\d*)d(\d+)([+-]\d+)?$`)
+
+// ParseDice parses dice notation such as "2d6+1" in to the number of dice,
+// the number of sides on each die and a bonus to add to the total
+func ParseDice(dice string) (count int, sides int, bonus int, err error) {
+	match := diceRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(dice)))
+
+	if match == nil {
+		return 0, 0, 0, errors.New("Invalid dice, expected something like 2d6+1")
+	}
+
+	count = 1
+	if match[1] != "" {
+		count, _ = strconv.Atoi(match[1])
+	}
+
+	sides, _ = strconv.Atoi(match[2])
+
+	if match[3] != "" {
+		bonus, _ = strconv.Atoi(match[3])
+	}
+
+	if count < 1 || sides < 1 {
+		return 0, 0, 0, errors.New("Dice need at least one die with at least one side")
+	}
+
+	return count, sides, bonus, nil
+}
+
+// RollDice rolls the given dice notation and returns the total, which is never
+// less than zero. Invalid dice roll zero.
+func RollDice(dice string) int {
+	count, sides, bonus, err := ParseDice(dice)
+
+	if err != nil {
+		return 0
+	}
+
+	total := bonus
+	for i := 0; i < count; i++ {
+		total += Random(1, sides)
+	}
+
+	if total < 0 {
+		total = 0
+	}
+
+	return total
+}
+
 func ReplaceColorTokens(message string) string {
 	// Token replacing
 	message = strings.Replace(message, "$n", "\r\n", -1)
@@ -394,4 +394,43 @@ func Test_Random(t *testing.T) {
 	}
 }
 
+func Test_ParseDice(t *testing.T) {
+	var tests = []struct {
+		dice  string
+		count int
+		sides int
+		bonus int
+		valid bool
+	}{
+		{"2d6", 2, 6, 0, true},
+		{"d8", 1, 8, 0, true},
+		{"1D4+2", 1, 4, 2, true},
+		{"3d10-1", 3, 10, -1, true},
+		{"0d6", 0, 0, 0, false},
+		{"2d", 0, 0, 0, false},
+		{"sword", 0, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		count, sides, bonus, err := ParseDice(test.dice)
+
+		if (err == nil) != test.valid {
+			t.Errorf("ParseDice(%q) error = %v, valid should be %v", test.dice, err, test.valid)
+		}
+
+		if count != test.count || sides != test.sides || bonus != test.bonus {
+			t.Errorf("ParseDice(%q) == %v, %v, %v, want %v, %v, %v", test.dice,
+				count, sides, bonus, test.count, test.sides, test.bonus)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		result := RollDice("2d6+1")
+
+		if result < 3 || result > 13 {
+			t.Errorf("RollDice(2d6+1) out of range, got %v", result)
+		}
+	}
+}
+
 // vim:nocindent